@@ -2,23 +2,78 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"github.com/caarlos0/env/v6"
 	handler "github.com/domano/pwgen/internal/http"
 	"github.com/domano/pwgen/internal/password"
+	"github.com/domano/pwgen/internal/systemd"
+	"github.com/domano/pwgen/internal/tlscert"
 	"github.com/gorilla/handlers"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
+	"io/ioutil"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 )
 
 type config struct {
 	CertFile    string        `env:"CERT_FILE" envDefault:"cert.pem"`
 	KeyFile     string        `env:"KEY_FILE" envDefault:"key.unencrypted.pem"`
+	Host        string        `env:"HOST"`
 	Port        int           `env:"PORT" envDefault:"8443"`
 	GracePeriod time.Duration `env:"GRACE_PERIOD" envDefault:"5s"`
+	// Inline PEM content replaces the files, encrypted keys need a passphrase
+	CertPEM           string `env:"CERT_PEM"`
+	KeyPEM            string `env:"KEY_PEM"`
+	KeyPassphrase     string `env:"KEY_PASSPHRASE"`
+	KeyPassphraseFile string `env:"KEY_PASSPHRASE_FILE"`
+	// Additional certificates selected by SNI as cert:key pairs of files, all certificates are reloaded when their files change
+	SNICerts           []string      `env:"SNI_CERTS" envSeparator:","`
+	CertReloadInterval time.Duration `env:"CERT_RELOAD_INTERVAL" envDefault:"10s"`
+	// Development certificate issued by a self-signed CA instead of the certificate files, only used if enabled
+	DevCert      bool     `env:"DEV_CERT"`
+	DevCertHosts []string `env:"DEV_CERT_HOSTS" envSeparator:","`
+	DevCertDir   string   `env:"DEV_CERT_DIR"`
+	// Client certificates are required and verified if a CA is given, rules restrict what their identities may use
+	ClientCAFile    string `env:"CLIENT_CA_FILE"`
+	ClientRulesFile string `env:"CLIENT_RULES_FILE"`
+	// API keys as id:hash pairs of hex encoded SHA-256 hashes, for callers without client certificates
+	APIKeys     []string `env:"API_KEYS" envSeparator:","`
+	APIKeysFile string   `env:"API_KEYS_FILE"`
+	// TLS policy, empty values keep the defaults of Go except for the cipher suites, which default to the AEAD suites
+	TLSMinVersion   string   `env:"TLS_MIN_VERSION" envDefault:"1.2"`
+	TLSMaxVersion   string   `env:"TLS_MAX_VERSION"`
+	TLSCipherSuites []string `env:"TLS_CIPHER_SUITES" envSeparator:","`
+	TLSCurves       []string `env:"TLS_CURVES" envSeparator:","`
+	TLSALPN         []string `env:"TLS_ALPN" envSeparator:"," envDefault:"h2,http/1.1"`
+	// Security headers, a zero max age disables HSTS
+	HSTSMaxAge            time.Duration `env:"HSTS_MAX_AGE" envDefault:"8760h"`
+	HSTSIncludeSubDomains bool          `env:"HSTS_INCLUDE_SUBDOMAINS"`
+	// Optional listeners besides HTTPS, zero values disable them
+	RedirectPort int    `env:"REDIRECT_PORT"`
+	UnixSocket   string `env:"UNIX_SOCKET"`
+	// Limits of a single request for passwords
+	MaxAmount      int `env:"MAX_AMOUNT" envDefault:"1000"`
+	MaxLength      int `env:"MAX_LENGTH" envDefault:"1024"`
+	MaxOutputBytes int `env:"MAX_OUTPUT_BYTES" envDefault:"1048576"`
+	MaxHashes      int `env:"MAX_HASHES" envDefault:"100"`
+	// Deadline for generating and hashing the passwords of a single request
+	GenerationTimeout time.Duration `env:"GENERATION_TIMEOUT" envDefault:"5s"`
+	// Timeouts and limits of the server against slow or misbehaving clients
+	ReadHeaderTimeout time.Duration `env:"READ_HEADER_TIMEOUT" envDefault:"5s"`
+	ReadTimeout       time.Duration `env:"READ_TIMEOUT" envDefault:"10s"`
+	WriteTimeout      time.Duration `env:"WRITE_TIMEOUT" envDefault:"30s"`
+	IdleTimeout       time.Duration `env:"IDLE_TIMEOUT" envDefault:"120s"`
+	MaxHeaderBytes    int           `env:"MAX_HEADER_BYTES" envDefault:"16384"`
+	// Date in RFC 3339 from which v1 routes with a successor are announced as deprecated
+	V1Deprecated time.Time `env:"V1_DEPRECATED" envDefault:"2026-10-19T00:00:00Z"`
 }
 
 var cfg config
@@ -48,68 +103,459 @@ func parseConfig() error {
 func run(stop chan os.Signal) error {
 	log.Infoln("Starting pwgen...")
 
-	// Create a new password handler using our single use PasswordAdapter
-	ph := handler.NewPasswordHandler(handler.PassworderFunc(PasswordAdapter))
+	certs, err := certificateStore()
+	if err != nil {
+		return errors.Wrap(err, "Could not load TLS certificate: ")
+	}
+	tlsConfig, err := tlscert.Policy{
+		MinVersion:   cfg.TLSMinVersion,
+		MaxVersion:   cfg.TLSMaxVersion,
+		CipherSuites: cfg.TLSCipherSuites,
+		Curves:       cfg.TLSCurves,
+		ALPN:         cfg.TLSALPN,
+	}.Config()
+	if err != nil {
+		return errors.Wrap(err, "Invalid TLS policy: ")
+	}
+	tlsConfig.GetCertificate = certs.GetCertificate
+	if cfg.ClientCAFile != "" {
+		if tlsConfig.ClientCAs, err = tlscert.CertPool(cfg.ClientCAFile); err != nil {
+			return errors.Wrap(err, "Could not load client CA: ")
+		}
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		// Callers with API keys do not need a certificate, but certificates which are given must be valid
+		if len(cfg.APIKeys) > 0 || cfg.APIKeysFile != "" {
+			tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+		}
+	}
+	logTLSPolicy(tlsConfig)
+	auth, err := authentication()
+	if err != nil {
+		return errors.Wrap(err, "Could not set up authentication: ")
+	}
+	server := createServer(auth, apiVersions()...)
+	server.TLSConfig = tlsConfig
+	// HTTP/2 is set up by the server unless it is left out of ALPN
+	if len(tlsConfig.NextProtos) > 0 && !contains(tlsConfig.NextProtos, "h2") {
+		server.TLSNextProto = map[string]func(*http.Server, *tls.Conn, http.Handler){}
+	}
+	redirectServer := createRedirectServer()
+	apiListeners, redirectListeners, err := listen()
+	if err != nil {
+		return errors.Wrap(err, "Could not start server: ")
+	}
+	errChan := make(chan error, len(apiListeners)+len(redirectListeners))
+	for _, l := range apiListeners {
+		startServer(&server, l, errChan)
+	}
+	for _, l := range redirectListeners {
+		startServer(&redirectServer, l, errChan)
+	}
+	if err := systemd.Notify(systemd.Ready); err != nil {
+		log.WithError(err).Warnln("Could not notify systemd about readiness")
+	}
 
-	server := createServer(ph, "/passwords")
-	errChan := startServer(&server)
+	// Reload certificates on SIGHUP or when their files change
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	defer signal.Stop(reload)
+	var watch <-chan time.Time
+	if cfg.CertReloadInterval > 0 {
+		ticker := time.NewTicker(cfg.CertReloadInterval)
+		defer ticker.Stop()
+		watch = ticker.C
+	}
 
 	// Wait for SIGINT or server error
-	select {
-	case err := <-errChan:
-		return errors.Wrap(err, "Could not start server: ")
-	case <-stop:
-		log.Infoln("pwgen shuts down now.")
+	for {
+		select {
+		case err := <-errChan:
+			_ = server.Close()
+			_ = redirectServer.Close()
+			return errors.Wrap(err, "Could not start server: ")
+		case <-reload:
+			reloadCertificates(certs)
+		case <-watch:
+			if certs.Changed() {
+				reloadCertificates(certs)
+			}
+		case <-stop:
+			log.Infoln("pwgen shuts down now.")
+			if err := systemd.Notify(systemd.Stopping); err != nil {
+				log.WithError(err).Warnln("Could not notify systemd about stopping")
+			}
+
+			// Trigger Graceful shutdown with 5 second time limit
+			ctx, ctxCancel := context.WithTimeout(context.Background(), cfg.GracePeriod)
+			defer ctxCancel()
+			err := server.Shutdown(ctx)
+			if err == nil {
+				err = redirectServer.Shutdown(ctx)
+			}
+			if err != nil {
+				return errors.Wrap(err, "pwgen failed during graceful shutdown")
+			}
+			log.Infoln("pwgen gracefully shut down.")
+			return nil
+		}
+	}
+}
+
+// certificateStore loads the main certificate and the additional certificates selected by SNI
+func certificateStore() (*tlscert.Store, error) {
+	source := tlscert.Source{
+		CertFile:       cfg.CertFile,
+		KeyFile:        cfg.KeyFile,
+		CertPEM:        cfg.CertPEM,
+		KeyPEM:         cfg.KeyPEM,
+		Passphrase:     cfg.KeyPassphrase,
+		PassphraseFile: cfg.KeyPassphraseFile,
+	}
+	if cfg.DevCert {
+		var err error
+		if source, err = devCertificate(); err != nil {
+			return nil, err
+		}
+	} else if _, err := os.Stat(cfg.CertFile); cfg.CertPEM == "" && os.IsNotExist(err) {
+		return nil, errors.Errorf("Certificate %s does not exist, set DEV_CERT=true to use a self-signed development certificate", cfg.CertFile)
+	}
+	sources := []tlscert.Source{source}
+	for _, pair := range cfg.SNICerts {
+		files := strings.SplitN(pair, ":", 2)
+		if len(files) != 2 {
+			return nil, errors.Errorf("SNI_CERTS must list pairs of cert:key files, got %s instead", pair)
+		}
+		sources = append(sources, tlscert.Source{
+			CertFile:       files[0],
+			KeyFile:        files[1],
+			Passphrase:     cfg.KeyPassphrase,
+			PassphraseFile: cfg.KeyPassphraseFile,
+		})
+	}
+	return tlscert.NewStore(sources...)
+}
 
-		// Trigger Graceful shutdown with 5 second time limit
-		ctx, ctxCancel := context.WithTimeout(context.Background(), cfg.GracePeriod)
-		err := server.Shutdown(ctx)
+// devCertificate issues a certificate for localhost and DEV_CERT_HOSTS by a development CA, which clients have to trust explicitly
+func devCertificate() (tlscert.Source, error) {
+	if cfg.CertPEM != "" || cfg.KeyPEM != "" {
+		return tlscert.Source{}, errors.New("DEV_CERT can not be combined with CERT_PEM or KEY_PEM")
+	}
+	ca, err := tlscert.LoadDevCA(cfg.DevCertDir)
+	if err != nil {
+		return tlscert.Source{}, err
+	}
+	source, err := ca.Issue(cfg.DevCertHosts...)
+	if err != nil {
+		return tlscert.Source{}, err
+	}
+	fields := log.Fields{"fingerprint": ca.Fingerprint(), "hosts": strings.Join(cfg.DevCertHosts, ",")}
+	if cfg.DevCertDir != "" {
+		fields["ca"] = filepath.Join(cfg.DevCertDir, tlscert.DevCACertFile)
+	}
+	log.WithFields(fields).Warnln("Serving a self-signed development certificate, never use DEV_CERT in production")
+	return source, nil
+}
+
+// middleware wraps a handler
+type middleware func(http.Handler) http.Handler
+
+// authentication identifies callers by their client certificates or API keys and authorizes them by their rules, nil if disabled
+func authentication() (middleware, error) {
+	keys, err := apiKeys()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.ClientCAFile == "" && len(keys) == 0 {
+		if cfg.ClientRulesFile != "" {
+			return nil, errors.New("CLIENT_RULES_FILE requires CLIENT_CA_FILE or API keys")
+		}
+		return nil, nil
+	}
+	var rules handler.Rules
+	if cfg.ClientRulesFile != "" {
+		f, err := os.Open(cfg.ClientRulesFile)
 		if err != nil {
-			ctxCancel()
-			return errors.Wrap(err, "pwgen failed during graceful shutdown")
+			return nil, errors.Wrap(err, "Could not open rules")
+		}
+		defer f.Close()
+		if rules, err = handler.ReadRules(f); err != nil {
+			return nil, err
 		}
-		log.Infoln("pwgen gracefully shut down.")
-		return nil
 	}
+	log.WithFields(log.Fields{
+		"client_certificates": cfg.ClientCAFile != "",
+		"api_keys":            len(keys),
+		"rules":               len(rules),
+	}).Infoln("Requiring authentication")
+	return func(next http.Handler) http.Handler {
+		h := handler.AuthorizationHandlerFunc(next, rules)
+		if len(keys) > 0 {
+			h = handler.APIKeyHandlerFunc(h, keys)
+		}
+		return handler.ClientCertHandlerFunc(h)
+	}, nil
 }
 
-// Starts the server in its own goroutine
-func startServer(server *http.Server) <-chan error {
-	errChan := make(chan error, 0)
-	go func() {
-		err := server.ListenAndServeTLS(cfg.CertFile, cfg.KeyFile)
+// apiKeys reads the API keys of API_KEYS and API_KEYS_FILE
+func apiKeys() (handler.APIKeys, error) {
+	lines := strings.Join(cfg.APIKeys, "\n")
+	if cfg.APIKeysFile != "" {
+		content, err := ioutil.ReadFile(cfg.APIKeysFile)
 		if err != nil {
+			return nil, errors.Wrap(err, "Could not read API keys")
+		}
+		lines += "\n" + string(content)
+	}
+	keys, err := handler.ParseAPIKeys(strings.NewReader(lines))
+	if err != nil {
+		return nil, err
+	}
+	// An empty file must not turn authentication off
+	if cfg.APIKeysFile != "" && len(keys) == 0 {
+		return nil, errors.Errorf("%s contains no API keys", cfg.APIKeysFile)
+	}
+	return keys, nil
+}
+
+// logTLSPolicy logs the effective TLS policy, empty lists use the defaults of Go
+func logTLSPolicy(c *tls.Config) {
+	suites, curves := []string{}, []string{}
+	for _, id := range c.CipherSuites {
+		suites = append(suites, tls.CipherSuiteName(id))
+	}
+	for _, id := range c.CurvePreferences {
+		curves = append(curves, tlscert.CurveName(id))
+	}
+	log.WithFields(log.Fields{
+		"min_version":   tlscert.VersionName(c.MinVersion),
+		"max_version":   tlscert.VersionName(c.MaxVersion),
+		"cipher_suites": strings.Join(suites, ","),
+		"curves":        strings.Join(curves, ","),
+		"alpn":          strings.Join(c.NextProtos, ","),
+	}).Infoln("Using TLS policy")
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// reloadCertificates replaces the served certificates, invalid ones keep being served until they are fixed
+func reloadCertificates(certs *tlscert.Store) {
+	if err := certs.Reload(); err != nil {
+		log.WithError(err).Errorln("Could not reload all TLS certificates")
+		return
+	}
+	log.Infoln("Reloaded TLS certificates")
+}
+
+// Names of sockets passed by systemd which are not served with HTTPS
+const (
+	socketRedirect = "redirect"
+	socketPlain    = "plain"
+)
+
+// listener is a socket of a server, plain listeners serve HTTP without TLS
+type listener struct {
+	net.Listener
+	plain bool
+}
+
+// listen opens the sockets of the API and of the redirects to HTTPS.
+// Sockets passed by systemd replace the configured address, they are
+// served with HTTPS unless named redirect or plain in their socket unit.
+func listen() (api, redirect []listener, err error) {
+	defer func() {
+		if err != nil {
+			for _, l := range append(api, redirect...) {
+				_ = l.Close()
+			}
+			api, redirect = nil, nil
+		}
+	}()
+	activated, err := systemd.Listeners()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Could not use sockets of systemd")
+	}
+	for _, l := range activated {
+		switch l.Name {
+		case socketRedirect:
+			redirect = append(redirect, listener{l, true})
+		case socketPlain:
+			api = append(api, listener{l, true})
+		default:
+			api = append(api, listener{Listener: l})
+		}
+	}
+
+	if len(api) == 0 {
+		l, err := net.Listen("tcp", address(cfg.Port))
+		if err != nil {
+			return api, redirect, errors.Wrap(err, "Could not listen for HTTPS")
+		}
+		api = append(api, listener{Listener: l})
+	}
+	if len(redirect) == 0 && cfg.RedirectPort > 0 {
+		l, err := net.Listen("tcp", address(cfg.RedirectPort))
+		if err != nil {
+			return api, redirect, errors.Wrap(err, "Could not listen for redirects to HTTPS")
+		}
+		redirect = append(redirect, listener{l, true})
+	}
+	if cfg.UnixSocket != "" {
+		l, err := listenUnix(cfg.UnixSocket)
+		if err != nil {
+			return api, redirect, err
+		}
+		api = append(api, listener{l, true})
+	}
+	return api, redirect, nil
+}
+
+// listenUnix listens on a unix domain socket, replacing a socket left over by a previous run
+func listenUnix(path string) (net.Listener, error) {
+	if info, err := os.Stat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		if err := os.Remove(path); err != nil {
+			return nil, errors.Wrap(err, "Could not remove old unix socket")
+		}
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, errors.Wrap(err, "Could not listen on unix socket")
+	}
+	return l, nil
+}
+
+// address is the address to listen on for the given port
+func address(port int) string {
+	return net.JoinHostPort(cfg.Host, strconv.Itoa(port))
+}
+
+// Starts serving the listener in its own goroutine
+func startServer(server *http.Server, l listener, errChan chan<- error) {
+	log.WithField("address", l.Addr().String()).Infoln("Listening for requests")
+	go func() {
+		var err error
+		if l.plain {
+			err = server.Serve(l)
+		} else {
+			err = server.ServeTLS(l, "", "")
+		}
+		if err != nil && err != http.ErrServerClosed {
 			errChan <- errors.Wrap(err, "HTTP Server threw an error, shutting down: ")
 		}
 	}()
-	return errChan
 }
 
-func createServer(h http.Handler, route string) http.Server {
-	// Wrap the handler with all necessary middlewares
-	lh := handler.LoggingHandlerFunc(h)
+// apiVersions lists the routes of every API version served by pwgen
+func apiVersions() []apiVersion {
+	// Create a new password handler using our single use PasswordAdapter
+	limits := handler.Limits{
+		MaxAmount:      cfg.MaxAmount,
+		MaxLength:      cfg.MaxLength,
+		MaxOutputBytes: cfg.MaxOutputBytes,
+		MaxHashes:      cfg.MaxHashes,
+		Timeout:        cfg.GenerationTimeout,
+	}
+	ph := handler.NewPasswordHandler(handler.PassworderFunc(PasswordAdapter), limits)
+
+	return []apiVersion{
+		{
+			routes: map[string]http.Handler{
+				"/passwords":   ph,
+				"/otp":         handler.NewOTPHandler(),
+				"/ssh-keys":    handler.NewSSHKeyHandler(handler.PassworderFunc(PasswordAdapter)),
+				"/x25519-keys": handler.NewX25519Handler(),
+				"/jwk":         handler.NewJWKHandler(),
+
+				handler.PolicySchemaPath: handler.NewPolicySchemaHandler(limits),
+				handler.OpenAPIPath:      handler.NewOpenAPIHandler(limits),
+			},
+			successors: map[string]string{"/passwords": "/v2/passwords"},
+			deprecated: cfg.V1Deprecated,
+		},
+		{
+			prefix: "/v2",
+			routes: map[string]http.Handler{
+				"/passwords": handler.NewPasswordHandlerV2(handler.PassworderFunc(PasswordAdapter), limits),
+			},
+		},
+	}
+}
+
+// apiVersion groups the routes of an API version below a common path prefix, v1 routes have no prefix
+type apiVersion struct {
+	prefix string
+	routes map[string]http.Handler
+	// successors maps deprecated routes to the routes replacing them in a newer version
+	successors map[string]string
+	deprecated time.Time
+}
 
-	// Route /passwords to our handler chain
+// createServer creates the API server, auth may be nil to allow every caller
+func createServer(auth middleware, versions ...apiVersion) http.Server {
+	// Route each path to its handler wrapped with all necessary middlewares
 	mux := http.NewServeMux()
-	mux.Handle(route, lh)
+	mux.Handle("/", handler.NotFoundHandler())
+	for _, version := range versions {
+		for route, h := range version.routes {
+			if successor, ok := version.successors[route]; ok {
+				h = handler.DeprecationHandlerFunc(h, version.deprecated, successor)
+			}
+			mux.Handle(version.prefix+route, h)
+		}
+	}
 
-	// Add a recovery handler in case anything unexpected happens
+	// Add a recovery handler in case anything unexpected happens and describe its errors as problems
 	rh := handlers.RecoveryHandler(handlers.RecoveryLogger(log.StandardLogger()), handlers.PrintRecoveryStack(true))(mux)
+	var h http.Handler = handler.ProblemHandlerFunc(rh)
+	if auth != nil {
+		h = auth(h)
+	}
+	// Log every request, including the ones denied by the authentication
+	h = handler.LoggingHandlerFunc(h)
 
-	return http.Server{Addr: ":8443", Handler: rh}
+	return http.Server{
+		Addr:              address(cfg.Port),
+		Handler:           handler.SecurityHeadersHandlerFunc(h, cfg.HSTSMaxAge, cfg.HSTSIncludeSubDomains),
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
+	}
+}
+
+// createRedirectServer creates the server which redirects plain HTTP requests to HTTPS
+func createRedirectServer() http.Server {
+	return http.Server{
+		Addr:              address(cfg.RedirectPort),
+		Handler:           handler.LoggingHandlerFunc(handler.NewHTTPSRedirectHandler(cfg.Port)),
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
+	}
 }
 
 // PasswordAdapter allows us to use a password
 // generator to fulfill the Passworder-interface for our handler
-func PasswordAdapter(amount, minLength, specialChars, numbers int, swap bool) (passwords []string) {
-	generator := password.NewGenerator(
-		password.MinLength(minLength),
-		password.SpecialChars(specialChars),
-		password.Numbers(numbers),
-		password.Swap(swap))
+func PasswordAdapter(ctx context.Context, amount int, policy password.Policy) (passwords []string, err error) {
+	generator, err := password.NewGenerator(policy.Options()...)
+	if err != nil {
+		return nil, errors.Wrap(err, "Could not create password generator")
+	}
 
 	for i := 0; i < amount; i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, errors.Wrapf(err, "Stopped after %d of %d passwords", i, amount)
+		}
 		passwords = append(passwords, generator.Password())
 	}
-	return passwords
+	return passwords, nil
 }
@@ -1,11 +1,30 @@
 package main
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
 	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
 	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"github.com/domano/pwgen/internal/password"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"math/big"
+	"net"
 	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
 	"testing"
 	"time"
 )
@@ -16,14 +35,16 @@ func Test_run(t *testing.T) {
 
 	// and a valid test config
 	cfg = config{
-		"../../cert.pem",
-		"../../key.unencrypted.pem",
-		8443,
-		5 * time.Second,
+		CertFile:    "../../cert.pem",
+		KeyFile:     "../../key.unencrypted.pem",
+		Port:        8443,
+		GracePeriod: 5 * time.Second,
 	}
 
 	// and our started app
-	go run(nil)
+	stop := make(chan os.Signal, 1)
+	done := make(chan error)
+	go func() { done <- run(stop) }()
 
 	// and a Transport which accepts self signed certs
 	http.DefaultTransport.(*http.Transport).TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
@@ -39,6 +60,10 @@ func Test_run(t *testing.T) {
 	err = json.NewDecoder(resp.Body).Decode(&passwords)
 	assert.NoError(t, err)
 	assert.Equal(t, 1, len(passwords))
+
+	// and the app shuts down
+	stop <- os.Interrupt
+	assert.NoError(t, <-done)
 }
 
 func Test_parseConfig(t *testing.T) {
@@ -48,6 +73,9 @@ func Test_parseConfig(t *testing.T) {
 	// then
 	assert.NoError(t, err)
 	assert.Equal(t, cfg.GracePeriod, 5*time.Second)
+	assert.Equal(t, cfg.ReadHeaderTimeout, 5*time.Second)
+	assert.Equal(t, cfg.MaxHeaderBytes, 16384)
+	assert.Equal(t, cfg.V1Deprecated, time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC))
 }
 
 func Test_parseConfig_withError(t *testing.T) {
@@ -70,6 +98,7 @@ func Test_run_withError(t *testing.T) {
 func Test_run_graceful_Shutdown(t *testing.T) {
 	// when
 	parseConfig()
+	cfg.CertFile, cfg.KeyFile = "../../cert.pem", "../../key.unencrypted.pem"
 	stopChan := make(chan os.Signal, 1)
 	stopChan <- os.Interrupt
 	err := run(stopChan)
@@ -77,3 +106,807 @@ func Test_run_graceful_Shutdown(t *testing.T) {
 	// then
 	assert.NoError(t, err)
 }
+
+func Test_createServer_routes(t *testing.T) {
+	// given a server with a handler for each route of two API versions
+	status := func(code int) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(code) })
+	}
+	server := createServer(nil,
+		apiVersion{
+			routes:     map[string]http.Handler{"/passwords": status(http.StatusAccepted), "/otp": status(http.StatusCreated)},
+			successors: map[string]string{"/passwords": "/v2/passwords"},
+			deprecated: time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC),
+		},
+		apiVersion{
+			prefix: "/v2",
+			routes: map[string]http.Handler{"/passwords": status(http.StatusOK)},
+		},
+	)
+
+	testCases := []struct {
+		route             string
+		expected          int
+		expectedSuccessor string
+	}{
+		{route: "/passwords", expected: http.StatusAccepted, expectedSuccessor: `</v2/passwords>; rel="successor-version"`},
+		{route: "/otp", expected: http.StatusCreated},
+		{route: "/v2/passwords", expected: http.StatusOK},
+		{route: "/v2/otp", expected: http.StatusNotFound},
+		{route: "/unknown", expected: http.StatusNotFound},
+	}
+	for _, tC := range testCases {
+		// when
+		rc := httptest.NewRecorder()
+		server.Handler.ServeHTTP(rc, httptest.NewRequest(http.MethodGet, tC.route, nil))
+
+		// then each route reaches its handler and only deprecated routes announce their successor
+		assert.Equal(t, tC.expected, rc.Code, tC.route)
+		assert.Equal(t, tC.expectedSuccessor, rc.Header().Get("Link"), tC.route)
+		assert.Equal(t, tC.expectedSuccessor != "", rc.Header().Get("Deprecation") != "", tC.route)
+	}
+}
+
+func Test_apiVersions_deprecation(t *testing.T) {
+	// given a server with all routes and a configured deprecation date
+	cfg = config{V1Deprecated: time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC)}
+	defer func() { cfg = config{} }()
+	server := createServer(nil, apiVersions()...)
+
+	// when requesting a deprecated v1 route
+	rc := httptest.NewRecorder()
+	server.Handler.ServeHTTP(rc, httptest.NewRequest(http.MethodGet, "/passwords", nil))
+
+	// then it is announced as deprecated since the configured date
+	assert.Equal(t, http.StatusOK, rc.Code)
+	assert.Equal(t, "@1798761600", rc.Header().Get("Deprecation"))
+}
+
+func Test_apiVersions_documented(t *testing.T) {
+	// given a server with all routes
+	server := createServer(nil, apiVersions()...)
+
+	// when requesting the OpenAPI document
+	rc := httptest.NewRecorder()
+	server.Handler.ServeHTTP(rc, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+	// then it documents every registered route
+	var doc struct {
+		Paths map[string]interface{} `json:"paths"`
+	}
+	assert.NoError(t, json.Unmarshal(rc.Body.Bytes(), &doc))
+	var routes, documented []string
+	for _, version := range apiVersions() {
+		for route := range version.routes {
+			routes = append(routes, version.prefix+route)
+		}
+	}
+	for path := range doc.Paths {
+		documented = append(documented, path)
+	}
+	assert.ElementsMatch(t, routes, documented)
+}
+
+func Test_createServer_problems(t *testing.T) {
+	// given a server with a panicking route
+	server := createServer(nil, apiVersion{routes: map[string]http.Handler{
+		"/panic": http.HandlerFunc(func(http.ResponseWriter, *http.Request) { panic("unexpected") }),
+	}})
+
+	for route, expected := range map[string]int{"/panic": http.StatusInternalServerError, "/unknown": http.StatusNotFound} {
+		// when
+		rc := httptest.NewRecorder()
+		server.Handler.ServeHTTP(rc, httptest.NewRequest(http.MethodGet, route, nil))
+
+		// then the error is described by a problem
+		var problem struct {
+			Type   string `json:"type"`
+			Status int    `json:"status"`
+		}
+		assert.Equal(t, expected, rc.Code, route)
+		assert.Equal(t, "application/problem+json", rc.Header().Get("Content-Type"), route)
+		assert.Equal(t, "no-store", rc.Header().Get("Cache-Control"), route)
+		assert.NoError(t, json.Unmarshal(rc.Body.Bytes(), &problem), route)
+		assert.Equal(t, expected, problem.Status, route)
+		assert.NotEmpty(t, problem.Type, route)
+	}
+}
+
+func Test_createServer_slow_clients(t *testing.T) {
+	// given a server with short timeouts and small headers
+	cfg = config{ReadHeaderTimeout: 100 * time.Millisecond, ReadTimeout: 200 * time.Millisecond, MaxHeaderBytes: 1 << 10}
+	defer func() { cfg = config{} }()
+	server := createServer(nil, apiVersion{routes: map[string]http.Handler{
+		"/passwords": http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, err := ioutil.ReadAll(r.Body); err != nil {
+				w.WriteHeader(http.StatusRequestTimeout)
+			}
+		}),
+	}})
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	go server.Serve(l)
+	defer server.Close()
+
+	testCases := []struct {
+		desc           string
+		request        string
+		expectedStatus string
+	}{
+		{desc: "Headers are never finished", request: "GET /passwords HTTP/1.1\r\nHost: pwgen\r\n"},
+		{desc: "Body is never finished", request: "POST /passwords HTTP/1.1\r\nHost: pwgen\r\nContent-Length: 100\r\n\r\n{", expectedStatus: "HTTP/1.1 408"},
+		{desc: "Headers are too large", request: "GET /passwords HTTP/1.1\r\nHost: pwgen\r\nX-Large: " + strings.Repeat("a", 8<<10) + "\r\n\r\n", expectedStatus: "HTTP/1.1 431"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// and a slow client which stops sending
+			conn, err := net.Dial("tcp", l.Addr().String())
+			assert.NoError(t, err)
+			defer conn.Close()
+			_, err = conn.Write([]byte(tC.request))
+			assert.NoError(t, err)
+
+			// when it waits for the response
+			assert.NoError(t, conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+			resp, err := ioutil.ReadAll(conn)
+
+			// then the server closed the connection long before
+			assert.NoError(t, err)
+			assert.True(t, strings.HasPrefix(string(resp), tC.expectedStatus), string(resp))
+		})
+	}
+}
+
+func TestPasswordAdapter(t *testing.T) {
+	// given
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// when
+	passwords, err := PasswordAdapter(ctx, 3, password.Policy{MinLength: 8})
+
+	// then
+	assert.NoError(t, err)
+	assert.Len(t, passwords, 3)
+
+	// when the context is done
+	cancel()
+	_, err = PasswordAdapter(ctx, 3, password.Policy{MinLength: 8})
+
+	// then generation stops
+	assert.Equal(t, context.Canceled, errors.Cause(err))
+}
+
+// freePort returns a port which is currently not in use
+func freePort(t *testing.T) int {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+func Test_run_listeners(t *testing.T) {
+	// given a config with a redirect port and a unix socket
+	dir, err := ioutil.TempDir("", "pwgen")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	cfg = config{
+		CertFile:     "../../cert.pem",
+		KeyFile:      "../../key.unencrypted.pem",
+		Host:         "127.0.0.1",
+		Port:         freePort(t),
+		RedirectPort: freePort(t),
+		UnixSocket:   filepath.Join(dir, "pwgen.sock"),
+		GracePeriod:  5 * time.Second,
+	}
+
+	// and systemd waiting for readiness
+	notify, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: filepath.Join(dir, "notify"), Net: "unixgram"})
+	assert.NoError(t, err)
+	defer notify.Close()
+	os.Setenv("NOTIFY_SOCKET", filepath.Join(dir, "notify"))
+	defer os.Unsetenv("NOTIFY_SOCKET")
+
+	// when the app is started
+	stop := make(chan os.Signal, 1)
+	done := make(chan error)
+	go func() { done <- run(stop) }()
+
+	// then systemd is notified once it is ready
+	state := make([]byte, 64)
+	assert.NoError(t, notify.SetReadDeadline(time.Now().Add(5*time.Second)))
+	n, err := notify.Read(state)
+	assert.NoError(t, err)
+	assert.Equal(t, "READY=1", string(state[:n]))
+
+	// and HTTPS is served on the configured port
+	client := &http.Client{
+		Transport:     &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	resp, err := client.Get(fmt.Sprintf("https://127.0.0.1:%d/passwords", cfg.Port))
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		resp.Body.Close()
+	}
+
+	// and plain HTTP is redirected to it
+	resp, err = client.Get(fmt.Sprintf("http://127.0.0.1:%d/passwords?amount=2", cfg.RedirectPort))
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusPermanentRedirect, resp.StatusCode)
+		assert.Equal(t, fmt.Sprintf("https://127.0.0.1:%d/passwords?amount=2", cfg.Port), resp.Header.Get("Location"))
+		resp.Body.Close()
+	}
+
+	// and the unix socket serves plain HTTP
+	unixClient := &http.Client{Transport: &http.Transport{DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, "unix", cfg.UnixSocket)
+	}}}
+	resp, err = unixClient.Get("http://pwgen/passwords?amount=2")
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var passwords []string
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&passwords))
+		assert.Len(t, passwords, 2)
+		resp.Body.Close()
+	}
+
+	// and everything shuts down gracefully
+	stop <- os.Interrupt
+	assert.NoError(t, <-done)
+	n, err = notify.Read(state)
+	assert.NoError(t, err)
+	assert.Equal(t, "STOPPING=1", string(state[:n]))
+}
+
+func Test_listenUnix_replaces_old_socket(t *testing.T) {
+	// given a socket left over by a previous run
+	dir, err := ioutil.TempDir("", "pwgen")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "pwgen.sock")
+	old, err := net.Listen("unix", path)
+	assert.NoError(t, err)
+	old.(*net.UnixListener).SetUnlinkOnClose(false)
+	old.Close()
+
+	// when
+	l, err := listenUnix(path)
+
+	// then
+	assert.NoError(t, err)
+	if l != nil {
+		l.Close()
+	}
+}
+
+func Test_listen_withError(t *testing.T) {
+	// given a unix socket which cannot be created
+	cfg = config{Host: "127.0.0.1", Port: freePort(t), UnixSocket: "/nonexistent/pwgen.sock"}
+
+	// when
+	api, redirect, err := listen()
+
+	// then nothing listens anymore
+	assert.Error(t, err)
+	assert.Empty(t, api)
+	assert.Empty(t, redirect)
+	l, err := net.Listen("tcp", address(cfg.Port))
+	if assert.NoError(t, err) {
+		l.Close()
+	}
+}
+
+func Test_createServer_address(t *testing.T) {
+	// given
+	cfg = config{Host: "::1", Port: 9443}
+	defer func() { cfg = config{} }()
+
+	// when
+	server := createServer(nil)
+
+	// then the configured address is used
+	assert.Equal(t, "[::1]:9443", server.Addr)
+}
+
+func Test_run_encrypted_inline_key(t *testing.T) {
+	// given the certificate and an encrypted key as inline PEM
+	cert, err := ioutil.ReadFile("../../cert.pem")
+	assert.NoError(t, err)
+	key, err := ioutil.ReadFile("../../key.pem")
+	assert.NoError(t, err)
+	cfg = config{
+		CertPEM:       string(cert),
+		KeyPEM:        string(key),
+		KeyPassphrase: "pwgen",
+		Host:          "127.0.0.1",
+		Port:          freePort(t),
+		GracePeriod:   5 * time.Second,
+	}
+
+	// and our started app
+	stop := make(chan os.Signal, 1)
+	done := make(chan error)
+	go func() { done <- run(stop) }()
+	<-time.After(100 * time.Millisecond)
+
+	// when
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+	resp, err := client.Get(fmt.Sprintf("https://127.0.0.1:%d/passwords", cfg.Port))
+
+	// then the decrypted key is used
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		resp.Body.Close()
+	}
+	stop <- os.Interrupt
+	assert.NoError(t, <-done)
+}
+
+func Test_run_wrong_passphrase(t *testing.T) {
+	// given an encrypted key with a wrong passphrase
+	cfg = config{CertFile: "../../cert.pem", KeyFile: "../../key.pem", KeyPassphrase: "wrong"}
+
+	// when
+	err := run(nil)
+
+	// then
+	assert.Error(t, err)
+}
+
+// writeCert writes a self signed certificate for the name and its key to the directory and returns both files
+func writeCert(t *testing.T, dir, name string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile := filepath.Join(dir, name+".pem"), filepath.Join(dir, name+".key")
+	for file, block := range map[string]*pem.Block{certFile: {Type: "CERTIFICATE", Bytes: der}, keyFile: {Type: "PRIVATE KEY", Bytes: keyDER}} {
+		// A later modification time marks the file as changed, even if the file system can not tell quick writes apart
+		modTime := time.Now()
+		if info, err := os.Stat(file); err == nil {
+			modTime = info.ModTime().Add(time.Second)
+		}
+		if err := ioutil.WriteFile(file, pem.EncodeToMemory(block), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(file, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return certFile, keyFile
+}
+
+// servedCert returns the certificate the app serves for the server name
+func servedCert(t *testing.T, serverName string) *x509.Certificate {
+	conn, err := tls.Dial("tcp", address(cfg.Port), &tls.Config{ServerName: serverName, InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	return conn.ConnectionState().PeerCertificates[0]
+}
+
+// eventuallyRotated waits until the served certificate for the server name differs from the given one
+func eventuallyRotated(t *testing.T, serverName string, old *x509.Certificate) *x509.Certificate {
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		cert := servedCert(t, serverName)
+		if cert.SerialNumber.Cmp(old.SerialNumber) != 0 || time.Now().After(deadline) {
+			return cert
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func Test_run_certificates(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		interval time.Duration
+		reload   func(t *testing.T)
+	}{
+		{
+			desc: "SIGHUP",
+			reload: func(t *testing.T) {
+				p, err := os.FindProcess(os.Getpid())
+				assert.NoError(t, err)
+				assert.NoError(t, p.Signal(syscall.SIGHUP))
+			},
+		},
+		{
+			desc:     "Changed files",
+			interval: 10 * time.Millisecond,
+			reload:   func(*testing.T) {},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// given a default certificate and one selected by SNI
+			dir, err := ioutil.TempDir("", "pwgen")
+			assert.NoError(t, err)
+			defer os.RemoveAll(dir)
+			certFile, keyFile := writeCert(t, dir, "a.test")
+			sniCert, sniKey := writeCert(t, dir, "b.test")
+			cfg = config{
+				CertFile:           certFile,
+				KeyFile:            keyFile,
+				SNICerts:           []string{sniCert + ":" + sniKey},
+				CertReloadInterval: tC.interval,
+				Host:               "127.0.0.1",
+				Port:               freePort(t),
+				GracePeriod:        5 * time.Second,
+			}
+
+			// and our started app
+			stop := make(chan os.Signal, 1)
+			done := make(chan error)
+			go func() { done <- run(stop) }()
+			<-time.After(100 * time.Millisecond)
+
+			// then certificates are selected by SNI
+			old := servedCert(t, "a.test")
+			assert.Equal(t, "a.test", old.Subject.CommonName)
+			assert.Equal(t, "b.test", servedCert(t, "b.test").Subject.CommonName)
+			assert.Equal(t, "a.test", servedCert(t, "unknown.test").Subject.CommonName)
+
+			// when the default certificate is rotated
+			writeCert(t, dir, "a.test")
+			tC.reload(t)
+
+			// then the new certificate is served without a restart
+			rotated := eventuallyRotated(t, "a.test", old)
+			assert.NotEqual(t, old.SerialNumber, rotated.SerialNumber)
+
+			// when the rotated certificate is broken
+			assert.NoError(t, ioutil.WriteFile(certFile, []byte("garbage"), 0600))
+			assert.NoError(t, os.Chtimes(certFile, time.Now().Add(time.Hour), time.Now().Add(time.Hour)))
+			tC.reload(t)
+			time.Sleep(50 * time.Millisecond)
+
+			// then the previous one is still served
+			assert.Equal(t, rotated.SerialNumber, servedCert(t, "a.test").SerialNumber)
+			stop <- os.Interrupt
+			assert.NoError(t, <-done)
+		})
+	}
+}
+
+func Test_certificateStore_withError(t *testing.T) {
+	// given an SNI certificate without key
+	cfg = config{CertFile: "../../cert.pem", KeyFile: "../../key.unencrypted.pem", SNICerts: []string{"../../cert.pem"}}
+
+	// when
+	_, err := certificateStore()
+
+	// then
+	assert.Error(t, err)
+}
+
+func Test_run_tls_policy(t *testing.T) {
+	// given a server allowing only TLS 1.3 and HTTP/1.1 with HSTS
+	cfg = config{
+		CertFile:              "../../cert.pem",
+		KeyFile:               "../../key.unencrypted.pem",
+		TLSMinVersion:         "1.3",
+		TLSALPN:               []string{"http/1.1"},
+		HSTSMaxAge:            time.Hour,
+		HSTSIncludeSubDomains: true,
+		Host:                  "127.0.0.1",
+		Port:                  freePort(t),
+		GracePeriod:           5 * time.Second,
+	}
+	stop := make(chan os.Signal, 1)
+	done := make(chan error)
+	go func() { done <- run(stop) }()
+	<-time.After(100 * time.Millisecond)
+	addr := address(cfg.Port)
+
+	// when a client only supports TLS 1.2
+	_, err := tls.Dial("tcp", addr, &tls.Config{InsecureSkipVerify: true, MaxVersion: tls.VersionTLS12})
+
+	// then the handshake fails
+	assert.Error(t, err)
+
+	// when a client prefers HTTP/2
+	conn, err := tls.Dial("tcp", addr, &tls.Config{InsecureSkipVerify: true, NextProtos: []string{"h2", "http/1.1"}})
+
+	// then TLS 1.3 and HTTP/1.1 are negotiated
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS13), conn.ConnectionState().Version)
+	assert.Equal(t, "http/1.1", conn.ConnectionState().NegotiatedProtocol)
+	conn.Close()
+
+	// when passwords are requested
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+	resp, err := client.Get("https://" + addr + "/passwords")
+
+	// then the security headers are set
+	assert.NoError(t, err)
+	assert.Equal(t, "max-age=3600; includeSubDomains", resp.Header.Get("Strict-Transport-Security"))
+	assert.Equal(t, "no-store", resp.Header.Get("Cache-Control"))
+	assert.Equal(t, "nosniff", resp.Header.Get("X-Content-Type-Options"))
+	assert.Equal(t, "no-referrer", resp.Header.Get("Referrer-Policy"))
+	resp.Body.Close()
+	stop <- os.Interrupt
+	assert.NoError(t, <-done)
+}
+
+func Test_run_invalid_tls_policy(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		policy func(*config)
+	}{
+		{desc: "Unknown version", policy: func(c *config) { c.TLSMinVersion = "2.0" }},
+		{desc: "Maximum below minimum", policy: func(c *config) { c.TLSMinVersion, c.TLSMaxVersion = "1.3", "1.2" }},
+		{desc: "Insecure cipher suite", policy: func(c *config) { c.TLSCipherSuites = []string{"TLS_RSA_WITH_RC4_128_SHA"} }},
+		{desc: "CBC cipher suite", policy: func(c *config) { c.TLSCipherSuites = []string{"TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA"} }},
+		{desc: "Unknown curve", policy: func(c *config) { c.TLSCurves = []string{"P-192"} }},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// given
+			cfg = config{CertFile: "../../cert.pem", KeyFile: "../../key.unencrypted.pem", Port: freePort(t)}
+			tC.policy(&cfg)
+
+			// when
+			err := run(make(chan os.Signal, 1))
+
+			// then
+			assert.Error(t, err)
+		})
+	}
+}
+
+func Test_run_dev_certificate(t *testing.T) {
+	// given the development certificate without certificate files
+	dir, err := ioutil.TempDir("", "pwgen")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	cfg = config{
+		CertFile:     "missing.pem",
+		KeyFile:      "missing.key",
+		DevCert:      true,
+		DevCertHosts: []string{"pwgen.test"},
+		DevCertDir:   dir,
+		Host:         "127.0.0.1",
+		Port:         freePort(t),
+		GracePeriod:  5 * time.Second,
+	}
+	stop := make(chan os.Signal, 1)
+	done := make(chan error)
+	go func() { done <- run(stop) }()
+	<-time.After(100 * time.Millisecond)
+
+	// and a client trusting the cached development CA
+	roots := x509.NewCertPool()
+	caPEM, err := ioutil.ReadFile(filepath.Join(dir, "ca.pem"))
+	assert.NoError(t, err)
+	assert.True(t, roots.AppendCertsFromPEM(caPEM))
+
+	for _, serverName := range []string{"localhost", "pwgen.test"} {
+		// when
+		conn, err := tls.Dial("tcp", address(cfg.Port), &tls.Config{RootCAs: roots, ServerName: serverName})
+
+		// then the certificate is verified
+		assert.NoError(t, err, serverName)
+		if err == nil {
+			conn.Close()
+		}
+	}
+	stop <- os.Interrupt
+	assert.NoError(t, <-done)
+}
+
+func Test_certificateStore_without_dev_certificate(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		cfg      config
+		expected string
+	}{
+		{desc: "Missing certificate file", cfg: config{CertFile: "missing.pem", KeyFile: "missing.key"}, expected: "DEV_CERT=true"},
+		{desc: "Dev certificate with inline PEM", cfg: config{DevCert: true, CertPEM: "inline"}, expected: "CERT_PEM"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// given
+			cfg = tC.cfg
+
+			// when
+			_, err := certificateStore()
+
+			// then
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), tC.expected)
+		})
+	}
+}
+
+func Test_run_client_certificates(t *testing.T) {
+	// given a trusted client certificate, an untrusted one and a rule for the trusted one
+	dir, err := ioutil.TempDir("", "pwgen")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	trustedCert, trustedKey := writeCert(t, dir, "billing.test")
+	untrustedCert, untrustedKey := writeCert(t, dir, "other.test")
+	rulesFile := filepath.Join(dir, "rules.json")
+	assert.NoError(t, ioutil.WriteFile(rulesFile, []byte(`[{"identity": "billing.test", "routes": ["/passwords"], "profiles": ["wifi"]}]`), 0600))
+	cfg = config{
+		CertFile:        "../../cert.pem",
+		KeyFile:         "../../key.unencrypted.pem",
+		ClientCAFile:    trustedCert,
+		ClientRulesFile: rulesFile,
+		Host:            "127.0.0.1",
+		Port:            freePort(t),
+		GracePeriod:     5 * time.Second,
+	}
+	stop := make(chan os.Signal, 1)
+	done := make(chan error)
+	go func() { done <- run(stop) }()
+	<-time.After(100 * time.Millisecond)
+
+	// clientWith creates a client presenting the certificate, if any
+	clientWith := func(certFile, keyFile string) *http.Client {
+		config := &tls.Config{InsecureSkipVerify: true}
+		if certFile != "" {
+			cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+			assert.NoError(t, err)
+			config.Certificates = []tls.Certificate{cert}
+		}
+		return &http.Client{Transport: &http.Transport{TLSClientConfig: config}}
+	}
+	testCases := []struct {
+		desc           string
+		client         *http.Client
+		path           string
+		expectedErr    bool
+		expectedStatus int
+	}{
+		{desc: "No certificate", client: clientWith("", ""), path: "/passwords?profile=wifi", expectedErr: true},
+		{desc: "Untrusted certificate", client: clientWith(untrustedCert, untrustedKey), path: "/passwords?profile=wifi", expectedErr: true},
+		{desc: "Allowed route and profile", client: clientWith(trustedCert, trustedKey), path: "/passwords?profile=wifi", expectedStatus: http.StatusOK},
+		{desc: "Other profile", client: clientWith(trustedCert, trustedKey), path: "/passwords?profile=db", expectedStatus: http.StatusForbidden},
+		{desc: "Other route", client: clientWith(trustedCert, trustedKey), path: "/otp", expectedStatus: http.StatusForbidden},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// when
+			resp, err := tC.client.Get("https://" + address(cfg.Port) + tC.path)
+
+			// then
+			if tC.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tC.expectedStatus, resp.StatusCode)
+			resp.Body.Close()
+		})
+	}
+	stop <- os.Interrupt
+	assert.NoError(t, <-done)
+}
+
+func Test_authentication_withError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pwgen")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	invalidRules := filepath.Join(dir, "rules.json")
+	assert.NoError(t, ioutil.WriteFile(invalidRules, []byte(`[{"identity": "billing.test"}]`), 0600))
+	emptyKeys := filepath.Join(dir, "keys.txt")
+	assert.NoError(t, ioutil.WriteFile(emptyKeys, []byte("# no keys yet\n"), 0600))
+
+	testCases := []struct {
+		desc string
+		cfg  config
+	}{
+		{desc: "Rules without client CA", cfg: config{ClientRulesFile: invalidRules}},
+		{desc: "Missing rules", cfg: config{ClientCAFile: "../../cert.pem", ClientRulesFile: "missing.json"}},
+		{desc: "Invalid rules", cfg: config{ClientCAFile: "../../cert.pem", ClientRulesFile: invalidRules}},
+		{desc: "Invalid API key", cfg: config{APIKeys: []string{"ci:secret"}}},
+		{desc: "Missing API keys file", cfg: config{APIKeysFile: "missing.txt"}},
+		{desc: "API keys file without keys", cfg: config{APIKeysFile: emptyKeys}},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// given
+			cfg = tC.cfg
+
+			// when
+			auth, err := authentication()
+
+			// then
+			assert.Error(t, err)
+			assert.Nil(t, auth)
+		})
+	}
+}
+
+func Test_run_api_keys(t *testing.T) {
+	// given a client certificate and API keys from the environment and a file, with a rule for one of the keys
+	dir, err := ioutil.TempDir("", "pwgen")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	clientCert, clientKey := writeCert(t, dir, "billing.test")
+	hash := func(key string) string {
+		sum := sha256.Sum256([]byte(key))
+		return hex.EncodeToString(sum[:])
+	}
+	keysFile := filepath.Join(dir, "keys.txt")
+	assert.NoError(t, ioutil.WriteFile(keysFile, []byte("# rotated in 2026\nnew:"+hash("new-secret")+"\n"), 0600))
+	rulesFile := filepath.Join(dir, "rules.json")
+	assert.NoError(t, ioutil.WriteFile(rulesFile, []byte(`[
+		{"identity": "billing.test", "routes": ["*"]},
+		{"identity": "key:new", "routes": ["*"]},
+		{"identity": "key:old", "routes": ["/otp"]}
+	]`), 0600))
+	cfg = config{
+		CertFile:        "../../cert.pem",
+		KeyFile:         "../../key.unencrypted.pem",
+		ClientCAFile:    clientCert,
+		ClientRulesFile: rulesFile,
+		APIKeys:         []string{"old:" + hash("old-secret")},
+		APIKeysFile:     keysFile,
+		Host:            "127.0.0.1",
+		Port:            freePort(t),
+		GracePeriod:     5 * time.Second,
+	}
+	stop := make(chan os.Signal, 1)
+	done := make(chan error)
+	go func() { done <- run(stop) }()
+	<-time.After(100 * time.Millisecond)
+
+	cert, err := tls.LoadX509KeyPair(clientCert, clientKey)
+	assert.NoError(t, err)
+	withCert := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true, Certificates: []tls.Certificate{cert}}}}
+	withoutCert := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+	testCases := []struct {
+		desc           string
+		client         *http.Client
+		key            string
+		path           string
+		expectedStatus int
+	}{
+		{desc: "Client certificate", client: withCert, path: "/passwords", expectedStatus: http.StatusOK},
+		{desc: "Key from file", client: withoutCert, key: "new-secret", path: "/passwords", expectedStatus: http.StatusOK},
+		{desc: "Key from environment", client: withoutCert, key: "old-secret", path: "/otp", expectedStatus: http.StatusOK},
+		{desc: "Route not allowed for key", client: withoutCert, key: "old-secret", path: "/passwords", expectedStatus: http.StatusForbidden},
+		{desc: "Unknown key", client: withoutCert, key: "guessed", path: "/passwords", expectedStatus: http.StatusUnauthorized},
+		{desc: "No credentials", client: withoutCert, path: "/passwords", expectedStatus: http.StatusUnauthorized},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// given
+			req, _ := http.NewRequest(http.MethodGet, "https://"+address(cfg.Port)+tC.path, nil)
+			if tC.key != "" {
+				req.Header.Set("Authorization", "Bearer "+tC.key)
+			}
+
+			// when
+			resp, err := tC.client.Do(req)
+
+			// then
+			assert.NoError(t, err)
+			assert.Equal(t, tC.expectedStatus, resp.StatusCode)
+			resp.Body.Close()
+		})
+	}
+	stop <- os.Interrupt
+	assert.NoError(t, <-done)
+}
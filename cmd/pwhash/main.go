@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/domano/pwgen/internal/passhash"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// pwhash reads passwords line by line from stdin and prints their hashes in the same order
+func main() {
+	err := run(os.Args[1:], os.Stdin, os.Stdout)
+	if err != nil {
+		log.WithError(err).Fatalln("Could not hash passwords.")
+	}
+}
+
+func run(args []string, in io.Reader, out io.Writer) error {
+	flags := flag.NewFlagSet("pwhash", flag.ContinueOnError)
+	algorithm := flags.String("algorithm", passhash.Bcrypt, "Hash algorithm, one of "+strings.Join(passhash.Algorithms, ", "))
+	cost := flags.Int("cost", 0, "Work factor of the algorithm, 0 selects its default")
+	user := flags.String("user", "", "Prints htpasswd lines for this user, requires apr1 or bcrypt")
+	if err := flags.Parse(args); err != nil {
+		return errors.Wrap(err, "Could not parse arguments")
+	}
+
+	hasher, err := newHasher(*algorithm, *cost, *user)
+	if err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		hash, err := hasher.Hash(scanner.Text())
+		if err != nil {
+			return errors.Wrap(err, "Could not hash password")
+		}
+		if _, err := fmt.Fprintln(out, hash); err != nil {
+			return errors.Wrap(err, "Could not write hash")
+		}
+	}
+	return errors.Wrap(scanner.Err(), "Could not read passwords")
+}
+
+func newHasher(algorithm string, cost int, user string) (passhash.Hasher, error) {
+	if user != "" {
+		return passhash.NewHtpasswd(user, algorithm, cost)
+	}
+	return passhash.New(algorithm, cost)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_run(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		args        []string
+		input       string
+		expected    []string
+		expectedErr bool
+	}{
+		{
+			desc:     "One hash per line",
+			args:     []string{"-algorithm", "sha512-crypt", "-cost", "1000"},
+			input:    "first\nsecond\n",
+			expected: []string{"$6$rounds=1000$", "$6$rounds=1000$"},
+		},
+		{
+			desc:     "LDAP",
+			args:     []string{"-algorithm", "ssha512"},
+			input:    "secret",
+			expected: []string{"{SSHA512}"},
+		},
+		{
+			desc:     "htpasswd",
+			args:     []string{"-algorithm", "apr1", "-user", "alice"},
+			input:    "secret\n",
+			expected: []string{"alice:$apr1$"},
+		},
+		{
+			desc:        "Unknown algorithm",
+			args:        []string{"-algorithm", "md5"},
+			input:       "secret\n",
+			expectedErr: true,
+		},
+		{
+			desc:        "Unknown flag",
+			args:        []string{"-rounds", "5"},
+			expectedErr: true,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// given
+			var out bytes.Buffer
+
+			// when
+			err := run(tC.args, strings.NewReader(tC.input), &out)
+
+			// then
+			assert.Equal(t, tC.expectedErr, err != nil)
+			if tC.expectedErr {
+				return
+			}
+			lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+			assert.Len(t, lines, len(tC.expected))
+			for i, prefix := range tC.expected {
+				assert.True(t, strings.HasPrefix(lines[i], prefix), lines[i])
+			}
+		})
+	}
+}
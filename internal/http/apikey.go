@@ -0,0 +1,123 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// identityKeyPrefix marks identities of callers authenticated by an API key, so rules can tell them from certificates
+const identityKeyPrefix = "key:"
+
+// apiKey is a SHA-256 hash of a key and the ID it is known by
+type apiKey struct {
+	id   string
+	hash []byte
+}
+
+// APIKeys are the hashes of the keys accepted as bearer tokens.
+// Several keys may be active at once, so a new key can be rolled out before the old one is removed.
+type APIKeys []apiKey
+
+// ParseAPIKeys reads keys as id:hash lines, where the hash is the hex encoded SHA-256 hash of the key.
+// Empty lines and lines starting with # are skipped.
+func ParseAPIKeys(r io.Reader) (APIKeys, error) {
+	var keys APIKeys
+	ids := map[string]bool{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, errors.New("API keys must be given as id:hash")
+		}
+		hash, err := hex.DecodeString(parts[1])
+		if err != nil || len(hash) != sha256.Size {
+			return nil, errors.Errorf("Hash of API key %s is no hex encoded SHA-256 hash", parts[0])
+		}
+		if ids[parts[0]] {
+			return nil, errors.Errorf("API key %s is given more than once", parts[0])
+		}
+		ids[parts[0]] = true
+		keys = append(keys, apiKey{id: parts[0], hash: hash})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "Could not read API keys")
+	}
+	return keys, nil
+}
+
+// lookup returns the ID of the key, every hash is compared in constant time so the position of a match does not leak
+func (keys APIKeys) lookup(key string) (string, bool) {
+	hash := sha256.Sum256([]byte(key))
+	var id string
+	found := 0
+	for _, k := range keys {
+		match := subtle.ConstantTimeCompare(hash[:], k.hash)
+		if match == 1 {
+			id = k.id
+		}
+		found |= match
+	}
+	return id, found == 1
+}
+
+// KeyIDFromContext returns the ID of the API key the caller authenticated with, empty if none was used
+func KeyIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(keyIDKey).(string)
+	return id
+}
+
+// APIKeyHandlerFunc wraps a given http.Handler with a middleware which authenticates callers by
+// Authorization: Bearer keys. Callers already identified by a client certificate do not need a key.
+func APIKeyHandlerFunc(next http.Handler, keys APIKeys) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordCaller(r.Context())
+		authorization := r.Header.Get("Authorization")
+		if authorization == "" && IdentityFromContext(r.Context()) != "" {
+			if next != nil {
+				next.ServeHTTP(w, r)
+			}
+			return
+		}
+
+		// The scheme is case insensitive as defined by RFC 7235
+		token := ""
+		if len(authorization) > len("Bearer ") && strings.EqualFold(authorization[:len("Bearer ")], "Bearer ") {
+			token = strings.TrimSpace(authorization[len("Bearer "):])
+		}
+		if token == "" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="pwgen"`)
+			writeProblem(w, r, http.StatusUnauthorized, errors.New("Request carries no bearer token"))
+			log.WithField("remote", r.RemoteAddr).Warnln("Received an unauthenticated request.")
+			return
+		}
+		id, ok := keys.lookup(token)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="pwgen", error="invalid_token"`)
+			writeProblem(w, r, http.StatusUnauthorized, errors.New("Bearer token is no valid API key"))
+			log.WithField("remote", r.RemoteAddr).Warnln("Received a request with an invalid API key.")
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), keyIDKey, id)
+		if IdentityFromContext(ctx) == "" {
+			ctx = WithIdentity(ctx, identityKeyPrefix+id)
+		}
+		recordCaller(ctx)
+		if next != nil {
+			next.ServeHTTP(w, r.WithContext(ctx))
+		}
+	}
+}
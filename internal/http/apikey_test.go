@@ -0,0 +1,165 @@
+package http
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// keyLine returns the id:hash line of the key
+func keyLine(id, key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return id + ":" + hex.EncodeToString(hash[:])
+}
+
+func TestParseAPIKeys(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		keys        string
+		expectedIDs []string
+		expectedErr bool
+	}{
+		{desc: "Several keys", keys: keyLine("old", "a") + "\n" + keyLine("new", "b"), expectedIDs: []string{"old", "new"}},
+		{desc: "Comments and empty lines", keys: "# rotated 2026-10\n\n  " + keyLine("ci", "a") + "  \n", expectedIDs: []string{"ci"}},
+		{desc: "None"},
+		{desc: "Missing hash", keys: "ci", expectedErr: true},
+		{desc: "Missing ID", keys: keyLine("", "a"), expectedErr: true},
+		{desc: "No hex", keys: "ci:secret", expectedErr: true},
+		{desc: "No SHA-256 hash", keys: "ci:abcd", expectedErr: true},
+		{desc: "Duplicate ID", keys: keyLine("ci", "a") + "\n" + keyLine("ci", "b"), expectedErr: true},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// when
+			keys, err := ParseAPIKeys(strings.NewReader(tC.keys))
+
+			// then
+			if tC.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			var ids []string
+			for _, k := range keys {
+				ids = append(ids, k.id)
+			}
+			assert.Equal(t, tC.expectedIDs, ids)
+		})
+	}
+}
+
+func TestAPIKeyHandlerFunc(t *testing.T) {
+	// given an old and a new key during a rotation
+	keys, err := ParseAPIKeys(strings.NewReader(keyLine("old", "old-secret") + "\n" + keyLine("new", "new-secret")))
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		desc              string
+		authorization     string
+		identity          string
+		expectedStatus    int
+		expectedKeyID     string
+		expectedIdentity  string
+		expectedChallenge string
+	}{
+		{desc: "Old key", authorization: "Bearer old-secret", expectedStatus: http.StatusOK, expectedKeyID: "old", expectedIdentity: "key:old"},
+		{desc: "New key", authorization: "Bearer new-secret", expectedStatus: http.StatusOK, expectedKeyID: "new", expectedIdentity: "key:new"},
+		{desc: "Case insensitive scheme", authorization: "bearer new-secret", expectedStatus: http.StatusOK, expectedKeyID: "new", expectedIdentity: "key:new"},
+		{desc: "Client certificate", identity: "billing.test", expectedStatus: http.StatusOK, expectedIdentity: "billing.test"},
+		{desc: "Client certificate and key", authorization: "Bearer new-secret", identity: "billing.test", expectedStatus: http.StatusOK, expectedKeyID: "new", expectedIdentity: "billing.test"},
+		{desc: "No credentials", expectedStatus: http.StatusUnauthorized, expectedChallenge: `Bearer realm="pwgen"`},
+		{desc: "Other scheme", authorization: "Basic b2xkLXNlY3JldA==", expectedStatus: http.StatusUnauthorized, expectedChallenge: `Bearer realm="pwgen"`},
+		{desc: "Unknown key", authorization: "Bearer guessed", expectedStatus: http.StatusUnauthorized, expectedChallenge: `Bearer realm="pwgen", error="invalid_token"`},
+		{desc: "Invalid key despite client certificate", authorization: "Bearer guessed", identity: "billing.test", expectedStatus: http.StatusUnauthorized, expectedChallenge: `Bearer realm="pwgen", error="invalid_token"`},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// given
+			req := httptest.NewRequest(http.MethodGet, "/passwords", nil)
+			if tC.authorization != "" {
+				req.Header.Set("Authorization", tC.authorization)
+			}
+			if tC.identity != "" {
+				req = req.WithContext(WithIdentity(req.Context(), tC.identity))
+			}
+			rc := httptest.NewRecorder()
+			var keyID, identity string
+			next := http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+				keyID, identity = KeyIDFromContext(r.Context()), IdentityFromContext(r.Context())
+			})
+
+			// when
+			APIKeyHandlerFunc(next, keys)(rc, req)
+
+			// then
+			assert.Equal(t, tC.expectedStatus, rc.Code)
+			assert.Equal(t, tC.expectedKeyID, keyID)
+			assert.Equal(t, tC.expectedIdentity, identity)
+			assert.Equal(t, tC.expectedChallenge, rc.Header().Get("WWW-Authenticate"))
+		})
+	}
+}
+
+func TestAPIKeyHandlerFunc_Access_Log(t *testing.T) {
+	// given some writer to test our log output
+	logBuffer := bytes.NewBufferString("")
+	logrus.SetOutput(logBuffer)
+	keys, err := ParseAPIKeys(strings.NewReader(keyLine("ci", "secret")))
+	assert.NoError(t, err)
+	req := httptest.NewRequest(http.MethodGet, "/passwords", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+
+	// when
+	APIKeyHandlerFunc(LoggingHandlerFunc(nil), keys)(httptest.NewRecorder(), req)
+
+	// then the key is logged by its ID only
+	body := logBuffer.String()
+	assert.Contains(t, body, "key_id=ci")
+	assert.Contains(t, body, "identity=\"key:ci\"")
+	assert.NotContains(t, body, "secret")
+}
+
+func TestAPIKeyHandlerFunc_Access_Log_Denied(t *testing.T) {
+	// given some writer to test our log output
+	logBuffer := bytes.NewBufferString("")
+	logrus.SetOutput(logBuffer)
+	keys, err := ParseAPIKeys(strings.NewReader(keyLine("ci", "secret")))
+	assert.NoError(t, err)
+	req := httptest.NewRequest(http.MethodGet, "/passwords", nil)
+	req.Header.Set("Authorization", "Bearer wrong")
+
+	// when the logging middleware wraps the authentication
+	LoggingHandlerFunc(APIKeyHandlerFunc(nil, keys))(httptest.NewRecorder(), req)
+
+	// then the rejected request is in the access log
+	body := logBuffer.String()
+	assert.Contains(t, body, "type=access")
+	assert.Contains(t, body, "response_code=401")
+	assert.NotContains(t, body, "key_id")
+}
+
+func TestAPIKeyHandlerFunc_Access_Log_Wrapped(t *testing.T) {
+	// given some writer to test our log output
+	logBuffer := bytes.NewBufferString("")
+	logrus.SetOutput(logBuffer)
+	keys, err := ParseAPIKeys(strings.NewReader(keyLine("ci", "secret")))
+	assert.NoError(t, err)
+	req := httptest.NewRequest(http.MethodGet, "/passwords", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+
+	// when the logging middleware wraps the authentication
+	LoggingHandlerFunc(APIKeyHandlerFunc(nil, keys))(httptest.NewRecorder(), req)
+
+	// then the caller found by the inner middleware is logged
+	body := logBuffer.String()
+	assert.Contains(t, body, "response_code=200")
+	assert.Contains(t, body, "key_id=ci")
+	assert.Contains(t, body, "identity=\"key:ci\"")
+}
@@ -0,0 +1,159 @@
+package http
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/domano/pwgen/internal/password"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// authContextKey is the type of the context keys of the authentication middlewares, so they can not collide with other packages
+type authContextKey int
+
+const (
+	identityKey authContextKey = iota
+	ruleKey
+	keyIDKey
+)
+
+// routeAny allows a rule to use every route
+const routeAny = "*"
+
+// errProfileForbidden is the cause of requests for profiles the caller may not use
+var errProfileForbidden = errors.New("Profile is not allowed")
+
+// Rule allows the caller with the identity to use routes and password profiles.
+// Callers restricted to profiles can only request passwords with one of them.
+type Rule struct {
+	Identity string   `json:"identity"`
+	Routes   []string `json:"routes"`
+	Profiles []string `json:"profiles,omitempty"`
+}
+
+// Rules authorize callers by their identity, callers without a rule are denied.
+// Without any rules every authenticated caller may use everything.
+type Rules map[string]Rule
+
+// ReadRules reads a JSON array of rules
+func ReadRules(r io.Reader) (Rules, error) {
+	var list []Rule
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&list); err != nil {
+		return nil, errors.Wrap(err, "Could not decode rules")
+	}
+	rules := Rules{}
+	for _, rule := range list {
+		if rule.Identity == "" {
+			return nil, errors.New("Every rule needs an identity")
+		}
+		if _, ok := rules[rule.Identity]; ok {
+			return nil, errors.Errorf("Identity %s has more than one rule", rule.Identity)
+		}
+		if len(rule.Routes) == 0 {
+			return nil, errors.Errorf("Rule of %s allows no routes", rule.Identity)
+		}
+		for _, name := range rule.Profiles {
+			if _, ok := password.LookupProfile(name); !ok {
+				return nil, errors.Errorf("Rule of %s allows unknown profile %s", rule.Identity, name)
+			}
+		}
+		rules[rule.Identity] = rule
+	}
+	return rules, nil
+}
+
+// allowsRoute reports whether the rule allows the path
+func (rule Rule) allowsRoute(path string) bool {
+	for _, route := range rule.Routes {
+		if route == routeAny || route == path {
+			return true
+		}
+	}
+	return false
+}
+
+// WithIdentity returns a copy of the context with the identity of the caller
+func WithIdentity(ctx context.Context, identity string) context.Context {
+	return context.WithValue(ctx, identityKey, identity)
+}
+
+// IdentityFromContext returns the identity of the caller, empty if the caller was not authenticated
+func IdentityFromContext(ctx context.Context) string {
+	identity, _ := ctx.Value(identityKey).(string)
+	return identity
+}
+
+// CertIdentity returns the identity of a client certificate.
+// The first URI, DNS name or email address of the subject alternative names is preferred over the common name.
+func CertIdentity(cert *x509.Certificate) string {
+	switch {
+	case len(cert.URIs) > 0:
+		return cert.URIs[0].String()
+	case len(cert.DNSNames) > 0:
+		return cert.DNSNames[0]
+	case len(cert.EmailAddresses) > 0:
+		return cert.EmailAddresses[0]
+	}
+	return cert.Subject.CommonName
+}
+
+// ClientCertHandlerFunc wraps a given http.Handler with a middleware
+// which identifies callers by their verified TLS client certificate
+func ClientCertHandlerFunc(next http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 && len(r.TLS.VerifiedChains[0]) > 0 {
+			r = r.WithContext(WithIdentity(r.Context(), CertIdentity(r.TLS.VerifiedChains[0][0])))
+		}
+		if next != nil {
+			next.ServeHTTP(w, r)
+		}
+	}
+}
+
+// AuthorizationHandlerFunc wraps a given http.Handler with a middleware
+// which only lets identified callers pass and applies their rules
+func AuthorizationHandlerFunc(next http.Handler, rules Rules) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordCaller(r.Context())
+		identity := IdentityFromContext(r.Context())
+		if identity == "" {
+			writeProblem(w, r, http.StatusUnauthorized, errors.New("Request carries no credentials"))
+			log.WithField("remote", r.RemoteAddr).Warnln("Received an unauthenticated request.")
+			return
+		}
+		if len(rules) > 0 {
+			rule, ok := rules[identity]
+			if !ok || !rule.allowsRoute(r.URL.Path) {
+				writeProblem(w, r, http.StatusForbidden, errors.Errorf("%s may not use %s", identity, r.URL.Path))
+				log.WithField("identity", identity).WithField("path", r.URL.Path).Warnln("Denied a request.")
+				return
+			}
+			r = r.WithContext(context.WithValue(r.Context(), ruleKey, rule))
+		}
+		if next != nil {
+			next.ServeHTTP(w, r)
+		}
+	}
+}
+
+// authorizeProfile checks whether the rule of the caller allows the profile, requests without profile are a profile of their own
+func authorizeProfile(ctx context.Context, profile *password.Profile) error {
+	rule, ok := ctx.Value(ruleKey).(Rule)
+	if !ok || len(rule.Profiles) == 0 {
+		return nil
+	}
+	if profile != nil {
+		for _, name := range rule.Profiles {
+			if name == profile.Name {
+				return nil
+			}
+		}
+	}
+	return invalidParam(paramProfile, errors.Wrapf(errProfileForbidden, "%s may only use the profiles %v", rule.Identity, rule.Profiles))
+}
@@ -0,0 +1,197 @@
+package http
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/domano/pwgen/internal/mock"
+	"github.com/golang/mock/gomock"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCertIdentity(t *testing.T) {
+	spiffe, _ := url.Parse("spiffe://example.org/billing")
+	testCases := []struct {
+		desc     string
+		cert     *x509.Certificate
+		expected string
+	}{
+		{desc: "URI", cert: &x509.Certificate{URIs: []*url.URL{spiffe}, DNSNames: []string{"billing.test"}, Subject: pkix.Name{CommonName: "billing"}}, expected: "spiffe://example.org/billing"},
+		{desc: "DNS name", cert: &x509.Certificate{DNSNames: []string{"billing.test"}, EmailAddresses: []string{"billing@example.org"}}, expected: "billing.test"},
+		{desc: "Email address", cert: &x509.Certificate{EmailAddresses: []string{"billing@example.org"}, Subject: pkix.Name{CommonName: "billing"}}, expected: "billing@example.org"},
+		{desc: "Common name", cert: &x509.Certificate{Subject: pkix.Name{CommonName: "billing"}}, expected: "billing"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// when
+			identity := CertIdentity(tC.cert)
+
+			// then
+			assert.Equal(t, tC.expected, identity)
+		})
+	}
+}
+
+func TestReadRules(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		rules       string
+		expectedErr bool
+	}{
+		{desc: "Valid", rules: `[{"identity": "a", "routes": ["*"]}, {"identity": "b", "routes": ["/passwords"], "profiles": ["wifi"]}]`},
+		{desc: "Empty", rules: `[]`},
+		{desc: "Missing identity", rules: `[{"routes": ["*"]}]`, expectedErr: true},
+		{desc: "Duplicate identity", rules: `[{"identity": "a", "routes": ["*"]}, {"identity": "a", "routes": ["/otp"]}]`, expectedErr: true},
+		{desc: "No routes", rules: `[{"identity": "a"}]`, expectedErr: true},
+		{desc: "Unknown profile", rules: `[{"identity": "a", "routes": ["*"], "profiles": ["ldap"]}]`, expectedErr: true},
+		{desc: "Unknown field", rules: `[{"identity": "a", "routes": ["*"], "paths": ["/otp"]}]`, expectedErr: true},
+		{desc: "No JSON", rules: `identity=a`, expectedErr: true},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// when
+			_, err := ReadRules(strings.NewReader(tC.rules))
+
+			// then
+			assert.Equal(t, tC.expectedErr, err != nil, err)
+		})
+	}
+}
+
+func TestAuthorizationHandlerFunc(t *testing.T) {
+	// given rules for two callers
+	rules, err := ReadRules(strings.NewReader(`[
+		{"identity": "admin", "routes": ["*"]},
+		{"identity": "wifi", "routes": ["/passwords"], "profiles": ["wifi"]}
+	]`))
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		desc           string
+		identity       string
+		rules          Rules
+		path           string
+		expectedStatus int
+	}{
+		{desc: "No identity", path: "/otp", expectedStatus: http.StatusUnauthorized},
+		{desc: "No rules", identity: "anyone", path: "/otp", expectedStatus: http.StatusOK},
+		{desc: "Any route", identity: "admin", rules: rules, path: "/otp", expectedStatus: http.StatusOK},
+		{desc: "Allowed route", identity: "wifi", rules: rules, path: "/passwords", expectedStatus: http.StatusOK},
+		{desc: "Other route", identity: "wifi", rules: rules, path: "/otp", expectedStatus: http.StatusForbidden},
+		{desc: "Unknown identity", identity: "anyone", rules: rules, path: "/passwords", expectedStatus: http.StatusForbidden},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// given
+			req := httptest.NewRequest(http.MethodGet, tC.path, nil)
+			if tC.identity != "" {
+				req = req.WithContext(WithIdentity(req.Context(), tC.identity))
+			}
+			rc := httptest.NewRecorder()
+
+			// when
+			AuthorizationHandlerFunc(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}), tC.rules)(rc, req)
+
+			// then
+			assert.Equal(t, tC.expectedStatus, rc.Code)
+			if tC.expectedStatus != http.StatusOK {
+				assert.Equal(t, "application/problem+json", rc.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestClientCertHandlerFunc(t *testing.T) {
+	// given a request with a verified client certificate
+	logBuffer := bytes.NewBufferString("")
+	logrus.SetOutput(logBuffer)
+	req := httptest.NewRequest(http.MethodGet, "https://www.test.de/test", nil)
+	req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: "billing"}}}}}
+
+	// when
+	var identity string
+	next := LoggingHandlerFunc(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		identity = IdentityFromContext(r.Context())
+	}))
+	ClientCertHandlerFunc(next)(httptest.NewRecorder(), req)
+
+	// then the identity is passed on and logged
+	assert.Equal(t, "billing", identity)
+	assert.Contains(t, logBuffer.String(), "identity=billing")
+}
+
+func TestAuthorizationHandlerFunc_Access_Log(t *testing.T) {
+	// given some writer to test our log output
+	logBuffer := bytes.NewBufferString("")
+	logrus.SetOutput(logBuffer)
+
+	// and a caller which may not use the route
+	rules, err := ReadRules(strings.NewReader(`[{"identity": "billing", "routes": ["/otp"]}]`))
+	assert.NoError(t, err)
+	req := httptest.NewRequest(http.MethodGet, "https://www.test.de/passwords", nil)
+	req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: "billing"}}}}}
+
+	// when the logging middleware wraps the authentication
+	LoggingHandlerFunc(ClientCertHandlerFunc(AuthorizationHandlerFunc(nil, rules)))(httptest.NewRecorder(), req)
+
+	// then the denied request is in the access log
+	body := logBuffer.String()
+	assert.Contains(t, body, "type=access")
+	assert.Contains(t, body, "response_code=403")
+	assert.Contains(t, body, "identity=billing")
+}
+
+func TestPasswordHandler_profile_rules(t *testing.T) {
+	// given a caller which may only use the wifi profile
+	rules, err := ReadRules(strings.NewReader(`[{"identity": "router", "routes": ["*"], "profiles": ["wifi"]}]`))
+	assert.NoError(t, err)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	passworder := mock.NewMockPassworder(ctrl)
+	passworder.EXPECT().Passwords(gomock.Any(), gomock.Any(), gomock.Any()).Return([]string{"abcdefgh"}, nil).AnyTimes()
+	h := AuthorizationHandlerFunc(NewPasswordHandler(passworder, DefaultLimits), rules)
+
+	testCases := []struct {
+		desc           string
+		req            *http.Request
+		expectedStatus int
+	}{
+		{desc: "Allowed profile", req: httptest.NewRequest(http.MethodGet, "/passwords?profile=wifi", nil), expectedStatus: http.StatusOK},
+		{desc: "Profile implied by SSID", req: httptest.NewRequest(http.MethodGet, "/passwords?ssid=home&format=qr", nil), expectedStatus: http.StatusOK},
+		{desc: "Other profile", req: httptest.NewRequest(http.MethodGet, "/passwords?profile=db", nil), expectedStatus: http.StatusForbidden},
+		{desc: "No profile", req: httptest.NewRequest(http.MethodGet, "/passwords", nil), expectedStatus: http.StatusForbidden},
+		{desc: "Posted profile", req: postPolicy(`{"profile": "db"}`), expectedStatus: http.StatusForbidden},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// given
+			rc := httptest.NewRecorder()
+
+			// when
+			h(rc, tC.req.WithContext(WithIdentity(tC.req.Context(), "router")))
+
+			// then
+			assert.Equal(t, tC.expectedStatus, rc.Code, rc.Body.String())
+			if tC.expectedStatus == http.StatusForbidden {
+				var p problem
+				assert.NoError(t, json.Unmarshal(rc.Body.Bytes(), &p))
+				assert.Equal(t, paramProfile, p.Param)
+			}
+		})
+	}
+}
+
+func postPolicy(policy string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/passwords", strings.NewReader(policy))
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
@@ -0,0 +1,19 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// DeprecationHandlerFunc wraps a given http.Handler with headers announcing that its route is deprecated
+// since the given time as defined by RFC 9745 and linking to its successor in a newer API version
+func DeprecationHandlerFunc(next http.Handler, since time.Time, successor string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", fmt.Sprintf("@%d", since.Unix()))
+		w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, successor))
+		if next != nil {
+			next.ServeHTTP(w, r)
+		}
+	}
+}
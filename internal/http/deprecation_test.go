@@ -0,0 +1,27 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeprecationHandlerFunc(t *testing.T) {
+	// given a test handler to check if it was called as next
+	var called bool
+	next := http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {
+		called = true
+	})
+	rc := httptest.NewRecorder()
+
+	// when
+	DeprecationHandlerFunc(next, time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC), "/v2/passwords")(rc, httptest.NewRequest(http.MethodGet, "/passwords", nil))
+
+	// then
+	assert.True(t, called)
+	assert.Equal(t, "@1792368000", rc.Header().Get("Deprecation"))
+	assert.Equal(t, `</v2/passwords>; rel="successor-version"`, rc.Header().Get("Link"))
+}
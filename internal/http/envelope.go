@@ -0,0 +1,109 @@
+package http
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"net/http"
+	"regexp"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// headerRequestID carries the ID of a request, IDs sent by clients are reused in the response
+const headerRequestID = "X-Request-ID"
+
+// generatorRandom names the generator which picks every character at random
+const generatorRandom = "random"
+
+// validRequestID matches request IDs sent by clients which are safe to echo and log
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// PasswordHandlerV2 accepts the same requests as the PasswordHandler,
+// but wraps JSON responses in an envelope with metadata about the passwords
+type PasswordHandlerV2 struct {
+	PasswordHandler
+}
+
+// NewPasswordHandlerV2 constructs a new PasswordHandlerV2 using the given Passworder within the limits
+func NewPasswordHandlerV2(p Passworder, limits Limits) *PasswordHandlerV2 {
+	return &PasswordHandlerV2{PasswordHandler{Passworder: p, Limits: limits}}
+}
+
+func (ph *PasswordHandlerV2) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ph.serve(w, r, true)
+}
+
+// envelope is the JSON response of the PasswordHandlerV2
+type envelope struct {
+	RequestID string           `json:"requestId"`
+	Generator string           `json:"generator"`
+	Policy    policyDocument   `json:"policy"`
+	Passwords []envelopeResult `json:"passwords"`
+}
+
+// envelopeResult adds the estimated entropy to a password
+type envelopeResult struct {
+	passwordResult
+	EntropyBits float64 `json:"entropyBits"`
+}
+
+// renderEnvelope returns the passwords in an envelope with the effective policy of the request
+func (o output) renderEnvelope(ctx context.Context, req passwordRequest, gen generated, requestID string) (string, []byte, error) {
+	results, err := o.results(ctx, gen.passwords)
+	if err != nil {
+		return "", nil, err
+	}
+	env := envelope{
+		RequestID: requestID,
+		Generator: generatorRandom,
+		Policy:    effectivePolicy(req, gen),
+		Passwords: make([]envelopeResult, len(results)),
+	}
+	for i, r := range results {
+		// Two decimals are plenty for an estimate
+		env.Passwords[i] = envelopeResult{r, math.Round(gen.entropy[i]*100) / 100}
+	}
+	body, err := json.Marshal(env)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "Error while marshalling json")
+	}
+	return "application/json", body, nil
+}
+
+// effectivePolicy describes the policy after the profile was applied with the fields of posted policies
+func effectivePolicy(req passwordRequest, gen generated) policyDocument {
+	separator := req.separator
+	doc := policyDocument{
+		Amount:          len(gen.passwords),
+		MinLength:       gen.policy.MinLength,
+		MaxLength:       gen.policy.MaxLength,
+		SpecialChars:    gen.policy.SpecialChars,
+		Numbers:         gen.policy.Numbers,
+		Swap:            gen.policy.Swap,
+		SpecialCharSet:  gen.policy.SpecialCharSet,
+		Exclude:         gen.policy.Exclude,
+		Group:           req.group,
+		Separator:       &separator,
+		CountSeparators: req.countSeparators,
+	}
+	if req.profile != nil {
+		doc.Profile = req.profile.Name
+	}
+	return doc
+}
+
+// requestIDFromHeader reuses a valid request ID of the client or creates a random one
+func requestIDFromHeader(r *http.Request) string {
+	if id := r.Header.Get(headerRequestID); validRequestID.MatchString(id) {
+		return id
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		log.WithError(err).Errorln("Could not create request ID")
+	}
+	return hex.EncodeToString(id)
+}
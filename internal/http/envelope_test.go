@@ -0,0 +1,115 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/domano/pwgen/internal/mock"
+	"github.com/domano/pwgen/internal/password"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasswordHandlerV2_ServeHTTP(t *testing.T) {
+	testCases := []struct {
+		desc                string
+		query               string
+		expectedPolicy      password.Policy
+		expectedContentType string
+		expectedBody        string
+	}{
+		{
+			desc:                "Envelope with metadata",
+			query:               "?minLength=4&numbers=4&amount=2",
+			expectedPolicy:      password.Policy{MinLength: 4, Numbers: 4},
+			expectedContentType: "application/json",
+			expectedBody: `{"requestId":"req-1","generator":"random","policy":{"amount":2,"minLength":4,"maxLength":0,"specialChars":0,"numbers":4,"swap":false,"specialCharSet":"","exclude":"","profile":"","group":0,"separator":"-","countSeparators":false},` +
+				`"passwords":[{"password":"1234","entropyBits":13.29},{"password":"1234","entropyBits":13.29}]}`,
+		},
+		{
+			desc:                "Effective policy of a profile with details",
+			query:               "?minLength=4&numbers=4&profile=db&spelling=en&group=2",
+			expectedPolicy:      password.Policy{MinLength: 4, Numbers: 4, Exclude: password.Database.Exclude},
+			expectedContentType: "application/json",
+			expectedBody: `{"requestId":"req-1","generator":"random","policy":{"amount":1,"minLength":4,"maxLength":0,"specialChars":0,"numbers":4,"swap":false,"specialCharSet":"","exclude":"'\"` + "`" + `\\","profile":"db","group":2,"separator":"-","countSeparators":false},` +
+				`"passwords":[{"password":"12-34","spelling":"one, two, Hyphen, three, four","entropyBits":13.29}]}`,
+		},
+		{
+			desc:                "Other formats are rendered like v1",
+			query:               "?minLength=4&numbers=4&format=text",
+			expectedPolicy:      password.Policy{MinLength: 4, Numbers: 4},
+			expectedContentType: "text/plain; charset=utf-8",
+			expectedBody:        "1234\n",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// given a handler with a mocked password generator
+			mockPassworder := mock.NewMockPassworder(gomock.NewController(t))
+			ph := NewPasswordHandlerV2(mockPassworder, Limits{})
+			rc := httptest.NewRecorder()
+
+			// and a request with an ID
+			req := httptest.NewRequest(http.MethodGet, "/v2/passwords"+tC.query, nil)
+			req.Header.Set(headerRequestID, "req-1")
+
+			// expect the generator to be called
+			mockPassworder.EXPECT().Passwords(gomock.Any(), gomock.Any(), tC.expectedPolicy).DoAndReturn(func(_ context.Context, amount int, _ password.Policy) ([]string, error) {
+				passwords := make([]string, amount)
+				for i := range passwords {
+					passwords[i] = "1234"
+				}
+				return passwords, nil
+			}).Times(1)
+
+			// when
+			ph.ServeHTTP(rc, req)
+
+			// then
+			assert.Equal(t, http.StatusOK, rc.Code)
+			assert.Equal(t, tC.expectedContentType, rc.Header().Get("Content-Type"))
+			assert.Equal(t, tC.expectedBody, rc.Body.String())
+		})
+	}
+}
+
+func TestPasswordHandlerV2_ServeHTTP_Request_ID(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		requestID string
+		reused    bool
+	}{
+		{desc: "Valid ID is reused", requestID: "3f2a-b.c:1", reused: true},
+		{desc: "Missing ID"},
+		{desc: "Invalid ID is replaced", requestID: "a b"},
+		{desc: "Too long ID is replaced", requestID: strings.Repeat("a", 129)},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// given
+			ph := NewPasswordHandlerV2(PassworderFunc(func(_ context.Context, amount int, _ password.Policy) ([]string, error) {
+				return []string{"secret"}, nil
+			}), Limits{})
+			rc := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/v2/passwords", nil)
+			req.Header.Set(headerRequestID, tC.requestID)
+
+			// when
+			ph.ServeHTTP(rc, req)
+
+			// then the ID is sent in the header and the envelope
+			var env envelope
+			assert.NoError(t, json.Unmarshal(rc.Body.Bytes(), &env))
+			assert.Equal(t, env.RequestID, rc.Header().Get(headerRequestID))
+			if tC.reused {
+				assert.Equal(t, tC.requestID, env.RequestID)
+				return
+			}
+			assert.Regexp(t, "^[0-9a-f]{32}$", env.RequestID)
+		})
+	}
+}
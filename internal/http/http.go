@@ -2,7 +2,9 @@
 package http
 
 import (
-	"encoding/json"
+	"context"
+	"github.com/domano/pwgen/internal/password"
+	"github.com/domano/pwgen/internal/spelling"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"net/http"
@@ -14,6 +16,8 @@ import (
 // delivers them with the help of the included Passworder
 type PasswordHandler struct {
 	Passworder
+	// Limits bound the passwords of a single request
+	Limits Limits
 }
 
 // Constants for the available query params
@@ -22,34 +26,144 @@ const paramSpecialChars = "specialChars"
 const paramNumbers = "numbers"
 const paramAmount = "amount"
 const paramSwap = "swap"
+const paramGroup = "group"
+const paramSeparator = "separator"
+const paramCountSeparators = "countSeparators"
+const paramSpelling = "spelling"
+const paramProfile = "profile"
+const paramFormat = "format"
+const paramImage = "image"
+const paramSSID = "ssid"
+const paramHash = "hash"
+const paramCost = "cost"
+const paramUser = "user"
+const paramKeys = "keys"
+const paramName = "name"
+const paramNamespace = "namespace"
 
-// NewPasswordHandler constructs a new PasswordHandler using the given Passworder
-func NewPasswordHandler(p Passworder) *PasswordHandler {
-	return &PasswordHandler{p}
+// NewPasswordHandler constructs a new PasswordHandler using the given Passworder within the limits
+func NewPasswordHandler(p Passworder, limits Limits) *PasswordHandler {
+	return &PasswordHandler{Passworder: p, Limits: limits}
 }
 
 func (ph *PasswordHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	// As we only provide passwords currently nothing except GET is supported
-	if r.Method != http.MethodGet && r.Method != http.MethodHead {
-		w.WriteHeader(http.StatusMethodNotAllowed)
+	ph.serve(w, r, false)
+}
+
+// serve answers requests for passwords, JSON responses are wrapped in an envelope with metadata if requested
+func (ph *PasswordHandler) serve(w http.ResponseWriter, r *http.Request, envelope bool) {
+	// Passwords are requested with query params or by posting a policy
+	if r.Method != http.MethodGet && r.Method != http.MethodHead && r.Method != http.MethodPost {
+		methodNotAllowed(w, r, http.MethodGet, http.MethodHead, http.MethodPost)
 		return
 	}
 
-	pw, err := ph.passwords(r)
+	// The response depends on the Accept header unless a format is requested
+	w.Header().Set("Vary", "Accept")
+	out, err := outputFromParams(r.URL.Query(), r.Header.Get("Accept"))
+	if errors.Cause(err) == errNotAcceptable {
+		writeProblem(w, r, http.StatusNotAcceptable, err)
+		log.WithError(err).Warnln("Received a request for an unsupported media type.")
+		return
+	}
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		writeProblem(w, r, http.StatusBadRequest, err)
 		log.WithError(err).Warnln("Received a bad request.")
 		return
 	}
 
-	// Write password as json response, implicit 200 if write succeeds
-	body, err := json.Marshal(pw)
+	req, err := ph.request(r)
+	if err == nil {
+		err = authorizeProfile(r.Context(), req.profile)
+	}
+	if err == nil {
+		err = ph.Limits.check(req)
+	}
+	if err == nil && out.hasher != nil {
+		err = ph.Limits.checkHashes(req.amount)
+	}
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		log.WithError(err).Errorln("Error while marshalling json")
+		ph.requestError(w, r, err)
+		return
+	}
+
+	// Generation and hashing stop at the deadline or as soon as the client goes away
+	ctx, cancel := context.WithTimeout(r.Context(), ph.Limits.withDefaults().Timeout)
+	defer cancel()
+	gen, err := ph.passwords(ctx, req)
+	if paramOf(err) != "" {
+		writeProblem(w, r, http.StatusBadRequest, err)
+		log.WithError(err).Warnln("Received a bad request.")
+		return
+	}
+	if isContextError(err) {
+		writeProblem(w, r, http.StatusServiceUnavailable, err)
+		log.WithError(err).Warnln("Could not generate passwords in time.")
+		return
+	}
+	if err != nil {
+		writeProblem(w, r, http.StatusInternalServerError, err)
+		log.WithError(err).Errorln("Error while generating passwords")
+		return
+	}
+
+	// Render passwords in the requested format, implicit 200 if write succeeds
+	var contentType string
+	var body []byte
+	if envelope && out.format == formatJSON {
+		requestID := requestIDFromHeader(r)
+		w.Header().Set(headerRequestID, requestID)
+		contentType, body, err = out.renderEnvelope(ctx, req, gen, requestID)
+	} else {
+		contentType, body, err = out.render(ctx, gen.passwords)
+	}
+	if isRenderInputError(err) {
+		writeProblem(w, r, http.StatusBadRequest, err)
+		log.WithError(err).Warnln("Received a bad request.")
+		return
+	}
+	if isContextError(err) {
+		writeProblem(w, r, http.StatusServiceUnavailable, err)
+		log.WithError(err).Warnln("Could not hash passwords in time.")
+		return
+	}
+	if err != nil {
+		writeProblem(w, r, http.StatusInternalServerError, err)
+		log.WithError(err).Errorln("Error while rendering response")
+		return
+	}
+	if err := ph.Limits.checkOutput(body); err != nil {
+		ph.requestError(w, r, err)
 		return
 	}
 
+	writeBody(w, r, contentType, body)
+}
+
+// isContextError reports whether the request was cancelled or ran out of time
+func isContextError(err error) bool {
+	cause := errors.Cause(err)
+	return cause == context.DeadlineExceeded || cause == context.Canceled
+}
+
+// requestError answers invalid requests, field errors of policies are listed in the problem
+func (ph *PasswordHandler) requestError(w http.ResponseWriter, r *http.Request, err error) {
+	log.WithError(err).Warnln("Received a bad request.")
+	switch errors.Cause(err) {
+	case errPolicyTooLarge, errOutputTooLarge:
+		writeProblem(w, r, http.StatusRequestEntityTooLarge, err)
+	case errUnsupportedPolicyType:
+		writeProblem(w, r, http.StatusUnsupportedMediaType, err)
+	case errProfileForbidden:
+		writeProblem(w, r, http.StatusForbidden, err)
+	default:
+		writeProblem(w, r, http.StatusBadRequest, err)
+	}
+}
+
+// writeBody answers with the body and its content type, HEAD requests only get the headers
+func writeBody(w http.ResponseWriter, r *http.Request, contentType string, body []byte) {
+	w.Header().Set("Content-Type", contentType)
 	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
 
 	// No Body for HEAD requests
@@ -59,7 +173,7 @@ func (ph *PasswordHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err = w.Write(body)
+	_, err := w.Write(body)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		log.WithError(err).Errorln("Error while writing body")
@@ -68,37 +182,139 @@ func (ph *PasswordHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	log.Debugln("Answered GET request")
 }
 
-func (ph *PasswordHandler) passwords(r *http.Request) ([]string, error) {
-	// Get parameters from URL & validate them
-	params := r.URL.Query()
+// passwordRequest describes the passwords to generate and how to group them
+type passwordRequest struct {
+	amount          int
+	policy          password.Policy
+	group           int
+	separator       string
+	countSeparators bool
+	profile         *password.Profile
+}
 
+// request reads the passwordRequest from the policy document of POST requests or from the query params
+func (ph *PasswordHandler) request(r *http.Request) (passwordRequest, error) {
+	var req passwordRequest
+	var err error
+	if r.Method == http.MethodPost {
+		req, err = requestFromBody(r)
+	} else {
+		req, err = requestFromParams(r.URL.Query())
+	}
+	if err != nil {
+		return req, err
+	}
+
+	// Wi-Fi payloads require a valid pre-shared key
+	if req.profile == nil && r.URL.Query().Get(paramSSID) != "" {
+		req.profile = &password.WiFi
+	}
+	// Stay backwards compatible, unless every key of a manifest needs a password
+	if req.amount == 0 {
+		req.amount = 1
+		if keys := keysFromParams(r.URL.Query()); len(keys) > 0 {
+			req.amount = len(keys)
+		}
+	}
+	return req, nil
+}
+
+func requestFromParams(params url.Values) (passwordRequest, error) {
+	// Get parameters from URL & validate them
 	minLength, err := numberFromParams(params, paramMinLength)
 	if err != nil {
-		return nil, errors.Wrap(err, "Could not read minLength parameter")
+		return passwordRequest{}, errors.Wrap(err, "Could not read minLength parameter")
 	}
 	specialChars, err := numberFromParams(params, paramSpecialChars)
 	if err != nil {
-		return nil, errors.Wrap(err, "Could not read special chars parameter")
+		return passwordRequest{}, errors.Wrap(err, "Could not read special chars parameter")
 	}
 	numbers, err := numberFromParams(params, paramNumbers)
 	if err != nil {
-		return nil, errors.Wrap(err, "Could not read numbers parameter")
+		return passwordRequest{}, errors.Wrap(err, "Could not read numbers parameter")
 	}
 	amount, err := numberFromParams(params, paramAmount)
 	if err != nil {
-		return nil, errors.Wrap(err, "Could not read numbers parameter")
+		return passwordRequest{}, errors.Wrap(err, "Could not read amount parameter")
 	}
 	swap, err := boolFromParams(params, paramSwap)
 	if err != nil {
-		return nil, errors.Wrap(err, "Could not read swap parameter")
+		return passwordRequest{}, errors.Wrap(err, "Could not read swap parameter")
+	}
+	group, err := numberFromParams(params, paramGroup)
+	if err != nil {
+		return passwordRequest{}, errors.Wrap(err, "Could not read group parameter")
+	}
+	separator := password.DefaultSeparator
+	if _, ok := params[paramSeparator]; ok {
+		separator = params.Get(paramSeparator)
+	}
+	countSeparators, err := boolFromParams(params, paramCountSeparators)
+	if err != nil {
+		return passwordRequest{}, errors.Wrap(err, "Could not read countSeparators parameter")
+	}
+	profile, err := profileFromParams(params, paramProfile)
+	if err != nil {
+		return passwordRequest{}, errors.Wrap(err, "Could not read profile parameter")
+	}
+	return passwordRequest{
+		amount:          amount,
+		policy:          password.Policy{MinLength: minLength, SpecialChars: specialChars, Numbers: numbers, Swap: swap},
+		group:           group,
+		separator:       separator,
+		countSeparators: countSeparators,
+		profile:         profile,
+	}, nil
+}
+
+// generated holds the passwords of a request together with the policy they were generated with
+type generated struct {
+	passwords []string
+	policy    password.Policy
+	// entropy holds the estimated bits of entropy of each password
+	entropy []float64
+}
+
+// passwords applies the profile to the policy, generates the passwords and groups them
+func (ph *PasswordHandler) passwords(ctx context.Context, req passwordRequest) (generated, error) {
+	policy, profile := req.policy, req.profile
+	if profile != nil {
+		if policy.MinLength < profile.MinLength {
+			policy.MinLength = profile.MinLength
+		}
+		policy.Exclude += profile.Exclude
+	}
+	gen := generated{policy: policy}
+
+	// Separators are added after generation, so the generated part can be shorter if they count towards the length
+	if req.countSeparators {
+		policy.MinLength = password.UngroupedLength(policy.MinLength, req.group, req.separator)
+		if policy.MaxLength > 0 {
+			policy.MaxLength = password.UngroupedLength(policy.MaxLength, req.group, req.separator)
+		}
+	}
+	pw, err := ph.Passwords(ctx, req.amount, policy)
+	if fe, ok := errors.Cause(err).(password.FieldError); ok {
+		return generated{}, invalidParam(fe.Field, errors.Wrap(err, "Parameters contradict each other"))
 	}
-	// Stay backwards compatible
-	if amount == 0 {
-		amount = 1
+	if err != nil {
+		return generated{}, errors.Wrap(err, "Could not generate passwords")
 	}
-	pw := ph.Passwords(amount, minLength, specialChars, numbers, swap)
+	gen.passwords = pw
+	gen.entropy = make([]float64, len(gen.passwords))
 
-	return pw, nil
+	for i, pw := range gen.passwords {
+		// Separators are fixed, so only the generated characters add entropy
+		gen.entropy[i] = policy.Entropy(len(pw))
+		gen.passwords[i] = password.Group(pw, req.group, req.separator)
+		if profile == nil {
+			continue
+		}
+		if err := profile.Validate(gen.passwords[i]); err != nil {
+			return generated{}, invalidParam(paramProfile, errors.Wrap(err, "Parameters do not satisfy the profile"))
+		}
+	}
+	return gen, nil
 }
 
 func numberFromParams(vals url.Values, name string) (int, error) {
@@ -108,7 +324,7 @@ func numberFromParams(vals url.Values, name string) (int, error) {
 	}
 	num, err := strconv.Atoi(val)
 	if err != nil {
-		return 0, errors.Wrapf(err, "Query Parameter %s was no number, got %s instead", name, val)
+		return 0, invalidParam(name, errors.Wrapf(err, "Query Parameter %s was no number, got %s instead", name, val))
 	}
 	return num, nil
 }
@@ -120,20 +336,45 @@ func boolFromParams(vals url.Values, name string) (bool, error) {
 	}
 	boolean, err := strconv.ParseBool(val)
 	if err != nil {
-		return false, errors.Wrapf(err, "Query Parameter %s was no bool, got %s instead", name, val)
+		return false, invalidParam(name, errors.Wrapf(err, "Query Parameter %s was no bool, got %s instead", name, val))
 	}
 	return boolean, nil
 }
 
-// Passworder provides us with a Password function to generate passwords
+func alphabetFromParams(vals url.Values, name string) (*spelling.Alphabet, error) {
+	val := vals.Get(name)
+	if val == "" {
+		return nil, nil
+	}
+	alphabet, ok := spelling.Lookup(val)
+	if !ok {
+		return nil, invalidParam(name, errors.Errorf("Query Parameter %s was no known spelling locale, got %s instead", name, val))
+	}
+	return &alphabet, nil
+}
+
+func profileFromParams(vals url.Values, name string) (*password.Profile, error) {
+	val := vals.Get(name)
+	if val == "" {
+		return nil, nil
+	}
+	profile, ok := password.LookupProfile(val)
+	if !ok {
+		return nil, invalidParam(name, errors.Errorf("Query Parameter %s was no known profile, got %s instead", name, val))
+	}
+	return &profile, nil
+}
+
+// Passworder provides us with a Password function to generate passwords,
+// it should stop and return the error of the context once it is done
 type Passworder interface {
-	Passwords(amount, minLength, specialChars, numbers int, swap bool) []string
+	Passwords(ctx context.Context, amount int, policy password.Policy) ([]string, error)
 }
 
 // PassworderFunc allows us to cast single functions to satisfy the Passworder interface
-type PassworderFunc func(amount, minLength, specialChars, numbers int, swap bool) []string
+type PassworderFunc func(ctx context.Context, amount int, policy password.Policy) ([]string, error)
 
 // Password calls its' own receiver as a function to implement the Passworder interface
-func (p PassworderFunc) Passwords(amount, minLength, specialChars, numbers int, swap bool) []string {
-	return p(amount, minLength, specialChars, numbers, swap)
+func (p PassworderFunc) Passwords(ctx context.Context, amount int, policy password.Policy) ([]string, error) {
+	return p(ctx, amount, policy)
 }
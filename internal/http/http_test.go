@@ -1,12 +1,14 @@
 package http
 
 import (
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
 	"testing"
 
 	"github.com/domano/pwgen/internal/mock"
+	"github.com/domano/pwgen/internal/password"
 	"github.com/golang/mock/gomock"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
@@ -17,10 +19,11 @@ func TestNewPasswordHandler(t *testing.T) {
 	pw := mock.NewMockPassworder(gomock.NewController(t))
 
 	// when
-	ph := NewPasswordHandler(pw)
+	ph := NewPasswordHandler(pw, Limits{MaxAmount: 10})
 
 	// then
 	assert.Equal(t, pw, ph.Passworder)
+	assert.Equal(t, 10, ph.Limits.MaxAmount)
 }
 
 func TestPasswordHandler_ServeHTTP(t *testing.T) {
@@ -36,6 +39,7 @@ func TestPasswordHandler_ServeHTTP(t *testing.T) {
 		expectedResponse      int
 		expectedBody          string
 		expectedContentLength int
+		expectedParam         string
 	}{
 		{
 			desc:                  "GET, no params",
@@ -56,12 +60,12 @@ func TestPasswordHandler_ServeHTTP(t *testing.T) {
 			expectedContentLength: 4,
 		},
 		{
-			desc:              "POST, no params",
+			desc:              "POST, no policy",
 			method:            http.MethodPost,
 			queryParams:       nil,
 			returnedPasswords: nil,
-			expectedResponse:  http.StatusMethodNotAllowed,
-			expectedBody:      "",
+			expectedResponse:  http.StatusBadRequest,
+			expectedBody:      `"errors":[{"field":"","message":"must be valid JSON: EOF"}]`,
 		},
 		{
 			desc:              "PUT, no params",
@@ -152,6 +156,7 @@ func TestPasswordHandler_ServeHTTP(t *testing.T) {
 		},
 		{
 			desc:                  "GET, invalid minLength",
+			expectedParam:         paramMinLength,
 			method:                http.MethodGet,
 			queryParams:           map[string]string{paramMinLength: "asdasd1"},
 			expectedResponse:      http.StatusBadRequest,
@@ -160,6 +165,7 @@ func TestPasswordHandler_ServeHTTP(t *testing.T) {
 		},
 		{
 			desc:                  "GET, invalid specialChars",
+			expectedParam:         paramSpecialChars,
 			method:                http.MethodGet,
 			queryParams:           map[string]string{paramSpecialChars: "asdasd1"},
 			expectedResponse:      http.StatusBadRequest,
@@ -168,6 +174,7 @@ func TestPasswordHandler_ServeHTTP(t *testing.T) {
 		},
 		{
 			desc:                  "GET, invalid numbers",
+			expectedParam:         paramNumbers,
 			method:                http.MethodGet,
 			queryParams:           map[string]string{paramNumbers: "asdasd1"},
 			expectedResponse:      http.StatusBadRequest,
@@ -176,13 +183,106 @@ func TestPasswordHandler_ServeHTTP(t *testing.T) {
 		},
 		{
 			desc:                  "GET, invalid amount",
+			expectedParam:         paramAmount,
 			method:                http.MethodGet,
 			queryParams:           map[string]string{paramAmount: "asdasd1"},
 			expectedResponse:      http.StatusBadRequest,
 			expectedBody:          "",
 			expectedContentLength: 0,
+		},
+		{
+			desc:                  "GET, group 3",
+			method:                http.MethodGet,
+			queryParams:           map[string]string{paramGroup: "3"},
+			returnedPasswords:     []string{"abcdefgh"},
+			expectedResponse:      http.StatusOK,
+			expectedBody:          "[\"abc-def-gh\"]",
+			expectedContentLength: 14,
+		},
+		{
+			desc:                  "GET, group 2, separator underscore",
+			method:                http.MethodGet,
+			queryParams:           map[string]string{paramGroup: "2", paramSeparator: "_"},
+			returnedPasswords:     []string{"abcd"},
+			expectedResponse:      http.StatusOK,
+			expectedBody:          "[\"ab_cd\"]",
+			expectedContentLength: 9,
+		},
+		{
+			desc:                  "GET, invalid group",
+			expectedParam:         paramGroup,
+			method:                http.MethodGet,
+			queryParams:           map[string]string{paramGroup: "asdasd1"},
+			expectedResponse:      http.StatusBadRequest,
+			expectedBody:          "",
+			expectedContentLength: 0,
+		},
+		{
+			desc:                  "GET, invalid countSeparators",
+			expectedParam:         paramCountSeparators,
+			method:                http.MethodGet,
+			queryParams:           map[string]string{paramCountSeparators: "asdasd1"},
+			expectedResponse:      http.StatusBadRequest,
+			expectedBody:          "",
+			expectedContentLength: 0,
+		},
+		{
+			desc:                  "GET, spelling en",
+			method:                http.MethodGet,
+			queryParams:           map[string]string{paramSpelling: "en"},
+			returnedPasswords:     []string{"B7&"},
+			expectedResponse:      http.StatusOK,
+			expectedBody:          `[{"password":"B7\u0026","spelling":"Capital Bravo, seven, Ampersand"}]`,
+			expectedContentLength: 70,
+		},
+		{
+			desc:                  "GET, unknown spelling locale",
+			expectedParam:         paramSpelling,
+			method:                http.MethodGet,
+			queryParams:           map[string]string{paramSpelling: "xx"},
+			expectedResponse:      http.StatusBadRequest,
+			expectedBody:          "",
+			expectedContentLength: 0,
+		},
+		{
+			desc:                  "GET, unknown hash",
+			expectedParam:         paramHash,
+			method:                http.MethodGet,
+			queryParams:           map[string]string{paramHash: "md5"},
+			expectedResponse:      http.StatusBadRequest,
+			expectedBody:          "",
+			expectedContentLength: 0,
+		},
+		{
+			desc:                  "GET, password too long for bcrypt",
+			expectedParam:         paramHash,
+			method:                http.MethodGet,
+			queryParams:           map[string]string{paramMinLength: "73", paramHash: "bcrypt"},
+			returnedPasswords:     []string{"aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"},
+			expectedResponse:      http.StatusBadRequest,
+			expectedBody:          "",
+			expectedContentLength: 0,
+		},
+		{
+			desc:                  "GET, unknown profile",
+			expectedParam:         paramProfile,
+			method:                http.MethodGet,
+			queryParams:           map[string]string{paramProfile: "unknown"},
+			expectedResponse:      http.StatusBadRequest,
+			expectedBody:          "",
+			expectedContentLength: 0,
+		},
+		{
+			desc:                  "GET, unknown format",
+			expectedParam:         paramFormat,
+			method:                http.MethodGet,
+			queryParams:           map[string]string{paramFormat: "gif"},
+			expectedResponse:      http.StatusBadRequest,
+			expectedBody:          "",
+			expectedContentLength: 0,
 		}, {
 			desc:                  "GET, invalid swap parameter",
+			expectedParam:         paramSwap,
 			method:                http.MethodGet,
 			queryParams:           map[string]string{paramSwap: "asdasd1"},
 			expectedResponse:      http.StatusBadRequest,
@@ -199,7 +299,7 @@ func TestPasswordHandler_ServeHTTP(t *testing.T) {
 			mockPassworder := mock.NewMockPassworder(ctrl)
 
 			// and our handler
-			ph := &PasswordHandler{mockPassworder}
+			ph := &PasswordHandler{Passworder: mockPassworder}
 
 			// and a recorder for our response
 			rc := httptest.NewRecorder()
@@ -213,8 +313,8 @@ func TestPasswordHandler_ServeHTTP(t *testing.T) {
 			req.URL.RawQuery = query.Encode()
 
 			// expect calls to the password generator
-			passwordCall := mockPassworder.EXPECT().Passwords(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
-			passwordCall.Return(tC.returnedPasswords)
+			passwordCall := mockPassworder.EXPECT().Passwords(gomock.Any(), gomock.Any(), gomock.Any())
+			passwordCall.Return(tC.returnedPasswords, nil)
 			passwordCall.Times(1)
 
 			// when our endpoint is called
@@ -224,12 +324,77 @@ func TestPasswordHandler_ServeHTTP(t *testing.T) {
 			assert.Equal(t, tC.expectedResponse, rc.Code)
 			assert.Contains(t, rc.Body.String(), tC.expectedBody)
 			contentLength, _ := strconv.Atoi(rc.Header().Get("Content-Length"))
+			if tC.expectedResponse >= http.StatusBadRequest {
+				// and errors are described by a problem naming the parameter
+				var p problem
+				assert.NoError(t, json.Unmarshal(rc.Body.Bytes(), &p))
+				assert.Equal(t, "application/problem+json", rc.Header().Get("Content-Type"))
+				assert.Equal(t, tC.expectedResponse, p.Status)
+				assert.Equal(t, tC.expectedParam, p.Param)
+				assert.Equal(t, rc.Body.Len(), contentLength)
+				return
+			}
 			assert.Equal(t, tC.expectedContentLength, contentLength)
 			assert.Equal(t, len(tC.expectedBody), rc.Body.Len())
 		})
 	}
 }
 
+func TestPasswordHandler_ServeHTTP_Count_Separators(t *testing.T) {
+	// given a mock controller
+	ctrl := gomock.NewController(t)
+
+	// and a mocked password generator
+	mockPassworder := mock.NewMockPassworder(ctrl)
+
+	// and our handler
+	ph := &PasswordHandler{Passworder: mockPassworder}
+
+	// and a recorder for our response
+	rc := httptest.NewRecorder()
+
+	// and a request for grouped passwords where separators count towards the length
+	req, _ := http.NewRequest(http.MethodGet, "?minLength=20&group=6&countSeparators=true", nil)
+
+	// expect the generator to be asked for a shorter password
+	mockPassworder.EXPECT().Passwords(gomock.Any(), 1, password.Policy{MinLength: 18}).Return([]string{"abcdefghijklmnopqr"}, nil).Times(1)
+
+	// when
+	ph.ServeHTTP(rc, req)
+
+	// then the grouped password has exactly the minimum length
+	assert.Equal(t, http.StatusOK, rc.Code)
+	assert.Equal(t, "[\"abcdef-ghijkl-mnopqr\"]", rc.Body.String())
+}
+
+func TestPasswordHandler_ServeHTTP_Database_Profile(t *testing.T) {
+	// given a mock controller
+	ctrl := gomock.NewController(t)
+
+	// and a mocked password generator
+	mockPassworder := mock.NewMockPassworder(ctrl)
+
+	// and our handler
+	ph := &PasswordHandler{Passworder: mockPassworder}
+
+	// and a recorder for our response
+	rc := httptest.NewRecorder()
+
+	// and a request for a database password with a PostgreSQL verifier
+	req, _ := http.NewRequest(http.MethodGet, "?minLength=16&specialChars=4&profile=db&hash=scram-sha-256", nil)
+
+	// expect the generator to avoid the characters of the profile
+	expectedPolicy := password.Policy{MinLength: 16, SpecialChars: 4, Exclude: password.Database.Exclude}
+	mockPassworder.EXPECT().Passwords(gomock.Any(), 1, expectedPolicy).Return([]string{"a!b#c$d%efghijkl"}, nil).Times(1)
+
+	// when
+	ph.ServeHTTP(rc, req)
+
+	// then the password comes with its verifier
+	assert.Equal(t, http.StatusOK, rc.Code)
+	assert.Contains(t, rc.Body.String(), `"hash":"SCRAM-SHA-256$4096:`)
+}
+
 func TestPasswordHandler_ServeHTTP_Fail_Body_Write(t *testing.T) {
 	// given a mock controller
 	ctrl := gomock.NewController(t)
@@ -238,7 +403,7 @@ func TestPasswordHandler_ServeHTTP_Fail_Body_Write(t *testing.T) {
 	mockPassworder := mock.NewMockPassworder(ctrl)
 
 	// and our handler
-	ph := &PasswordHandler{mockPassworder}
+	ph := &PasswordHandler{Passworder: mockPassworder}
 
 	// and a failing responsewrite
 	w := &failWriter{}
@@ -247,7 +412,7 @@ func TestPasswordHandler_ServeHTTP_Fail_Body_Write(t *testing.T) {
 	req, _ := http.NewRequest(http.MethodGet, "", nil)
 
 	// expect calls to the password generator
-	passwordCall := mockPassworder.EXPECT().Passwords(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return([]string{""})
+	passwordCall := mockPassworder.EXPECT().Passwords(gomock.Any(), gomock.Any(), gomock.Any()).Return([]string{""}, nil)
 	passwordCall.Times(1)
 
 	// when
@@ -0,0 +1,113 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/domano/pwgen/internal/jwk"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// JWKHandler generates signing keys as JSON Web Keys
+type JWKHandler struct{}
+
+// Constants for the available query params
+const paramAlg = "alg"
+const paramJWKS = "jwks"
+
+// defaultJWKAlgorithm is used if no algorithm is requested
+const defaultJWKAlgorithm = jwk.ES256
+
+// jwkResponse contains the private key and optionally a key set with its public part
+type jwkResponse struct {
+	Key  jwk.JWK  `json:"key"`
+	JWKS *jwk.Set `json:"jwks,omitempty"`
+}
+
+// NewJWKHandler constructs a new JWKHandler
+func NewJWKHandler() *JWKHandler {
+	return &JWKHandler{}
+}
+
+func (jh *JWKHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Keys are only generated, so nothing except GET is supported
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		methodNotAllowed(w, r, http.MethodGet, http.MethodHead)
+		return
+	}
+
+	req, err := jh.request(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, err)
+		log.WithError(err).Warnln("Received a bad request.")
+		return
+	}
+	resp, err := jh.key(req)
+	if err != nil {
+		writeProblem(w, r, http.StatusInternalServerError, err)
+		log.WithError(err).Errorln("Error while generating key")
+		return
+	}
+
+	body, err := json.Marshal(resp)
+	if err != nil {
+		writeProblem(w, r, http.StatusInternalServerError, err)
+		log.WithError(err).Errorln("Error while marshalling json")
+		return
+	}
+	writeBody(w, r, "application/json", body)
+}
+
+// jwkRequest holds the validated params of a request for a key
+type jwkRequest struct {
+	alg     jwk.Algorithm
+	bits    int
+	withSet bool
+}
+
+// request reads the params of a key, they are validated before anything is generated
+func (jh *JWKHandler) request(r *http.Request) (jwkRequest, error) {
+	// Get parameters from URL & validate them
+	params := r.URL.Query()
+
+	req := jwkRequest{alg: defaultJWKAlgorithm}
+	if val := params.Get(paramAlg); val != "" {
+		var ok bool
+		if req.alg, ok = jwk.ParseAlgorithm(val); !ok {
+			return jwkRequest{}, invalidParam(paramAlg, errors.Errorf("Query Parameter %s was no known algorithm, got %s instead", paramAlg, val))
+		}
+	}
+	bits, err := numberFromParams(params, paramBits)
+	if err != nil {
+		return jwkRequest{}, errors.Wrap(err, "Could not read bits parameter")
+	}
+	if err := jwk.ValidBits(req.alg, bits); err != nil {
+		return jwkRequest{}, invalidParam(paramBits, err)
+	}
+	req.bits = bits
+	if req.withSet, err = boolFromParams(params, paramJWKS); err != nil {
+		return jwkRequest{}, errors.Wrap(err, "Could not read jwks parameter")
+	}
+	if req.withSet && req.alg.Symmetric() {
+		return jwkRequest{}, invalidParam(paramJWKS, errors.Errorf("Query Parameter %s is not supported for the symmetric algorithm %s", paramJWKS, req.alg))
+	}
+	return req, nil
+}
+
+// key generates the key and its key set, all errors are failures of the server
+func (jh *JWKHandler) key(req jwkRequest) (jwkResponse, error) {
+	k, err := jwk.Generate(req.alg, req.bits)
+	if err != nil {
+		return jwkResponse{}, errors.Wrap(err, "Could not generate key")
+	}
+	resp := jwkResponse{Key: k}
+	if req.withSet {
+		pub, err := k.Public()
+		if err != nil {
+			return jwkResponse{}, errors.Wrap(err, "Could not create key set")
+		}
+		resp.JWKS = &jwk.Set{Keys: []jwk.JWK{pub}}
+	}
+	return resp, nil
+}
@@ -0,0 +1,113 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJWKHandler_ServeHTTP(t *testing.T) {
+	testCases := []struct {
+		desc string
+
+		//given
+		method string
+		query  string
+
+		// expect
+		expectedResponse int
+		expectedKty      string
+		expectJWKS       bool
+		expectedParam    string
+	}{
+		{
+			desc:   "GET, no params",
+			method: http.MethodGet, query: "",
+			expectedResponse: http.StatusOK, expectedKty: "EC",
+		},
+		{
+			desc:   "GET, HS512",
+			method: http.MethodGet, query: "alg=HS512",
+			expectedResponse: http.StatusOK, expectedKty: "oct",
+		},
+		{
+			desc:   "GET, EdDSA with jwks",
+			method: http.MethodGet, query: "alg=EdDSA&jwks=true",
+			expectedResponse: http.StatusOK, expectedKty: "OKP", expectJWKS: true,
+		},
+		{
+			desc:   "GET, RS256 2048 with jwks",
+			method: http.MethodGet, query: "alg=RS256&bits=2048&jwks=true",
+			expectedResponse: http.StatusOK, expectedKty: "RSA", expectJWKS: true,
+		},
+		{
+			desc:   "GET, symmetric key with jwks",
+			method: http.MethodGet, query: "alg=HS256&jwks=true",
+			expectedResponse: http.StatusBadRequest, expectedParam: paramJWKS,
+		},
+		{
+			desc:   "GET, unknown algorithm",
+			method: http.MethodGet, query: "alg=none",
+			expectedResponse: http.StatusBadRequest, expectedParam: paramAlg,
+		},
+		{
+			desc:   "GET, invalid bits",
+			method: http.MethodGet, query: "alg=RS256&bits=1024",
+			expectedResponse: http.StatusBadRequest, expectedParam: paramBits,
+		},
+		{
+			desc:   "GET, bits are ignored for EC keys",
+			method: http.MethodGet, query: "alg=ES256&bits=1024",
+			expectedResponse: http.StatusOK, expectedKty: "EC",
+		},
+		{
+			desc:   "GET, invalid jwks",
+			method: http.MethodGet, query: "jwks=abc",
+			expectedResponse: http.StatusBadRequest, expectedParam: paramJWKS,
+		},
+		{
+			desc:   "PUT",
+			method: http.MethodPut, query: "",
+			expectedResponse: http.StatusMethodNotAllowed,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// given our handler
+			jh := NewJWKHandler()
+
+			// and a recorder for our response
+			rc := httptest.NewRecorder()
+
+			// and a test request
+			req, _ := http.NewRequest(tC.method, "?"+tC.query, nil)
+
+			// when our endpoint is called
+			jh.ServeHTTP(rc, req)
+
+			// then
+			assert.Equal(t, tC.expectedResponse, rc.Code)
+			if tC.expectedResponse == http.StatusBadRequest {
+				var p problem
+				assert.NoError(t, json.NewDecoder(rc.Body).Decode(&p))
+				assert.Equal(t, tC.expectedParam, p.Param)
+			}
+			if tC.expectedResponse != http.StatusOK {
+				return
+			}
+			var resp jwkResponse
+			assert.NoError(t, json.NewDecoder(rc.Body).Decode(&resp))
+			assert.Equal(t, tC.expectedKty, resp.Key.Kty)
+			assert.NotEmpty(t, resp.Key.Kid)
+			assert.Equal(t, tC.expectJWKS, resp.JWKS != nil)
+			if tC.expectJWKS {
+				// the key set only contains the public key with the same key id
+				assert.Equal(t, resp.Key.Kid, resp.JWKS.Keys[0].Kid)
+				assert.Empty(t, resp.JWKS.Keys[0].D)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package http
+
+import (
+	"time"
+
+	"github.com/domano/pwgen/internal/password"
+	"github.com/pkg/errors"
+)
+
+// Limits bound the resources a single request for passwords may use, zero values fall back to the DefaultLimits
+type Limits struct {
+	// MaxAmount is the maximum number of passwords per request
+	MaxAmount int
+	// MaxLength is the maximum length of a password
+	MaxLength int
+	// MaxOutputBytes is the maximum size of the response body
+	MaxOutputBytes int
+	// MaxHashes is the maximum number of passwords hashed per request
+	MaxHashes int
+	// Timeout is the deadline for generating and hashing the passwords of a request
+	Timeout time.Duration
+}
+
+// DefaultLimits are used for limits which are not configured
+var DefaultLimits = Limits{MaxAmount: 1000, MaxLength: 1024, MaxOutputBytes: 1 << 20, MaxHashes: 100, Timeout: 5 * time.Second}
+
+// errOutputTooLarge is returned when the passwords of a request would exceed MaxOutputBytes
+var errOutputTooLarge = errors.New("output too large")
+
+// withDefaults replaces unset limits with the DefaultLimits
+func (l Limits) withDefaults() Limits {
+	if l.MaxAmount <= 0 {
+		l.MaxAmount = DefaultLimits.MaxAmount
+	}
+	if l.MaxLength <= 0 {
+		l.MaxLength = DefaultLimits.MaxLength
+	}
+	if l.MaxOutputBytes <= 0 {
+		l.MaxOutputBytes = DefaultLimits.MaxOutputBytes
+	}
+	if l.MaxHashes <= 0 {
+		l.MaxHashes = DefaultLimits.MaxHashes
+	}
+	if l.Timeout <= 0 {
+		l.Timeout = DefaultLimits.Timeout
+	}
+	return l
+}
+
+// check rejects negative and contradicting values and requests exceeding the limits before anything is generated
+func (l Limits) check(req passwordRequest) error {
+	l = l.withDefaults()
+	if req.amount < 0 {
+		return invalidParam(paramAmount, errors.Errorf("%s must not be negative, got %d", paramAmount, req.amount))
+	}
+	if req.group < 0 {
+		return invalidParam(paramGroup, errors.Errorf("%s must not be negative, got %d", paramGroup, req.group))
+	}
+	if len(req.separator) > maxSeparatorLength || !separatorPattern.MatchString(req.separator) {
+		return invalidParam(paramSeparator, errors.Errorf("%s must be at most %d printable ASCII characters without spaces, quotes, commas or backslashes, got %q", paramSeparator, maxSeparatorLength, req.separator))
+	}
+	if err := req.policy.Validate(); err != nil {
+		fe, _ := errors.Cause(err).(password.FieldError)
+		return invalidParam(fe.Field, errors.Wrap(err, "Parameters are invalid"))
+	}
+	if req.amount > l.MaxAmount {
+		return invalidParam(paramAmount, errors.Errorf("%s must be at most %d, got %d", paramAmount, l.MaxAmount, req.amount))
+	}
+	for _, v := range []struct {
+		param string
+		value int
+	}{
+		{paramMinLength, req.policy.MinLength},
+		{"maxLength", req.policy.MaxLength},
+		{paramSpecialChars, req.policy.SpecialChars},
+		{paramNumbers, req.policy.Numbers},
+		{paramNumbers, req.policy.SpecialChars + req.policy.Numbers},
+	} {
+		if v.value > l.MaxLength {
+			return invalidParam(v.param, errors.Errorf("passwords must be at most %d characters long, %s requires %d", l.MaxLength, v.param, v.value))
+		}
+	}
+
+	// The longest password decides, its separators count even if they are included in the length
+	length := req.policy.SpecialChars + req.policy.Numbers
+	for _, l := range []int{req.policy.MinLength, req.policy.MaxLength} {
+		if l > length {
+			length = l
+		}
+	}
+	if req.profile != nil && req.profile.MinLength > length {
+		length = req.profile.MinLength
+	}
+	size := password.GroupedLength(length, req.group, req.separator)
+	if int64(req.amount)*int64(size) > int64(l.MaxOutputBytes) {
+		return errors.Wrapf(errOutputTooLarge, "%d passwords of up to %d bytes exceed the limit of %d bytes", req.amount, size, l.MaxOutputBytes)
+	}
+	return nil
+}
+
+// checkHashes rejects requests hashing more passwords than MaxHashes, as every hash takes a considerable amount of time
+func (l Limits) checkHashes(amount int) error {
+	l = l.withDefaults()
+	if amount > l.MaxHashes {
+		return invalidParam(paramAmount, errors.Errorf("at most %d passwords can be hashed per request, got %d", l.MaxHashes, amount))
+	}
+	return nil
+}
+
+// checkOutput rejects response bodies which grew beyond MaxOutputBytes by spellings, hashes or the format
+func (l Limits) checkOutput(body []byte) error {
+	l = l.withDefaults()
+	if len(body) > l.MaxOutputBytes {
+		return errors.Wrapf(errOutputTooLarge, "response of %d bytes exceeds the limit of %d bytes", len(body), l.MaxOutputBytes)
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/domano/pwgen/internal/mock"
+	"github.com/domano/pwgen/internal/password"
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimits_withDefaults(t *testing.T) {
+	// when
+	l := Limits{MaxAmount: 5}.withDefaults()
+
+	// then configured limits are kept and the others default
+	assert.Equal(t, Limits{MaxAmount: 5, MaxLength: DefaultLimits.MaxLength, MaxOutputBytes: DefaultLimits.MaxOutputBytes, MaxHashes: DefaultLimits.MaxHashes, Timeout: DefaultLimits.Timeout}, l)
+}
+
+func TestPasswordHandler_ServeHTTP_Limits(t *testing.T) {
+	limits := Limits{MaxAmount: 10, MaxLength: 64, MaxOutputBytes: 200, MaxHashes: 2}
+	testCases := []struct {
+		desc           string
+		method         string
+		query          string
+		body           string
+		expectedStatus int
+		expectedParam  string
+	}{
+		{desc: "Negative amount", query: "amount=-1", expectedStatus: http.StatusBadRequest, expectedParam: paramAmount},
+		{desc: "Negative minLength", query: "minLength=-5", expectedStatus: http.StatusBadRequest, expectedParam: paramMinLength},
+		{desc: "Negative specialChars", query: "specialChars=-1", expectedStatus: http.StatusBadRequest, expectedParam: paramSpecialChars},
+		{desc: "Negative numbers", query: "numbers=-1", expectedStatus: http.StatusBadRequest, expectedParam: paramNumbers},
+		{desc: "Negative group", query: "group=-4", expectedStatus: http.StatusBadRequest, expectedParam: paramGroup},
+		{desc: "Too many passwords", query: "amount=11", expectedStatus: http.StatusBadRequest, expectedParam: paramAmount},
+		{desc: "Too long", query: "minLength=65", expectedStatus: http.StatusBadRequest, expectedParam: paramMinLength},
+		{desc: "Too many special chars", query: "specialChars=65", expectedStatus: http.StatusBadRequest, expectedParam: paramSpecialChars},
+		{desc: "Too many chars in sum", query: "specialChars=40&numbers=40", expectedStatus: http.StatusBadRequest, expectedParam: paramNumbers},
+		{desc: "Too long policy", method: http.MethodPost, body: `{"maxLength":65}`, expectedStatus: http.StatusBadRequest, expectedParam: "maxLength"},
+		{desc: "Contradicting policy", method: http.MethodPost, body: `{"minLength":20,"maxLength":10}`, expectedStatus: http.StatusBadRequest},
+		{desc: "Output too large", query: "amount=10&minLength=30", expectedStatus: http.StatusRequestEntityTooLarge},
+		{desc: "Separator with line break", query: "group=2&separator=%0A", expectedStatus: http.StatusBadRequest, expectedParam: paramSeparator},
+		{desc: "Separator with space", query: "group=2&separator=+", expectedStatus: http.StatusBadRequest, expectedParam: paramSeparator},
+		{desc: "Separator with comma", query: "group=2&separator=,", expectedStatus: http.StatusBadRequest, expectedParam: paramSeparator},
+		{desc: "Long separator", query: "group=2&separator=-----", expectedStatus: http.StatusBadRequest, expectedParam: paramSeparator},
+		{desc: "Too many hashes", query: "amount=3&hash=ssha", expectedStatus: http.StatusBadRequest, expectedParam: paramAmount},
+		{desc: "Output with separators too large", query: "amount=10&minLength=16&group=2", expectedStatus: http.StatusRequestEntityTooLarge},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// given a passworder which must not be called
+			mockPassworder := mock.NewMockPassworder(gomock.NewController(t))
+			mockPassworder.EXPECT().Passwords(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+			// and our handler
+			ph := NewPasswordHandler(mockPassworder, limits)
+			rc := httptest.NewRecorder()
+			method := tC.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			req := httptest.NewRequest(method, "/passwords?"+tC.query, strings.NewReader(tC.body))
+			if tC.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+
+			// when
+			ph.ServeHTTP(rc, req)
+
+			// then
+			assert.Equal(t, tC.expectedStatus, rc.Code)
+			var p problem
+			assert.NoError(t, json.Unmarshal(rc.Body.Bytes(), &p))
+			assert.Equal(t, tC.expectedParam, p.Param)
+		})
+	}
+}
+
+func TestPasswordHandler_ServeHTTP_Output_Limit(t *testing.T) {
+	// given passwords within the limits which grow beyond them when spelled
+	mockPassworder := mock.NewMockPassworder(gomock.NewController(t))
+	mockPassworder.EXPECT().Passwords(gomock.Any(), 2, gomock.Any()).Return([]string{"abcdefgh", "ijklmnop"}, nil)
+	ph := NewPasswordHandler(mockPassworder, Limits{MaxOutputBytes: 100})
+	rc := httptest.NewRecorder()
+
+	// when
+	ph.ServeHTTP(rc, httptest.NewRequest(http.MethodGet, "/passwords?amount=2&minLength=8&spelling=en", nil))
+
+	// then
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rc.Code)
+	assert.Contains(t, rc.Body.String(), `"type":"urn:pwgen:problem:request-too-large"`)
+}
+
+func TestPasswordHandler_ServeHTTP_Generator_Errors(t *testing.T) {
+	testCases := []struct {
+		desc           string
+		err            error
+		expectedStatus int
+		expectedParam  string
+	}{
+		{desc: "Contradicting parameters", err: password.FieldError{Field: "maxLength", Message: "must leave room for specialChars and numbers"}, expectedStatus: http.StatusBadRequest, expectedParam: "maxLength"},
+		{desc: "Internal error", err: errors.New("no randomness"), expectedStatus: http.StatusInternalServerError},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// given a failing passworder
+			mockPassworder := mock.NewMockPassworder(gomock.NewController(t))
+			mockPassworder.EXPECT().Passwords(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, tC.err)
+			rc := httptest.NewRecorder()
+
+			// when
+			NewPasswordHandler(mockPassworder, Limits{}).ServeHTTP(rc, httptest.NewRequest(http.MethodGet, "/passwords", nil))
+
+			// then
+			assert.Equal(t, tC.expectedStatus, rc.Code)
+			var p problem
+			assert.NoError(t, json.Unmarshal(rc.Body.Bytes(), &p))
+			assert.Equal(t, tC.expectedParam, p.Param)
+		})
+	}
+}
+
+func TestPasswordHandler_ServeHTTP_Timeout(t *testing.T) {
+	// given a passworder which takes until the deadline
+	var deadline bool
+	slow := PassworderFunc(func(ctx context.Context, _ int, _ password.Policy) ([]string, error) {
+		_, deadline = ctx.Deadline()
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	rc := httptest.NewRecorder()
+
+	// when
+	NewPasswordHandler(slow, Limits{Timeout: 10 * time.Millisecond}).ServeHTTP(rc, httptest.NewRequest(http.MethodGet, "/passwords", nil))
+
+	// then the deadline was propagated and the request failed
+	assert.True(t, deadline)
+	assert.Equal(t, http.StatusServiceUnavailable, rc.Code)
+	assert.Contains(t, rc.Body.String(), `"type":"urn:pwgen:problem:unavailable"`)
+}
+
+func TestPasswordHandler_ServeHTTP_Hash_Timeout(t *testing.T) {
+	// given a passworder which finishes at the deadline
+	late := PassworderFunc(func(ctx context.Context, _ int, _ password.Policy) ([]string, error) {
+		<-ctx.Done()
+		return []string{"abcdefgh", "ijklmnop"}, nil
+	})
+	rc := httptest.NewRecorder()
+
+	// when
+	NewPasswordHandler(late, Limits{Timeout: 10 * time.Millisecond}).ServeHTTP(rc, httptest.NewRequest(http.MethodGet, "/passwords?amount=2&hash=ssha", nil))
+
+	// then the passwords are not hashed after the deadline
+	assert.Equal(t, http.StatusServiceUnavailable, rc.Code)
+	assert.Contains(t, rc.Body.String(), `"type":"urn:pwgen:problem:unavailable"`)
+}
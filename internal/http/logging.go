@@ -1,25 +1,62 @@
 package http
 
 import (
+	"context"
 	log "github.com/sirupsen/logrus"
 	"net/http"
 )
 
+// loggingContextKey is the type of the context key of the logging middleware, so it can not collide with other packages
+type loggingContextKey int
+
+const callerKey loggingContextKey = iota
+
+// caller holds what the authentication middlewares found out about the caller of a request,
+// as the logging middleware can not see the contexts they pass on
+type caller struct {
+	identity string
+	keyID    string
+}
+
+// recordCaller adds the identity and API key of the context to the access log entry of the request
+func recordCaller(ctx context.Context) {
+	c, ok := ctx.Value(callerKey).(*caller)
+	if !ok {
+		return
+	}
+	if identity := IdentityFromContext(ctx); identity != "" {
+		c.identity = identity
+	}
+	if keyID := KeyIDFromContext(ctx); keyID != "" {
+		c.keyID = keyID
+	}
+}
+
 // LoggingHandlerFunc wraps a given
-// http.Handler with a logging middleware
+// http.Handler with a logging middleware.
+// Wrapping the authentication middlewares logs denied requests as well.
 func LoggingHandlerFunc(next http.Handler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		lw := loggingWriter{w, 200}
+		lw := &loggingWriter{w, http.StatusOK}
+		r = r.WithContext(context.WithValue(r.Context(), callerKey, &caller{}))
 		if next != nil {
 			next.ServeHTTP(lw, r)
 		}
-		log.
+		recordCaller(r.Context())
+		c := r.Context().Value(callerKey).(*caller)
+		entry := log.
 			WithField("type", "access").
 			WithField("path", r.URL.Path).
 			WithField("host", r.Host).
 			WithField("remote", r.RemoteAddr).
-			WithField("response_code", lw.statusCode).
-			Infoln("Received a request")
+			WithField("response_code", lw.statusCode)
+		if c.identity != "" {
+			entry = entry.WithField("identity", c.identity)
+		}
+		if c.keyID != "" {
+			entry = entry.WithField("key_id", c.keyID)
+		}
+		entry.Infoln("Received a request")
 	}
 }
 
@@ -28,7 +65,7 @@ type loggingWriter struct {
 	statusCode int
 }
 
-func (w loggingWriter) WriteHeader(statusCode int) {
+func (w *loggingWriter) WriteHeader(statusCode int) {
 	w.statusCode = statusCode
 	w.ResponseWriter.WriteHeader(statusCode)
 }
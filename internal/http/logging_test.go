@@ -54,3 +54,20 @@ func TestLoggingHandlerFunc_Access_Log_With_Next(t *testing.T) {
 	assert.Contains(t, body, "host=www.test.de")
 	assert.True(t, called)
 }
+
+func TestLoggingHandlerFunc_Access_Log_Status(t *testing.T) {
+	// given some writer to test our log output
+	logBuffer := bytes.NewBufferString("")
+	logrus.SetOutput(logBuffer)
+
+	// and a handler which answers with a status other than 200
+	next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	// when
+	LoggingHandlerFunc(next)(httptest.NewRecorder(), httptest.NewRequest("GET", "https://www.test.de/test", nil))
+
+	//then
+	assert.Contains(t, logBuffer.String(), "response_code=404")
+}
@@ -0,0 +1,181 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/domano/pwgen/internal/passhash"
+	"github.com/pkg/errors"
+)
+
+// Manifest formats render passwords as named secrets for infrastructure tooling
+const (
+	formatKubernetes = "kubernetes"
+	formatDotenv     = "dotenv"
+	formatCloudInit  = "cloud-init"
+)
+
+// Name of the Kubernetes Secret if none was requested
+const defaultSecretName = "pwgen"
+
+// errKeyCount is returned when the number of keys does not match the number of passwords
+var errKeyCount = errors.New("every password needs exactly one key")
+
+// Valid names of Kubernetes Secret data keys, Secrets as DNS subdomains, namespaces as DNS labels, dotenv variables and Linux users
+var (
+	kubernetesKey   = regexp.MustCompile(`^[-._a-zA-Z0-9]{1,253}$`)
+	kubernetesName  = regexp.MustCompile(`^[a-z0-9]([-a-z0-9.]{0,251}[a-z0-9])?$`)
+	kubernetesLabel = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]{0,61}[a-z0-9])?$`)
+	dotenvKey       = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	cloudInitUser   = regexp.MustCompile(`^[a-z_][a-z0-9_-]{0,31}$`)
+)
+
+// manifest names the secrets of a manifest format, the name and namespace are only used by Kubernetes
+type manifest struct {
+	keys            []string
+	name, namespace string
+}
+
+func isManifestFormat(format string) bool {
+	return format == formatKubernetes || format == formatDotenv || format == formatCloudInit
+}
+
+// manifestFromParams reads and validates the key names for the format, one key is needed for each password
+func manifestFromParams(vals url.Values, format string) (manifest, error) {
+	if !isManifestFormat(format) {
+		return manifest{}, nil
+	}
+	m := manifest{keys: keysFromParams(vals)}
+	if len(m.keys) == 0 {
+		return m, invalidParam(paramKeys, errors.Errorf("Query Parameter %s is required for format %s", paramKeys, format))
+	}
+
+	keyPattern := map[string]*regexp.Regexp{formatKubernetes: kubernetesKey, formatDotenv: dotenvKey, formatCloudInit: cloudInitUser}[format]
+	seen := map[string]bool{}
+	for _, key := range m.keys {
+		if !keyPattern.MatchString(key) {
+			return m, invalidParam(paramKeys, errors.Errorf("Query Parameter %s contains %q, which is no valid key for format %s", paramKeys, key, format))
+		}
+		if seen[key] {
+			return m, invalidParam(paramKeys, errors.Errorf("Query Parameter %s contains %q more than once", paramKeys, key))
+		}
+		seen[key] = true
+	}
+
+	// Secrets and variables hold the passwords themselves, only chpasswd passes hashes on to crypt(3),
+	// which does not know most of the other formats
+	if format != formatCloudInit && vals.Get(paramHash) != "" {
+		return m, invalidParam(paramHash, errors.Errorf("Query Parameter %s is not supported by format %s", paramHash, format))
+	}
+	if format == formatCloudInit {
+		if algorithm := vals.Get(paramHash); algorithm != "" && algorithm != passhash.SHA512Crypt && algorithm != passhash.Bcrypt {
+			return m, invalidParam(paramHash, errors.Errorf("Query Parameter %s must be %s or %s for format %s, got %s instead", paramHash, passhash.SHA512Crypt, passhash.Bcrypt, format, algorithm))
+		}
+		if vals.Get(paramUser) != "" {
+			return m, invalidParam(paramUser, errors.Errorf("Query Parameter %s is not supported by format %s", paramUser, format))
+		}
+	}
+
+	if format != formatKubernetes {
+		return m, nil
+	}
+	m.name = defaultSecretName
+	if name := vals.Get(paramName); name != "" {
+		m.name = name
+	}
+	if !kubernetesName.MatchString(m.name) {
+		return m, invalidParam(paramName, errors.Errorf("Query Parameter %s was no valid Kubernetes name, got %s instead", paramName, m.name))
+	}
+	m.namespace = vals.Get(paramNamespace)
+	if m.namespace != "" && !kubernetesLabel.MatchString(m.namespace) {
+		return m, invalidParam(paramNamespace, errors.Errorf("Query Parameter %s was no valid Kubernetes namespace, got %s instead", paramNamespace, m.namespace))
+	}
+	return m, nil
+}
+
+// keysFromParams reads the comma separated key names
+func keysFromParams(vals url.Values) []string {
+	val := vals.Get(paramKeys)
+	if val == "" {
+		return nil
+	}
+	return strings.Split(val, ",")
+}
+
+// renderManifest returns the content type and body of the manifest format with one secret per key
+func (o output) renderManifest(ctx context.Context, passwords []string) (string, []byte, error) {
+	if len(o.manifest.keys) != len(passwords) {
+		return "", nil, invalidParam(paramKeys, errors.Wrapf(errKeyCount, "got %d keys for %d passwords", len(o.manifest.keys), len(passwords)))
+	}
+	switch o.format {
+	case formatKubernetes:
+		return "application/yaml", o.kubernetesSecret(passwords), nil
+	case formatDotenv:
+		return "text/plain; charset=utf-8", o.dotenv(passwords), nil
+	}
+	body, err := o.cloudConfig(ctx, passwords)
+	return "text/cloud-config", body, err
+}
+
+// kubernetesSecret renders an Opaque Secret with base64 encoded data.
+// Strings are double quoted, as YAML would read names like "true" or "123" as other types and
+// double quoted YAML strings understand the escapes of Go string literals.
+func (o output) kubernetesSecret(passwords []string) []byte {
+	var b bytes.Buffer
+	b.WriteString("apiVersion: v1\nkind: Secret\nmetadata:\n")
+	fmt.Fprintf(&b, "  name: %s\n", strconv.Quote(o.manifest.name))
+	if o.manifest.namespace != "" {
+		fmt.Fprintf(&b, "  namespace: %s\n", strconv.Quote(o.manifest.namespace))
+	}
+	b.WriteString("type: Opaque\ndata:\n")
+	for i, key := range o.manifest.keys {
+		fmt.Fprintf(&b, "  %s: %s\n", strconv.Quote(key), base64.StdEncoding.EncodeToString([]byte(passwords[i])))
+	}
+	return b.Bytes()
+}
+
+// dotenv renders one variable per line, which is understood by docker compose and the common dotenv libraries
+func (o output) dotenv(passwords []string) []byte {
+	var b bytes.Buffer
+	for i, key := range o.manifest.keys {
+		fmt.Fprintf(&b, "%s=%s\n", key, dotenvQuote(passwords[i]))
+	}
+	return b.Bytes()
+}
+
+// dotenvQuote prefers single quotes, which keep the value literally.
+// Values containing single quotes are double quoted with backslash escapes instead.
+func dotenvQuote(value string) string {
+	if !strings.Contains(value, "'") {
+		return "'" + value + "'"
+	}
+	escaper := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`, "`", "\\`")
+	return `"` + escaper.Replace(value) + `"`
+}
+
+// cloudConfig renders the chpasswd module of cloud-init user-data, using hashes if a hash was requested
+func (o output) cloudConfig(ctx context.Context, passwords []string) ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteString("#cloud-config\nchpasswd:\n  expire: false\n  users:\n")
+	for i, user := range o.manifest.keys {
+		value, valueType := passwords[i], "text"
+		if o.hasher != nil {
+			if err := ctx.Err(); err != nil {
+				return nil, errors.Wrap(err, "Could not hash passwords")
+			}
+			hash, err := o.hasher.Hash(passwords[i])
+			if err != nil {
+				return nil, hashError(err)
+			}
+			value, valueType = hash, "hash"
+		}
+		fmt.Fprintf(&b, "    - name: %s\n      password: %s\n      type: %s\n", strconv.Quote(user), strconv.Quote(value), valueType)
+	}
+	return b.Bytes(), nil
+}
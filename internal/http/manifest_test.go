@@ -0,0 +1,275 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/domano/pwgen/internal/mock"
+	"github.com/domano/pwgen/internal/passhash"
+	"github.com/domano/pwgen/internal/password"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManifestFromParams(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		query       string
+		format      string
+		expected    manifest
+		expectedErr bool
+	}{
+		{
+			desc:   "No manifest format",
+			query:  "keys=a,b",
+			format: formatJSON,
+		},
+		{
+			desc:     "Kubernetes with defaults",
+			query:    "keys=username,password",
+			format:   formatKubernetes,
+			expected: manifest{keys: []string{"username", "password"}, name: "pwgen"},
+		},
+		{
+			desc:     "Kubernetes with name and namespace",
+			query:    "keys=tls.key&name=db-credentials&namespace=prod",
+			format:   formatKubernetes,
+			expected: manifest{keys: []string{"tls.key"}, name: "db-credentials", namespace: "prod"},
+		},
+		{
+			desc:        "Kubernetes with invalid name",
+			query:       "keys=password&name=DB",
+			format:      formatKubernetes,
+			expectedErr: true,
+		},
+		{
+			desc:        "Kubernetes with namespace containing dots",
+			query:       "keys=password&namespace=team.prod",
+			format:      formatKubernetes,
+			expectedErr: true,
+		},
+		{
+			desc:        "Kubernetes with namespace longer than a label",
+			query:       "keys=password&namespace=" + strings.Repeat("a", 64),
+			format:      formatKubernetes,
+			expectedErr: true,
+		},
+		{
+			desc:     "Kubernetes with longest namespace",
+			query:    "keys=password&namespace=" + strings.Repeat("a", 63),
+			format:   formatKubernetes,
+			expected: manifest{keys: []string{"password"}, name: "pwgen", namespace: strings.Repeat("a", 63)},
+		},
+		{
+			desc:        "Kubernetes with hash",
+			query:       "keys=password&hash=bcrypt",
+			format:      formatKubernetes,
+			expectedErr: true,
+		},
+		{
+			desc:        "dotenv with hash",
+			query:       "keys=DB_PASSWORD&hash=sha512-crypt",
+			format:      formatDotenv,
+			expectedErr: true,
+		},
+		{
+			desc:     "dotenv",
+			query:    "keys=DB_PASSWORD,_SECRET",
+			format:   formatDotenv,
+			expected: manifest{keys: []string{"DB_PASSWORD", "_SECRET"}},
+		},
+		{
+			desc:        "dotenv with invalid variable",
+			query:       "keys=DB-PASSWORD",
+			format:      formatDotenv,
+			expectedErr: true,
+		},
+		{
+			desc:     "cloud-init with sha512-crypt",
+			query:    "keys=ubuntu,admin&hash=sha512-crypt",
+			format:   formatCloudInit,
+			expected: manifest{keys: []string{"ubuntu", "admin"}},
+		},
+		{
+			desc:        "cloud-init with unsupported hash",
+			query:       "keys=ubuntu&hash=argon2id",
+			format:      formatCloudInit,
+			expectedErr: true,
+		},
+		{
+			desc:        "cloud-init with htpasswd user",
+			query:       "keys=ubuntu&hash=bcrypt&user=ubuntu",
+			format:      formatCloudInit,
+			expectedErr: true,
+		},
+		{
+			desc:        "cloud-init with invalid user",
+			query:       "keys=Ubuntu",
+			format:      formatCloudInit,
+			expectedErr: true,
+		},
+		{
+			desc:        "Missing keys",
+			query:       "",
+			format:      formatDotenv,
+			expectedErr: true,
+		},
+		{
+			desc:        "Empty key",
+			query:       "keys=a,,b",
+			format:      formatKubernetes,
+			expectedErr: true,
+		},
+		{
+			desc:        "Duplicate key",
+			query:       "keys=a,b,a",
+			format:      formatKubernetes,
+			expectedErr: true,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// given
+			vals, _ := url.ParseQuery(tC.query)
+
+			// when
+			m, err := manifestFromParams(vals, tC.format)
+
+			// then
+			assert.Equal(t, tC.expectedErr, err != nil)
+			if !tC.expectedErr {
+				assert.Equal(t, tC.expected, m)
+			}
+		})
+	}
+}
+
+func TestOutput_Render_Manifest(t *testing.T) {
+	testCases := []struct {
+		desc                string
+		out                 output
+		expectedContentType string
+		expectedBody        string
+	}{
+		{
+			desc:                "Kubernetes Secret",
+			out:                 output{format: formatKubernetes, manifest: manifest{keys: []string{"username", "true"}, name: "db", namespace: "prod"}},
+			expectedContentType: "application/yaml",
+			expectedBody: `apiVersion: v1
+kind: Secret
+metadata:
+  name: "db"
+  namespace: "prod"
+type: Opaque
+data:
+  "username": YWRtaW4=
+  "true": cyNjcidldA==
+`,
+		},
+		{
+			desc:                "dotenv",
+			out:                 output{format: formatDotenv, manifest: manifest{keys: []string{"DB_USER", "DB_PASSWORD"}}},
+			expectedContentType: "text/plain; charset=utf-8",
+			expectedBody:        "DB_USER='admin'\nDB_PASSWORD=\"s#cr'et\"\n",
+		},
+		{
+			desc:                "cloud-init",
+			out:                 output{format: formatCloudInit, manifest: manifest{keys: []string{"ubuntu", "admin"}}},
+			expectedContentType: "text/cloud-config",
+			expectedBody: `#cloud-config
+chpasswd:
+  expire: false
+  users:
+    - name: "ubuntu"
+      password: "admin"
+      type: text
+    - name: "admin"
+      password: "s#cr'et"
+      type: text
+`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// when
+			contentType, body, err := tC.out.render(context.Background(), []string{"admin", "s#cr'et"})
+
+			// then
+			assert.NoError(t, err)
+			assert.Equal(t, tC.expectedContentType, contentType)
+			assert.Equal(t, tC.expectedBody, string(body))
+		})
+	}
+}
+
+func TestOutput_Render_CloudInit_Hash(t *testing.T) {
+	// given
+	out := output{format: formatCloudInit, hasher: mustHasher(passhash.SHA512Crypt, 0), manifest: manifest{keys: []string{"ubuntu"}}}
+
+	// when
+	_, body, err := out.render(context.Background(), []string{"secret"})
+
+	// then
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), "      password: \"$6$")
+	assert.Contains(t, string(body), "      type: hash\n")
+	assert.NotContains(t, string(body), "secret")
+}
+
+func TestOutput_Render_Manifest_Key_Count(t *testing.T) {
+	// given
+	out := output{format: formatDotenv, manifest: manifest{keys: []string{"A", "B"}}}
+
+	// when
+	_, _, err := out.render(context.Background(), []string{"secret"})
+
+	// then
+	assert.True(t, isRenderInputError(err))
+}
+
+func TestDotenvQuote(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		value    string
+		expected string
+	}{
+		{desc: "Plain", value: "abc", expected: `'abc'`},
+		{desc: "Special characters stay literal", value: `a"$b\c`, expected: `'a"$b\c'`},
+		{desc: "Single quote", value: `a'"$b\c` + "`", expected: `"a'\"\$b\\c\` + "`" + `"`},
+		{desc: "Space", value: "a b", expected: `'a b'`},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			assert.Equal(t, tC.expected, dotenvQuote(tC.value))
+		})
+	}
+}
+
+func TestPasswordHandler_ServeHTTP_Manifest(t *testing.T) {
+	// given a mocked password generator
+	mockPassworder := mock.NewMockPassworder(gomock.NewController(t))
+
+	// and our handler
+	ph := &PasswordHandler{Passworder: mockPassworder}
+
+	// and a recorder for our response
+	rc := httptest.NewRecorder()
+
+	// and a request for a dotenv file without an amount
+	req, _ := http.NewRequest(http.MethodGet, "?minLength=8&format=dotenv&keys=DB_PASSWORD,API_TOKEN", nil)
+
+	// expect a password for each key
+	mockPassworder.EXPECT().Passwords(gomock.Any(), 2, password.Policy{MinLength: 8}).Return([]string{"abcdefgh", "ijklmnop"}, nil).Times(1)
+
+	// when
+	ph.ServeHTTP(rc, req)
+
+	// then
+	assert.Equal(t, http.StatusOK, rc.Code)
+	assert.Equal(t, "text/plain; charset=utf-8", rc.Header().Get("Content-Type"))
+	assert.Equal(t, []string{"DB_PASSWORD='abcdefgh'", "API_TOKEN='ijklmnop'"}, strings.Fields(rc.Body.String()))
+}
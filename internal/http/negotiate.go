@@ -0,0 +1,94 @@
+package http
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// errNotAcceptable is returned when none of the accepted media types can be produced
+var errNotAcceptable = errors.New("no acceptable media type available")
+
+// negotiable lists the media types which can be selected with the Accept header in order of preference
+var negotiable = []struct {
+	mediaType, format string
+}{
+	{"application/json", formatJSON},
+	{"text/plain", formatText},
+	{"text/csv", formatCSV},
+	{"application/x-ndjson", formatNDJSON},
+	{"application/ndjson", formatNDJSON},
+	{"application/xml", formatXML},
+	{"text/xml", formatXML},
+}
+
+// mediaRange is a single entry of an Accept header with its quality
+type mediaRange struct {
+	mediaType string
+	quality   float64
+}
+
+// negotiateFormat selects the format of the most preferred media type of the Accept header as defined by RFC 7231.
+// Without an Accept header JSON is used.
+func negotiateFormat(accept string) (string, error) {
+	if strings.TrimSpace(accept) == "" {
+		return formatJSON, nil
+	}
+	ranges := parseAccept(accept)
+
+	format, best := "", 0.0
+	for _, n := range negotiable {
+		if q := quality(ranges, n.mediaType); q > best {
+			format, best = n.format, q
+		}
+	}
+	if format == "" {
+		return "", errors.Wrapf(errNotAcceptable, "accepted %s", accept)
+	}
+	return format, nil
+}
+
+func parseAccept(accept string) []mediaRange {
+	var ranges []mediaRange
+	for _, entry := range strings.Split(accept, ",") {
+		params := strings.Split(entry, ";")
+		r := mediaRange{mediaType: strings.ToLower(strings.TrimSpace(params[0])), quality: 1}
+		for _, param := range params[1:] {
+			kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+			if len(kv) != 2 || strings.ToLower(kv[0]) != "q" {
+				continue
+			}
+			q, err := strconv.ParseFloat(kv[1], 64)
+			if err != nil || q < 0 || q > 1 {
+				q = 0
+			}
+			r.quality = q
+		}
+		if r.mediaType != "" {
+			ranges = append(ranges, r)
+		}
+	}
+	return ranges
+}
+
+// quality returns the quality of the most specific range matching the media type
+func quality(ranges []mediaRange, mediaType string) float64 {
+	mainType := strings.SplitN(mediaType, "/", 2)[0]
+	q, specificity := 0.0, -1
+	for _, r := range ranges {
+		s := -1
+		switch r.mediaType {
+		case mediaType:
+			s = 2
+		case mainType + "/*":
+			s = 1
+		case "*/*":
+			s = 0
+		}
+		if s > specificity {
+			q, specificity = r.quality, s
+		}
+	}
+	return q
+}
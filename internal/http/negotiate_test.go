@@ -0,0 +1,43 @@
+package http
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegotiateFormat(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		accept        string
+		expected      string
+		notAcceptable bool
+	}{
+		{desc: "No Accept header", accept: "", expected: formatJSON},
+		{desc: "Anything", accept: "*/*", expected: formatJSON},
+		{desc: "Plain text", accept: "text/plain", expected: formatText},
+		{desc: "CSV with charset", accept: "text/csv; charset=utf-8", expected: formatCSV},
+		{desc: "NDJSON", accept: "application/x-ndjson", expected: formatNDJSON},
+		{desc: "XML", accept: "text/xml", expected: formatXML},
+		{desc: "Case insensitive", accept: "Application/XML", expected: formatXML},
+		{desc: "Highest quality wins", accept: "application/json;q=0.5, text/plain;q=0.9", expected: formatText},
+		{desc: "Server preference on equal quality", accept: "text/csv, application/json", expected: formatJSON},
+		{desc: "Wildcard subtype", accept: "text/*", expected: formatText},
+		{desc: "Specific range overrides wildcard", accept: "text/*, text/plain;q=0", expected: formatCSV},
+		{desc: "Browser", accept: "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", expected: formatXML},
+		{desc: "Unsupported type", accept: "text/html", notAcceptable: true},
+		{desc: "Everything refused", accept: "*/*;q=0", notAcceptable: true},
+		{desc: "Invalid quality", accept: "text/plain;q=abc", notAcceptable: true},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// when
+			format, err := negotiateFormat(tC.accept)
+
+			// then
+			assert.Equal(t, tC.notAcceptable, errors.Cause(err) == errNotAcceptable)
+			assert.Equal(t, tC.expected, format)
+		})
+	}
+}
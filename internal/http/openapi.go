@@ -0,0 +1,375 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/domano/pwgen/internal/passhash"
+	"github.com/domano/pwgen/internal/password"
+	log "github.com/sirupsen/logrus"
+)
+
+// OpenAPIPath is the route of the OpenAPI document describing the service
+const OpenAPIPath = "/openapi.json"
+
+// openAPI is the subset of an OpenAPI 3.1 document used to describe the service
+type openAPI struct {
+	OpenAPI    string                     `json:"openapi"`
+	Info       openAPIInfo                `json:"info"`
+	Paths      map[string]openAPIPathItem `json:"paths"`
+	Components openAPIComponents          `json:"components"`
+}
+
+type openAPIInfo struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Version     string `json:"version"`
+}
+
+// openAPIPathItem maps the lower case HTTP methods of a path to their operations
+type openAPIPathItem map[string]*openAPIOperation
+
+type openAPIOperation struct {
+	Summary     string                     `json:"summary"`
+	OperationID string                     `json:"operationId"`
+	Deprecated  bool                       `json:"deprecated,omitempty"`
+	Parameters  []openAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *openAPIRequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]openAPIResponse `json:"responses"`
+}
+
+type openAPIParameter struct {
+	Name        string      `json:"name"`
+	In          string      `json:"in"`
+	Description string      `json:"description"`
+	Schema      *jsonSchema `json:"schema"`
+}
+
+type openAPIRequestBody struct {
+	Required bool                        `json:"required"`
+	Content  map[string]openAPIMediaType `json:"content"`
+}
+
+type openAPIMediaType struct {
+	Schema *jsonSchema `json:"schema"`
+}
+
+type openAPIResponse struct {
+	Description string                      `json:"description"`
+	Headers     map[string]openAPIHeader    `json:"headers,omitempty"`
+	Content     map[string]openAPIMediaType `json:"content,omitempty"`
+}
+
+type openAPIHeader struct {
+	Description string      `json:"description"`
+	Schema      *jsonSchema `json:"schema"`
+}
+
+type openAPIComponents struct {
+	Schemas map[string]*jsonSchema `json:"schemas"`
+}
+
+// Helpers to keep the parameter lists of the document readable
+func queryParam(name, description string, schema *jsonSchema) openAPIParameter {
+	return openAPIParameter{Name: name, In: "query", Description: description, Schema: schema}
+}
+
+func integerSchema(minimum, maximum int64, def interface{}) *jsonSchema {
+	s := &jsonSchema{Type: "integer", Minimum: int64Ptr(minimum), Default: def}
+	if maximum > 0 {
+		s.Maximum = int64Ptr(maximum)
+	}
+	return s
+}
+
+func separatorSchema() *jsonSchema {
+	s := stringSchema(password.DefaultSeparator)
+	s.MaxLength, s.Pattern = intPtr(maxSeparatorLength), separatorChars
+	return s
+}
+
+func booleanSchema() *jsonSchema {
+	return &jsonSchema{Type: "boolean", Default: false}
+}
+
+func stringSchema(def interface{}, enum ...string) *jsonSchema {
+	return &jsonSchema{Type: "string", Enum: enum, Default: def}
+}
+
+func ref(name string) *jsonSchema {
+	return &jsonSchema{Ref: "#/components/schemas/" + name}
+}
+
+func jsonContent(schema *jsonSchema) map[string]openAPIMediaType {
+	return map[string]openAPIMediaType{"application/json": {schema}}
+}
+
+// problemResponse describes an error response with an RFC 7807 problem body
+func problemResponse(description string) openAPIResponse {
+	return openAPIResponse{Description: description, Content: map[string]openAPIMediaType{"application/problem+json": {ref("Problem")}}}
+}
+
+// Responses shared by the operations
+var (
+	badRequestResponse       = problemResponse("A parameter is invalid, the problem names it in param.")
+	methodNotAllowedResponse = openAPIResponse{
+		Description: "The method is not supported by the route.",
+		Headers:     map[string]openAPIHeader{"Allow": {Description: "The supported methods.", Schema: &jsonSchema{Type: "string"}}},
+		Content:     problemResponse("").Content,
+	}
+	internalErrorResponse = problemResponse("The response could not be rendered or the server failed unexpectedly.")
+)
+
+// passwordParams are accepted by both versions of the passwords route, bounded by the limits of the server
+func passwordParams(l Limits) []openAPIParameter {
+	l = l.withDefaults()
+	return []openAPIParameter{
+		queryParam(paramMinLength, fmt.Sprintf("Minimum length of a password, at most %d.", l.MaxLength), integerSchema(0, int64(l.MaxLength), 0)),
+		queryParam(paramSpecialChars, "Exact amount of special characters.", integerSchema(0, 0, 0)),
+		queryParam(paramNumbers, "Exact amount of numbers.", integerSchema(0, 0, 0)),
+		queryParam(paramAmount, fmt.Sprintf("Number of passwords, the number of keys for manifest formats. At most %d.", l.MaxAmount), integerSchema(1, int64(l.MaxAmount), 1)),
+		queryParam(paramSwap, "Swap random vowels for numbers.", booleanSchema()),
+		queryParam(paramGroup, "Split passwords into chunks of this many characters.", integerSchema(0, 0, 0)),
+		queryParam(paramSeparator, "Separator between chunks, up to 4 printable ASCII characters without spaces, quotes, commas or backslashes.", separatorSchema()),
+		queryParam(paramCountSeparators, "Count separators towards minLength.", booleanSchema()),
+		queryParam(paramSpelling, "Add a spelling of each password, NATO for en and DIN 5009 for de.", stringSchema(nil, "en", "de")),
+		queryParam(paramProfile, "Enforce the rules of a password consumer.", stringSchema(nil, password.ProfileNames()...)),
+		queryParam(paramFormat, "Response format, overrides the Accept header.", stringSchema(nil, formatJSON, formatText, formatCSV, formatNDJSON, formatXML, formatQR, formatKubernetes, formatDotenv, formatCloudInit)),
+		queryParam(paramImage, "Image type of QR codes.", stringSchema(imagePNG, imagePNG, imageSVG)),
+		queryParam(paramSSID, "Wrap the password of a QR code into a Wi-Fi network payload for this SSID of 1 to 32 bytes without control characters, implies the wifi profile.", stringSchema(nil)),
+		queryParam(paramHash, fmt.Sprintf("Add a hash of each password, at most %d passwords are hashed per request. bcrypt only accepts passwords up to 72 bytes.", l.MaxHashes), stringSchema(nil, passhash.Algorithms...)),
+		queryParam(paramCost, "Work factor of the hash, the range depends on the algorithm. bcrypt 10 to 14, scrypt 14 to 17, argon2id 1 to 10, pbkdf2-sha256 100000 to 2000000, sha512-crypt 1000 to 500000 and scram-sha-256 4096 to 2000000.", integerSchema(0, 0, nil)),
+		queryParam(paramUser, "Turn hashes into htpasswd lines for this user, requires the apr1 or bcrypt hash.", stringSchema(nil)),
+		queryParam(paramKeys, "Comma separated names of the secrets in a manifest format.", stringSchema(nil)),
+		queryParam(paramName, "Name of the Kubernetes Secret.", stringSchema(defaultSecretName)),
+		queryParam(paramNamespace, "Namespace of the Kubernetes Secret, a DNS label of at most 63 characters.", stringSchema(nil)),
+	}
+}
+
+// passwordsRequestBody is the policy which can be posted instead of query params for the policy
+var passwordsRequestBody = &openAPIRequestBody{Content: jsonContent(ref("PasswordPolicy"))}
+
+// passwordsContent lists the media types of the list, QR code and manifest formats
+func passwordsContent(list *jsonSchema) map[string]openAPIMediaType {
+	text := &jsonSchema{Type: "string"}
+	binary := &jsonSchema{Type: "string", Format: "binary"}
+	return map[string]openAPIMediaType{
+		"application/json":     {list},
+		"text/plain":           {text},
+		"text/csv":             {text},
+		"application/x-ndjson": {text},
+		"application/xml":      {text},
+		"image/png":            {binary},
+		"image/svg+xml":        {text},
+		"application/yaml":     {text},
+		"text/cloud-config":    {text},
+	}
+}
+
+// passwordsResponses are the responses of both versions of the passwords route besides 200
+func passwordsResponses(l Limits) map[string]openAPIResponse {
+	l = l.withDefaults()
+	return map[string]openAPIResponse{
+		"400": problemResponse("A parameter is invalid, the problem names it in param. Invalid fields of posted policies are listed in errors."),
+		"401": problemResponse("The server requires a client certificate or an API key and the request carries neither, or the key is invalid."),
+		"403": problemResponse("The rules of the caller do not allow the route or the requested profile."),
+		"405": methodNotAllowedResponse,
+		"406": problemResponse("None of the accepted media types can be produced."),
+		"413": problemResponse(fmt.Sprintf("The posted policy is larger than 64 KiB or the response would exceed the output limit of %d bytes.", l.MaxOutputBytes)),
+		"415": problemResponse("The posted policy is not application/json."),
+		"500": internalErrorResponse,
+		"503": problemResponse(fmt.Sprintf("The passwords could not be generated and hashed within the deadline of %s.", l.Timeout)),
+	}
+}
+
+// passwordsOperation describes a method of a passwords route
+func passwordsOperation(method, summary, operationID string, deprecated bool, ok openAPIResponse, l Limits) *openAPIOperation {
+	op := &openAPIOperation{
+		Summary:     summary,
+		OperationID: operationID,
+		Deprecated:  deprecated,
+		Parameters:  passwordParams(l),
+		Responses:   map[string]openAPIResponse{"200": ok},
+	}
+	for status, resp := range passwordsResponses(l) {
+		op.Responses[status] = resp
+	}
+	if method == http.MethodPost {
+		op.RequestBody = passwordsRequestBody
+	}
+	return op
+}
+
+// keyOperation describes the GET operation of a key generating route
+func keyOperation(summary, operationID string, params []openAPIParameter, ok openAPIResponse) openAPIPathItem {
+	return openAPIPathItem{"get": {
+		Summary:     summary,
+		OperationID: operationID,
+		Parameters:  params,
+		Responses:   map[string]openAPIResponse{"200": ok, "400": badRequestResponse, "405": methodNotAllowedResponse, "500": internalErrorResponse},
+	}}
+}
+
+var (
+	v1PasswordsResponse = openAPIResponse{
+		Description: "The generated passwords in the requested format.",
+		Headers: map[string]openAPIHeader{
+			"Deprecation": {Description: "Date since the route is deprecated as defined by RFC 9745.", Schema: &jsonSchema{Type: "string"}},
+			"Link":        {Description: "The successor-version of the route.", Schema: &jsonSchema{Type: "string"}},
+		},
+		Content: passwordsContent(&jsonSchema{Type: "array", Items: &jsonSchema{OneOf: []*jsonSchema{{Type: "string"}, ref("PasswordResult")}}}),
+	}
+	v2PasswordsResponse = openAPIResponse{
+		Description: "The generated passwords, JSON responses are wrapped in an envelope with metadata.",
+		Headers: map[string]openAPIHeader{
+			headerRequestID: {Description: "ID of the request.", Schema: &jsonSchema{Type: "string"}},
+		},
+		Content: passwordsContent(ref("PasswordEnvelope")),
+	}
+)
+
+// newOpenAPIDocument describes every route of the service with the given limits
+func newOpenAPIDocument(limits Limits) *openAPI {
+	return &openAPI{
+		OpenAPI: "3.1.0",
+		Info: openAPIInfo{
+			Title:       "pwgen",
+			Description: "Generates passwords, one-time password secrets and keys.",
+			Version:     "2",
+		},
+		Paths: map[string]openAPIPathItem{
+			"/passwords": {
+				"get":  passwordsOperation(http.MethodGet, "Generate passwords", "getPasswords", true, v1PasswordsResponse, limits),
+				"post": passwordsOperation(http.MethodPost, "Generate passwords with a posted policy", "postPasswords", true, v1PasswordsResponse, limits),
+			},
+			"/v2/passwords": {
+				"get":  passwordsOperation(http.MethodGet, "Generate passwords with metadata", "getPasswordsV2", false, v2PasswordsResponse, limits),
+				"post": passwordsOperation(http.MethodPost, "Generate passwords with metadata and a posted policy", "postPasswordsV2", false, v2PasswordsResponse, limits),
+			},
+			"/otp": keyOperation("Provision a TOTP secret", "getOTP", []openAPIParameter{
+				queryParam(paramDigits, "Number of digits of a code.", integerSchema(6, 8, 6)),
+				queryParam(paramPeriod, "Seconds a code is valid.", integerSchema(1, 0, 30)),
+				queryParam(paramAlgorithm, "HMAC algorithm.", stringSchema("SHA1", "SHA1", "SHA256", "SHA512")),
+				queryParam(paramIssuer, "Issuer shown in authenticator apps.", stringSchema(nil)),
+				queryParam(paramAccount, "Account shown in authenticator apps.", stringSchema(nil)),
+				queryParam(paramFormat, "Response format, qr for a QR code of the otpauth URI.", stringSchema(formatJSON, formatJSON, formatQR)),
+				queryParam(paramImage, "Image type of QR codes.", stringSchema(imagePNG, imagePNG, imageSVG)),
+			}, openAPIResponse{Description: "The secret or a QR code of it.", Content: map[string]openAPIMediaType{
+				"application/json": {ref("OTP")},
+				"image/png":        {&jsonSchema{Type: "string", Format: "binary"}},
+				"image/svg+xml":    {&jsonSchema{Type: "string"}},
+			}}),
+			"/ssh-keys": keyOperation("Generate an SSH key pair", "getSSHKey", []openAPIParameter{
+				queryParam(paramType, "Key type.", stringSchema("ed25519", "ed25519", "ecdsa", "rsa")),
+				queryParam(paramBits, "Key size, 256, 384 or 521 for ecdsa and 2048, 3072 or 4096 for rsa.", integerSchema(0, 0, nil)),
+				queryParam(paramComment, "Comment appended to the public key, without line breaks or other control characters.", stringSchema(nil)),
+				queryParam(paramEncrypt, "Encrypt the private key with a generated passphrase.", booleanSchema()),
+			}, openAPIResponse{Description: "The key pair in OpenSSH formats.", Content: jsonContent(ref("SSHKey"))}),
+			"/x25519-keys": keyOperation("Generate a Curve25519 key pair", "getX25519Key", []openAPIParameter{
+				queryParam(paramFormat, "Key encoding.", stringSchema(formatWireGuard, formatWireGuard, formatAge)),
+				queryParam(paramPresharedKey, "Generate a WireGuard preshared key as well.", booleanSchema()),
+			}, openAPIResponse{Description: "The key pair in the requested encoding.", Content: jsonContent(ref("X25519Key"))}),
+			"/jwk": keyOperation("Generate a JSON Web Key", "getJWK", []openAPIParameter{
+				queryParam(paramAlg, "Algorithm.", stringSchema("ES256", "HS256", "HS384", "HS512", "ES256", "ES384", "EdDSA", "RS256")),
+				queryParam(paramBits, "Key size of RSA keys, 2048, 3072 or 4096.", integerSchema(0, 0, nil)),
+				queryParam(paramJWKS, "Return a key set with the public key as well, not available for symmetric keys.", booleanSchema()),
+			}, openAPIResponse{Description: "The key and optionally a key set.", Content: jsonContent(ref("JWK"))}),
+			PolicySchemaPath: {"get": {
+				Summary:     "JSON Schema of posted password policies",
+				OperationID: "getPolicySchema",
+				Responses: map[string]openAPIResponse{
+					"200": {Description: "The JSON Schema.", Content: map[string]openAPIMediaType{"application/schema+json": {&jsonSchema{Type: "object"}}}},
+					"405": methodNotAllowedResponse,
+				},
+			}},
+			OpenAPIPath: {"get": {
+				Summary:     "This OpenAPI document",
+				OperationID: "getOpenAPI",
+				Responses: map[string]openAPIResponse{
+					"200": {Description: "The OpenAPI document.", Content: jsonContent(&jsonSchema{Type: "object"})},
+					"405": methodNotAllowedResponse,
+				},
+			}},
+		},
+		Components: openAPIComponents{Schemas: map[string]*jsonSchema{
+			"PasswordPolicy": limitedPolicySchema(limits),
+			"PasswordResult": {Type: "object", Properties: map[string]*jsonSchema{
+				"password": {Type: "string"},
+				"spelling": {Type: "string"},
+				"hash":     {Type: "string"},
+			}},
+			"PasswordEnvelope": {Type: "object", Properties: map[string]*jsonSchema{
+				"requestId": {Type: "string"},
+				"generator": {Type: "string", Enum: []string{generatorRandom}},
+				"policy":    {Type: "object", Description: "Effective policy with the fields of posted policies."},
+				"passwords": {Type: "array", Items: &jsonSchema{Type: "object", Properties: map[string]*jsonSchema{
+					"password":    {Type: "string"},
+					"spelling":    {Type: "string"},
+					"hash":        {Type: "string"},
+					"entropyBits": {Type: "number"},
+				}}},
+			}},
+			"Problem": {Type: "object", Description: "RFC 7807 problem details.", Properties: map[string]*jsonSchema{
+				"type":     {Type: "string", Description: "Stable URN of the kind of problem."},
+				"title":    {Type: "string"},
+				"status":   {Type: "integer"},
+				"detail":   {Type: "string"},
+				"instance": {Type: "string"},
+				"param":    {Type: "string", Description: "Query parameter which caused the problem."},
+				"errors": {Type: "array", Description: "Invalid fields of a posted policy.", Items: &jsonSchema{Type: "object", Properties: map[string]*jsonSchema{
+					"field":   {Type: "string"},
+					"message": {Type: "string"},
+				}}},
+			}},
+			"OTP": {Type: "object", Properties: map[string]*jsonSchema{
+				"secret":    {Type: "string"},
+				"uri":       {Type: "string"},
+				"algorithm": {Type: "string"},
+				"digits":    {Type: "integer"},
+				"period":    {Type: "integer"},
+			}},
+			"SSHKey": {Type: "object", Properties: map[string]*jsonSchema{
+				"publicKey":   {Type: "string"},
+				"privateKey":  {Type: "string"},
+				"fingerprint": {Type: "string"},
+				"passphrase":  {Type: "string"},
+			}},
+			"X25519Key": {Type: "object", Properties: map[string]*jsonSchema{
+				"privateKey":   {Type: "string"},
+				"publicKey":    {Type: "string"},
+				"presharedKey": {Type: "string"},
+				"identity":     {Type: "string"},
+				"recipient":    {Type: "string"},
+			}},
+			"JWK": {Type: "object", Properties: map[string]*jsonSchema{
+				"key":  {Type: "object"},
+				"jwks": {Type: "object"},
+			}},
+		}},
+	}
+}
+
+// OpenAPIHandler publishes the OpenAPI document with the limits of the password handlers
+type OpenAPIHandler struct {
+	Limits Limits
+}
+
+// NewOpenAPIHandler constructs a new OpenAPIHandler
+func NewOpenAPIHandler(limits Limits) *OpenAPIHandler {
+	return &OpenAPIHandler{Limits: limits}
+}
+
+func (oh *OpenAPIHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		methodNotAllowed(w, r, http.MethodGet, http.MethodHead)
+		return
+	}
+	body, err := json.MarshalIndent(newOpenAPIDocument(oh.Limits), "", "  ")
+	if err != nil {
+		writeProblem(w, r, http.StatusInternalServerError, err)
+		log.WithError(err).Errorln("Error while marshalling json")
+		return
+	}
+	writeBody(w, r, "application/json", body)
+}
@@ -0,0 +1,211 @@
+package http
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// paramConstants returns the values of the param* constants declared in the given files
+func paramConstants(t *testing.T, files ...string) []string {
+	var params []string
+	fset := token.NewFileSet()
+	for _, file := range files {
+		f, err := parser.ParseFile(fset, file, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, decl := range f.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.CONST {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				vs := spec.(*ast.ValueSpec)
+				for i, name := range vs.Names {
+					if !strings.HasPrefix(name.Name, "param") {
+						continue
+					}
+					value, err := strconv.Unquote(vs.Values[i].(*ast.BasicLit).Value)
+					if err != nil {
+						t.Fatal(err)
+					}
+					params = append(params, value)
+				}
+			}
+		}
+	}
+	return params
+}
+
+// servedDocument requests the OpenAPI document from a handler with the given limits
+func servedDocument(t *testing.T, limits Limits) openAPI {
+	rc := httptest.NewRecorder()
+	NewOpenAPIHandler(limits).ServeHTTP(rc, httptest.NewRequest(http.MethodGet, OpenAPIPath, nil))
+	assert.Equal(t, http.StatusOK, rc.Code)
+	assert.Equal(t, "application/json", rc.Header().Get("Content-Type"))
+	var doc openAPI
+	if err := json.Unmarshal(rc.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func parameterNames(op *openAPIOperation) []string {
+	var names []string
+	for _, p := range op.Parameters {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+func TestOpenAPI_Password_Params(t *testing.T) {
+	// given the params declared for the password handler
+	params := paramConstants(t, "http.go")
+
+	// when
+	doc := servedDocument(t, Limits{})
+
+	// then every operation of both passwords routes documents exactly these params
+	for _, path := range []string{"/passwords", "/v2/passwords"} {
+		for method, op := range doc.Paths[path] {
+			assert.ElementsMatch(t, params, parameterNames(op), method+" "+path)
+		}
+	}
+}
+
+func TestOpenAPI_All_Params(t *testing.T) {
+	// given the params declared by all handlers
+	files, _ := filepath.Glob("*.go")
+	var sources []string
+	for _, file := range files {
+		if !strings.HasSuffix(file, "_test.go") {
+			sources = append(sources, file)
+		}
+	}
+	params := paramConstants(t, sources...)
+
+	// when
+	doc := servedDocument(t, Limits{})
+
+	// then each of them is documented and nothing else
+	documented := map[string]bool{}
+	for _, item := range doc.Paths {
+		for _, op := range item {
+			for _, name := range parameterNames(op) {
+				documented[name] = true
+			}
+		}
+	}
+	var names []string
+	for name := range documented {
+		names = append(names, name)
+	}
+	assert.ElementsMatch(t, dedupe(params), names)
+}
+
+func TestOpenAPI_References(t *testing.T) {
+	// given the document as generic JSON
+	document := newOpenAPIDocument(Limits{})
+	body, err := json.Marshal(document)
+	assert.NoError(t, err)
+	var doc interface{}
+	assert.NoError(t, json.Unmarshal(body, &doc))
+
+	// when collecting all references
+	var refs []string
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			for key, child := range v {
+				if ref, ok := child.(string); ok && key == "$ref" {
+					refs = append(refs, ref)
+				}
+				walk(child)
+			}
+		case []interface{}:
+			for _, child := range v {
+				walk(child)
+			}
+		}
+	}
+	walk(doc)
+
+	// then each of them points to a schema of the components
+	assert.NotEmpty(t, refs)
+	for _, ref := range refs {
+		name := strings.TrimPrefix(ref, "#/components/schemas/")
+		assert.Contains(t, document.Components.Schemas, name, ref)
+	}
+}
+
+func TestOpenAPI_Limits(t *testing.T) {
+	testCases := []struct {
+		desc              string
+		limits            Limits
+		expectedAmount    int64
+		expectedMinLength int64
+	}{
+		{desc: "Defaults", expectedAmount: 1000, expectedMinLength: 1024},
+		{desc: "Configured", limits: Limits{MaxAmount: 50, MaxLength: 64}, expectedAmount: 50, expectedMinLength: 64},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// when
+			doc := servedDocument(t, tC.limits)
+
+			// then the params of both passwords routes publish the limits as maximums
+			for _, path := range []string{"/passwords", "/v2/passwords"} {
+				for method, op := range doc.Paths[path] {
+					for _, p := range op.Parameters {
+						switch p.Name {
+						case paramAmount:
+							assert.Equal(t, tC.expectedAmount, *p.Schema.Maximum, method+" "+path)
+						case paramMinLength:
+							assert.Equal(t, tC.expectedMinLength, *p.Schema.Maximum, method+" "+path)
+						}
+					}
+				}
+			}
+
+			// and so does the policy schema
+			policy := doc.Components.Schemas["PasswordPolicy"]
+			assert.Equal(t, tC.expectedAmount, *policy.Properties["amount"].Maximum)
+			assert.Equal(t, tC.expectedMinLength, *policy.Properties["minLength"].Maximum)
+			assert.Equal(t, tC.expectedMinLength, *policy.Properties["maxLength"].Maximum)
+		})
+	}
+}
+
+func TestOpenAPIHandler_ServeHTTP_Method(t *testing.T) {
+	// given
+	rc := httptest.NewRecorder()
+
+	// when
+	NewOpenAPIHandler(Limits{}).ServeHTTP(rc, httptest.NewRequest(http.MethodPost, OpenAPIPath, nil))
+
+	// then
+	assert.Equal(t, http.StatusMethodNotAllowed, rc.Code)
+}
+
+func dedupe(values []string) []string {
+	seen := map[string]bool{}
+	var unique []string
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			unique = append(unique, v)
+		}
+	}
+	return unique
+}
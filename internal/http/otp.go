@@ -0,0 +1,129 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/domano/pwgen/internal/otp"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// OTPHandler provisions secrets for time-based one-time passwords
+type OTPHandler struct{}
+
+// Constants for the available query params
+const paramDigits = "digits"
+const paramPeriod = "period"
+const paramAlgorithm = "algorithm"
+const paramIssuer = "issuer"
+const paramAccount = "account"
+
+// otpResponse contains the secret and everything needed to set up an authenticator with it
+type otpResponse struct {
+	Secret    string `json:"secret"`
+	URI       string `json:"uri"`
+	Algorithm string `json:"algorithm"`
+	Digits    int    `json:"digits"`
+	Period    int    `json:"period"`
+}
+
+// NewOTPHandler constructs a new OTPHandler
+func NewOTPHandler() *OTPHandler {
+	return &OTPHandler{}
+}
+
+func (oh *OTPHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Secrets are only provisioned, so nothing except GET is supported
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		methodNotAllowed(w, r, http.MethodGet, http.MethodHead)
+		return
+	}
+
+	// Keys are only rendered as JSON or QR code
+	format, image, err := formatFromParams(r.URL.Query(), "")
+	if err == nil && format != formatJSON && format != formatQR {
+		err = invalidParam(paramFormat, errors.Errorf("Query Parameter %s must be %s or %s, got %s instead", paramFormat, formatJSON, formatQR, format))
+	}
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, err)
+		log.WithError(err).Warnln("Received a bad request.")
+		return
+	}
+
+	options, err := oh.options(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, err)
+		log.WithError(err).Warnln("Received a bad request.")
+		return
+	}
+	key, err := otp.NewKey(options...)
+	if err != nil {
+		writeProblem(w, r, http.StatusInternalServerError, err)
+		log.WithError(err).Errorln("Error while creating otp key")
+		return
+	}
+
+	contentType, body, err := renderKey(key, format, image)
+	if err != nil {
+		writeProblem(w, r, http.StatusInternalServerError, err)
+		log.WithError(err).Errorln("Error while rendering response")
+		return
+	}
+	writeBody(w, r, contentType, body)
+}
+
+// options reads the options of the key from the query params, they are validated before any secret is generated
+func (oh *OTPHandler) options(r *http.Request) ([]otp.Option, error) {
+	// Get parameters from URL & validate them
+	params := r.URL.Query()
+
+	options := []otp.Option{otp.Label(params.Get(paramIssuer), params.Get(paramAccount))}
+	digits, err := numberFromParams(params, paramDigits)
+	if err != nil {
+		return nil, errors.Wrap(err, "Could not read digits parameter")
+	}
+	if digits != 0 {
+		if err := otp.ValidDigits(digits); err != nil {
+			return nil, invalidParam(paramDigits, err)
+		}
+		options = append(options, otp.Digits(digits))
+	}
+	period, err := numberFromParams(params, paramPeriod)
+	if err != nil {
+		return nil, errors.Wrap(err, "Could not read period parameter")
+	}
+	if period != 0 {
+		if err := otp.ValidPeriod(time.Duration(period) * time.Second); err != nil {
+			return nil, invalidParam(paramPeriod, err)
+		}
+		options = append(options, otp.Period(time.Duration(period)*time.Second))
+	}
+	if algorithm := params.Get(paramAlgorithm); algorithm != "" {
+		if err := otp.ValidAlgorithm(otp.Algorithm(strings.ToUpper(algorithm))); err != nil {
+			return nil, invalidParam(paramAlgorithm, err)
+		}
+		options = append(options, otp.WithAlgorithm(otp.Algorithm(strings.ToUpper(algorithm))))
+	}
+	return options, nil
+}
+
+// renderKey returns the content type and body for the key, QR codes contain the otpauth URI
+func renderKey(key otp.Key, format, image string) (string, []byte, error) {
+	if format == formatQR {
+		return qrImage(key.URI(), image)
+	}
+	body, err := json.Marshal(otpResponse{
+		Secret:    key.Base32(),
+		URI:       key.URI(),
+		Algorithm: string(key.Algorithm),
+		Digits:    key.Digits,
+		Period:    int(key.Period / time.Second),
+	})
+	if err != nil {
+		return "", nil, errors.Wrap(err, "Error while marshalling json")
+	}
+	return "application/json", body, nil
+}
@@ -0,0 +1,142 @@
+package http
+
+import (
+	"encoding/base32"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/domano/pwgen/internal/otp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOTPHandler_ServeHTTP(t *testing.T) {
+	testCases := []struct {
+		desc string
+
+		//given
+		method string
+		query  string
+
+		// expect
+		expectedResponse  int
+		expectedType      string
+		expectedDigits    int
+		expectedPeriod    int
+		expectedAlgorithm string
+		expectedParam     string
+	}{
+		{
+			desc:   "GET, no params",
+			method: http.MethodGet, query: "",
+			expectedResponse: http.StatusOK, expectedType: "application/json",
+			expectedDigits: 6, expectedPeriod: 30, expectedAlgorithm: "SHA1",
+		},
+		{
+			desc:   "GET, 8 digits, 60 seconds, sha256",
+			method: http.MethodGet, query: "digits=8&period=60&algorithm=sha256&issuer=ACME&account=svc",
+			expectedResponse: http.StatusOK, expectedType: "application/json",
+			expectedDigits: 8, expectedPeriod: 60, expectedAlgorithm: "SHA256",
+		},
+		{
+			desc:   "GET, QR code",
+			method: http.MethodGet, query: "format=qr&image=svg",
+			expectedResponse: http.StatusOK, expectedType: "image/svg+xml",
+		},
+		{
+			desc:   "GET, invalid digits",
+			method: http.MethodGet, query: "digits=12",
+			expectedResponse: http.StatusBadRequest, expectedParam: paramDigits,
+		},
+		{
+			desc:   "GET, invalid period",
+			method: http.MethodGet, query: "period=abc",
+			expectedResponse: http.StatusBadRequest, expectedParam: paramPeriod,
+		},
+		{
+			desc:   "GET, period of zero seconds",
+			method: http.MethodGet, query: "period=-1",
+			expectedResponse: http.StatusBadRequest, expectedParam: paramPeriod,
+		},
+		{
+			desc:   "GET, unknown algorithm",
+			method: http.MethodGet, query: "algorithm=md5",
+			expectedResponse: http.StatusBadRequest, expectedParam: paramAlgorithm,
+		},
+		{
+			desc:   "GET, unknown format",
+			method: http.MethodGet, query: "format=gif",
+			expectedResponse: http.StatusBadRequest, expectedParam: paramFormat,
+		},
+		{
+			desc:   "GET, list format",
+			method: http.MethodGet, query: "format=csv",
+			expectedResponse: http.StatusBadRequest, expectedParam: paramFormat,
+		},
+		{
+			desc:   "POST",
+			method: http.MethodPost, query: "",
+			expectedResponse: http.StatusMethodNotAllowed,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// given our handler
+			oh := NewOTPHandler()
+
+			// and a recorder for our response
+			rc := httptest.NewRecorder()
+
+			// and a test request
+			req, _ := http.NewRequest(tC.method, "?"+tC.query, nil)
+
+			// when our endpoint is called
+			oh.ServeHTTP(rc, req)
+
+			// then
+			assert.Equal(t, tC.expectedResponse, rc.Code)
+			if tC.expectedResponse == http.StatusBadRequest {
+				var p problem
+				assert.NoError(t, json.Unmarshal(rc.Body.Bytes(), &p))
+				assert.Equal(t, tC.expectedParam, p.Param)
+			}
+			if tC.expectedType == "" {
+				return
+			}
+			assert.Equal(t, tC.expectedType, rc.Header().Get("Content-Type"))
+			if tC.expectedType != "application/json" {
+				return
+			}
+			var resp otpResponse
+			assert.NoError(t, json.NewDecoder(rc.Body).Decode(&resp))
+			assert.Equal(t, tC.expectedDigits, resp.Digits)
+			assert.Equal(t, tC.expectedPeriod, resp.Period)
+			assert.Equal(t, tC.expectedAlgorithm, resp.Algorithm)
+			assert.Contains(t, resp.URI, "secret="+resp.Secret)
+		})
+	}
+}
+
+func TestOTPHandler_ServeHTTP_Verify_Secret(t *testing.T) {
+	// given a provisioned secret with other parameters than the defaults
+	rc := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/otp?algorithm=SHA256&period=60&digits=8", nil)
+	NewOTPHandler().ServeHTTP(rc, req)
+	var resp otpResponse
+	_ = json.NewDecoder(rc.Body).Decode(&resp)
+
+	// and a code computed from it like an authenticator app would
+	secret, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(resp.Secret)
+	assert.NoError(t, err)
+	now := time.Now()
+	code := otp.Key{Secret: secret, Digits: 8, Period: time.Minute, Algorithm: otp.SHA256}.Code(now)
+
+	// when
+	valid, err := otp.Verify(resp.URI, code, now)
+
+	// then
+	assert.NoError(t, err)
+	assert.True(t, valid)
+}
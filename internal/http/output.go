@@ -0,0 +1,330 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"net/url"
+	"strings"
+
+	"github.com/domano/pwgen/internal/passhash"
+	"github.com/domano/pwgen/internal/qr"
+	"github.com/domano/pwgen/internal/spelling"
+	"github.com/pkg/errors"
+)
+
+// Formats and images which can be requested with the format and image query params
+const (
+	formatJSON   = "json"
+	formatText   = "text"
+	formatCSV    = "csv"
+	formatNDJSON = "ndjson"
+	formatXML    = "xml"
+	formatQR     = "qr"
+	imagePNG     = "png"
+	imageSVG     = "svg"
+)
+
+// Pixels per QR code module in PNG images
+const qrScale = 8
+
+// errSinglePassword is returned when a format can only hold one password
+var errSinglePassword = errors.New("format can only render a single password")
+
+// output describes how generated passwords are rendered in the response
+type output struct {
+	format   string
+	image    string
+	ssid     string
+	alphabet *spelling.Alphabet
+	hasher   passhash.Hasher
+	manifest manifest
+}
+
+// passwordResult is returned instead of a bare password when a spelling or hash was requested
+type passwordResult struct {
+	Password string `json:"password" xml:",chardata"`
+	Spelling string `json:"spelling,omitempty" xml:"spelling,attr,omitempty"`
+	Hash     string `json:"hash,omitempty" xml:"hash,attr,omitempty"`
+}
+
+// xmlPasswords is the root element of XML responses
+type xmlPasswords struct {
+	XMLName   xml.Name         `xml:"passwords"`
+	Passwords []passwordResult `xml:"password"`
+}
+
+// outputFromParams reads the output from the query params, the format falls back to the Accept header
+func outputFromParams(vals url.Values, accept string) (output, error) {
+	out := output{ssid: vals.Get(paramSSID)}
+	if _, ok := vals[paramSSID]; ok {
+		if err := qr.ValidSSID(out.ssid); err != nil {
+			return out, invalidParam(paramSSID, err)
+		}
+	}
+	format, image, err := formatFromParams(vals, accept)
+	if err != nil {
+		return out, err
+	}
+	out.format, out.image = format, image
+	alphabet, err := alphabetFromParams(vals, paramSpelling)
+	if err != nil {
+		return out, err
+	}
+	out.alphabet = alphabet
+	out.hasher, err = hasherFromParams(vals)
+	if err != nil {
+		return out, err
+	}
+	out.manifest, err = manifestFromParams(vals, format)
+	return out, err
+}
+
+// hasherFromParams reads the hash algorithm and its cost, a user turns hashes into htpasswd lines
+func hasherFromParams(vals url.Values) (passhash.Hasher, error) {
+	algorithm, user := vals.Get(paramHash), vals.Get(paramUser)
+	if algorithm == "" {
+		if user != "" {
+			return nil, invalidParam(paramUser, errors.Errorf("Query Parameter %s requires the %s parameter", paramUser, paramHash))
+		}
+		return nil, nil
+	}
+	cost, err := numberFromParams(vals, paramCost)
+	if err != nil {
+		return nil, errors.Wrap(err, "Could not read cost parameter")
+	}
+	var hasher passhash.Hasher
+	if user != "" {
+		hasher, err = passhash.NewHtpasswd(user, algorithm, cost)
+	} else {
+		hasher, err = passhash.New(algorithm, cost)
+	}
+	if err != nil {
+		return nil, invalidParam(hashErrorParam(err), errors.Wrap(err, "Could not create hasher"))
+	}
+	return hasher, nil
+}
+
+// hashErrorParam finds the parameter which made creating a hasher fail
+func hashErrorParam(err error) string {
+	switch errors.Cause(err) {
+	case passhash.ErrCost:
+		return paramCost
+	case passhash.ErrUser:
+		return paramUser
+	}
+	return paramHash
+}
+
+// formatFromParams reads the response format, which overrides the Accept header, and the image type used for QR codes
+func formatFromParams(vals url.Values, accept string) (format string, image string, err error) {
+	image = imagePNG
+	format = vals.Get(paramFormat)
+	if format == "" {
+		format, err = negotiateFormat(accept)
+		if err != nil {
+			return "", "", err
+		}
+	}
+	if !isListFormat(format) && format != formatQR && !isManifestFormat(format) {
+		return "", "", invalidParam(paramFormat, errors.Errorf("Query Parameter %s was no known format, got %s instead", paramFormat, format))
+	}
+	if val := vals.Get(paramImage); val != "" {
+		image = val
+	}
+	if image != imagePNG && image != imageSVG {
+		return "", "", invalidParam(paramImage, errors.Errorf("Query Parameter %s was no known image type, got %s instead", paramImage, image))
+	}
+	return format, image, nil
+}
+
+// render returns the content type and body for the passwords, hashing stops when the context is done
+func (o output) render(ctx context.Context, passwords []string) (string, []byte, error) {
+	if o.format == formatQR {
+		return o.renderQR(passwords)
+	}
+	if isManifestFormat(o.format) {
+		return o.renderManifest(ctx, passwords)
+	}
+
+	results, err := o.results(ctx, passwords)
+	if err != nil {
+		return "", nil, err
+	}
+	switch o.format {
+	case formatText:
+		return "text/plain; charset=utf-8", o.text(results), nil
+	case formatCSV:
+		body, err := o.csv(results)
+		return "text/csv; charset=utf-8", body, err
+	case formatNDJSON:
+		body, err := o.ndjson(results)
+		return "application/x-ndjson", body, err
+	case formatXML:
+		body, err := xml.Marshal(xmlPasswords{Passwords: results})
+		if err != nil {
+			return "", nil, errors.Wrap(err, "Error while marshalling xml")
+		}
+		return "application/xml; charset=utf-8", append([]byte(xml.Header), body...), nil
+	}
+
+	body, err := json.Marshal(o.jsonValues(results))
+	if err != nil {
+		return "", nil, errors.Wrap(err, "Error while marshalling json")
+	}
+	return "application/json", body, nil
+}
+
+func (o output) renderQR(passwords []string) (string, []byte, error) {
+	if len(passwords) != 1 {
+		return "", nil, invalidParam(paramAmount, errors.Wrapf(errSinglePassword, "got %d passwords for a QR code", len(passwords)))
+	}
+	payload := passwords[0]
+	if o.ssid != "" {
+		payload = qr.WiFi(o.ssid, payload)
+	}
+	return qrImage(payload, o.image)
+}
+
+// qrImage renders the payload as QR code image and returns its content type
+func qrImage(payload, image string) (string, []byte, error) {
+	code, err := qr.Encode([]byte(payload), qr.Medium)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "Could not encode QR code")
+	}
+	if image == imageSVG {
+		return "image/svg+xml", code.SVG(), nil
+	}
+	body, err := code.PNG(qrScale)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "Could not render QR code")
+	}
+	return "image/png", body, nil
+}
+
+// isRenderInputError reports whether rendering failed because of the requested parameters
+func isRenderInputError(err error) bool {
+	cause := errors.Cause(err)
+	return cause == errSinglePassword || cause == errKeyCount || cause == qr.ErrTooLong || cause == passhash.ErrTooLong
+}
+
+// results adds the requested spelling and hash to each password
+func (o output) results(ctx context.Context, passwords []string) ([]passwordResult, error) {
+	results := make([]passwordResult, len(passwords))
+	for i, pw := range passwords {
+		results[i].Password = pw
+		if o.alphabet != nil {
+			results[i].Spelling = o.alphabet.Spell(pw)
+		}
+		if o.hasher == nil {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, errors.Wrap(err, "Could not hash passwords")
+		}
+		hash, err := o.hasher.Hash(pw)
+		if err != nil {
+			return nil, hashError(err)
+		}
+		results[i].Hash = hash
+	}
+	return results, nil
+}
+
+// hashError blames the hash parameter only for passwords the algorithm can not hash, any other error is a server error
+func hashError(err error) error {
+	err = errors.Wrap(err, "Could not hash password")
+	if errors.Cause(err) == passhash.ErrTooLong {
+		return invalidParam(paramHash, err)
+	}
+	return err
+}
+
+// isListFormat reports whether the format renders any number of passwords as a list
+func isListFormat(format string) bool {
+	switch format {
+	case formatJSON, formatText, formatCSV, formatNDJSON, formatXML:
+		return true
+	}
+	return false
+}
+
+// detailed reports whether a spelling or hash is added to the passwords
+func (o output) detailed() bool {
+	return o.alphabet != nil || o.hasher != nil
+}
+
+// jsonValues returns bare passwords to stay backwards compatible unless details were requested
+func (o output) jsonValues(results []passwordResult) []interface{} {
+	values := make([]interface{}, len(results))
+	for i, r := range results {
+		values[i] = r.Password
+		if o.detailed() {
+			values[i] = r
+		}
+	}
+	return values
+}
+
+// columns names the fields of text and CSV responses
+func (o output) columns() []string {
+	columns := []string{"password"}
+	if o.alphabet != nil {
+		columns = append(columns, "spelling")
+	}
+	if o.hasher != nil {
+		columns = append(columns, "hash")
+	}
+	return columns
+}
+
+func (o output) fields(r passwordResult) []string {
+	fields := []string{r.Password}
+	if o.alphabet != nil {
+		fields = append(fields, r.Spelling)
+	}
+	if o.hasher != nil {
+		fields = append(fields, r.Hash)
+	}
+	return fields
+}
+
+// text renders one password per line, details are separated by tabs
+func (o output) text(results []passwordResult) []byte {
+	var b bytes.Buffer
+	for _, r := range results {
+		b.WriteString(strings.Join(o.fields(r), "\t"))
+		b.WriteByte('\n')
+	}
+	return b.Bytes()
+}
+
+// csv renders a header and one row per password
+func (o output) csv(results []passwordResult) ([]byte, error) {
+	var b bytes.Buffer
+	w := csv.NewWriter(&b)
+	if err := w.Write(o.columns()); err != nil {
+		return nil, errors.Wrap(err, "Error while writing csv")
+	}
+	for _, r := range results {
+		if err := w.Write(o.fields(r)); err != nil {
+			return nil, errors.Wrap(err, "Error while writing csv")
+		}
+	}
+	w.Flush()
+	return b.Bytes(), errors.Wrap(w.Error(), "Error while writing csv")
+}
+
+// ndjson renders every JSON value of the list on its own line
+func (o output) ndjson(results []passwordResult) ([]byte, error) {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	for _, v := range o.jsonValues(results) {
+		if err := enc.Encode(v); err != nil {
+			return nil, errors.Wrap(err, "Error while marshalling json")
+		}
+	}
+	return b.Bytes(), nil
+}
@@ -0,0 +1,410 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/domano/pwgen/internal/mock"
+	"github.com/domano/pwgen/internal/passhash"
+	"github.com/domano/pwgen/internal/password"
+	"github.com/domano/pwgen/internal/spelling"
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestOutputFromParams(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		query       string
+		expected    output
+		expectedErr bool
+	}{
+		{
+			desc:     "Defaults",
+			query:    "",
+			expected: output{format: formatJSON, image: imagePNG},
+		},
+		{
+			desc:     "QR code as SVG for a Wi-Fi network",
+			query:    "format=qr&image=svg&ssid=guest",
+			expected: output{format: formatQR, image: imageSVG, ssid: "guest"},
+		},
+		{
+			desc:        "Empty ssid",
+			query:       "format=qr&ssid=",
+			expectedErr: true,
+		},
+		{
+			desc:        "ssid with control characters",
+			query:       "format=qr&ssid=guest%0D",
+			expectedErr: true,
+		},
+		{
+			desc:        "ssid longer than 32 bytes",
+			query:       "format=qr&ssid=" + strings.Repeat("a", 33),
+			expectedErr: true,
+		},
+		{
+			desc:        "Unknown format",
+			query:       "format=gif",
+			expectedErr: true,
+		},
+		{
+			desc:        "Unknown image",
+			query:       "format=qr&image=gif",
+			expectedErr: true,
+		},
+		{
+			desc:        "Unknown spelling",
+			query:       "spelling=xx",
+			expectedErr: true,
+		},
+		{
+			desc:     "bcrypt hash with cost",
+			query:    "hash=bcrypt&cost=10",
+			expected: output{format: formatJSON, image: imagePNG, hasher: mustHasher(passhash.Bcrypt, 10)},
+		},
+		{
+			desc:        "Unknown hash",
+			query:       "hash=md5",
+			expectedErr: true,
+		},
+		{
+			desc:        "Invalid cost",
+			query:       "hash=bcrypt&cost=abc",
+			expectedErr: true,
+		},
+		{
+			desc:        "Cost out of range",
+			query:       "hash=bcrypt&cost=31",
+			expectedErr: true,
+		},
+		{
+			desc:     "htpasswd line",
+			query:    "hash=apr1&user=alice",
+			expected: output{format: formatJSON, image: imagePNG, hasher: mustHtpasswd("alice", passhash.APR1)},
+		},
+		{
+			desc:        "User without hash",
+			query:       "user=alice",
+			expectedErr: true,
+		},
+		{
+			desc:        "User with hash unsupported by htpasswd",
+			query:       "hash=argon2id&user=alice",
+			expectedErr: true,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// given
+			vals, _ := url.ParseQuery(tC.query)
+
+			// when
+			out, err := outputFromParams(vals, "")
+
+			// then
+			assert.Equal(t, tC.expectedErr, err != nil)
+			if !tC.expectedErr {
+				assert.Equal(t, tC.expected, out)
+			}
+		})
+	}
+}
+
+func TestOutput_Render_QR(t *testing.T) {
+	// given
+	out := output{format: formatQR, image: imagePNG}
+
+	// when
+	contentType, body, err := out.render(context.Background(), []string{"secret"})
+
+	// then we get a PNG image
+	assert.NoError(t, err)
+	assert.Equal(t, "image/png", contentType)
+	_, err = png.Decode(bytes.NewReader(body))
+	assert.NoError(t, err)
+}
+
+func TestOutput_Render_QR_SVG(t *testing.T) {
+	// given
+	out := output{format: formatQR, image: imageSVG, ssid: "guest"}
+
+	// when
+	contentType, body, err := out.render(context.Background(), []string{"secret12"})
+
+	// then we get an SVG image
+	assert.NoError(t, err)
+	assert.Equal(t, "image/svg+xml", contentType)
+	assert.True(t, strings.HasPrefix(string(body), "<svg"))
+}
+
+func TestOutput_Render_QR_Multiple_Passwords(t *testing.T) {
+	// given
+	out := output{format: formatQR, image: imagePNG}
+
+	// when
+	_, _, err := out.render(context.Background(), []string{"secret", "secret"})
+
+	// then
+	assert.True(t, isRenderInputError(err))
+}
+
+func TestOutput_Render_Hash(t *testing.T) {
+	// given
+	out := output{format: formatJSON, hasher: mustHasher(passhash.Bcrypt, 10)}
+
+	// when
+	contentType, body, err := out.render(context.Background(), []string{"secret"})
+
+	// then every password comes with its hash
+	assert.NoError(t, err)
+	assert.Equal(t, "application/json", contentType)
+	var results []passwordResult
+	assert.NoError(t, json.Unmarshal(body, &results))
+	assert.Equal(t, "secret", results[0].Password)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(results[0].Hash), []byte("secret")))
+}
+
+func TestOutput_Render_Hash_Too_Long_For_Bcrypt(t *testing.T) {
+	// given
+	out := output{format: formatJSON, hasher: mustHasher(passhash.Bcrypt, 10)}
+
+	// when
+	_, _, err := out.render(context.Background(), []string{strings.Repeat("a", 73)})
+
+	// then
+	assert.True(t, isRenderInputError(err))
+}
+
+// failingHasher fails like a hasher which can not read random salts
+type failingHasher struct{}
+
+func (failingHasher) Hash(string) (string, error) {
+	return "", errors.New("no randomness")
+}
+
+func TestOutput_Render_Hash_Failure(t *testing.T) {
+	// given
+	out := output{format: formatJSON, hasher: failingHasher{}}
+
+	// when
+	_, _, err := out.render(context.Background(), []string{"secret"})
+
+	// then the server is blamed instead of the hash parameter
+	assert.Error(t, err)
+	assert.False(t, isRenderInputError(err))
+	assert.Empty(t, paramOf(err))
+}
+
+func TestOutput_Render_Hash_Cancelled(t *testing.T) {
+	// given
+	out := output{format: formatJSON, hasher: mustHasher(passhash.Bcrypt, 10)}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// when
+	_, _, err := out.render(ctx, []string{"secret"})
+
+	// then
+	assert.Equal(t, context.Canceled, errors.Cause(err))
+}
+
+func TestOutput_Render_Lists(t *testing.T) {
+	testCases := []struct {
+		desc                string
+		out                 output
+		expectedContentType string
+		expectedBody        string
+	}{
+		{
+			desc:                "JSON",
+			out:                 output{format: formatJSON},
+			expectedContentType: "application/json",
+			expectedBody:        `["a,b","c\"d"]`,
+		},
+		{
+			desc:                "Plain text",
+			out:                 output{format: formatText},
+			expectedContentType: "text/plain; charset=utf-8",
+			expectedBody:        "a,b\nc\"d\n",
+		},
+		{
+			desc:                "Plain text with spelling",
+			out:                 output{format: formatText, alphabet: &spelling.English},
+			expectedContentType: "text/plain; charset=utf-8",
+			expectedBody:        "a,b\tlowercase alfa, Comma, lowercase bravo\nc\"d\tlowercase charlie, Double quote, lowercase delta\n",
+		},
+		{
+			desc:                "CSV",
+			out:                 output{format: formatCSV},
+			expectedContentType: "text/csv; charset=utf-8",
+			expectedBody:        "password\n\"a,b\"\n\"c\"\"d\"\n",
+		},
+		{
+			desc:                "CSV with spelling",
+			out:                 output{format: formatCSV, alphabet: &spelling.English},
+			expectedContentType: "text/csv; charset=utf-8",
+			expectedBody:        "password,spelling\n\"a,b\",\"lowercase alfa, Comma, lowercase bravo\"\n\"c\"\"d\",\"lowercase charlie, Double quote, lowercase delta\"\n",
+		},
+		{
+			desc:                "NDJSON",
+			out:                 output{format: formatNDJSON},
+			expectedContentType: "application/x-ndjson",
+			expectedBody:        "\"a,b\"\n\"c\\\"d\"\n",
+		},
+		{
+			desc:                "XML",
+			out:                 output{format: formatXML},
+			expectedContentType: "application/xml; charset=utf-8",
+			expectedBody:        xml.Header + `<passwords><password>a,b</password><password>c&#34;d</password></passwords>`,
+		},
+		{
+			desc:                "XML with spelling",
+			out:                 output{format: formatXML, alphabet: &spelling.English},
+			expectedContentType: "application/xml; charset=utf-8",
+			expectedBody:        xml.Header + `<passwords><password spelling="lowercase alfa, Comma, lowercase bravo">a,b</password><password spelling="lowercase charlie, Double quote, lowercase delta">c&#34;d</password></passwords>`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// when
+			contentType, body, err := tC.out.render(context.Background(), []string{"a,b", `c"d`})
+
+			// then
+			assert.NoError(t, err)
+			assert.Equal(t, tC.expectedContentType, contentType)
+			assert.Equal(t, tC.expectedBody, string(body))
+		})
+	}
+}
+
+func TestPasswordHandler_ServeHTTP_Accept(t *testing.T) {
+	testCases := []struct {
+		desc                string
+		query               string
+		accept              string
+		expectedResponse    int
+		expectedContentType string
+	}{
+		{desc: "Negotiated plain text", accept: "text/plain", expectedResponse: http.StatusOK, expectedContentType: "text/plain; charset=utf-8"},
+		{desc: "Format overrides Accept", query: "format=csv", accept: "text/plain", expectedResponse: http.StatusOK, expectedContentType: "text/csv; charset=utf-8"},
+		{desc: "Unsupported media type", accept: "text/html", expectedResponse: http.StatusNotAcceptable, expectedContentType: "application/problem+json"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// given a handler with a mocked password generator
+			mockPassworder := mock.NewMockPassworder(gomock.NewController(t))
+			mockPassworder.EXPECT().Passwords(gomock.Any(), gomock.Any(), gomock.Any()).Return([]string{"secret"}, nil).AnyTimes()
+			ph := &PasswordHandler{Passworder: mockPassworder}
+			rc := httptest.NewRecorder()
+
+			// and a request with an Accept header
+			req, _ := http.NewRequest(http.MethodGet, "?"+tC.query, nil)
+			req.Header.Set("Accept", tC.accept)
+
+			// when
+			ph.ServeHTTP(rc, req)
+
+			// then
+			assert.Equal(t, tC.expectedResponse, rc.Code)
+			assert.Equal(t, tC.expectedContentType, rc.Header().Get("Content-Type"))
+			assert.Equal(t, "Accept", rc.Header().Get("Vary"))
+		})
+	}
+}
+
+func mustHasher(algorithm string, cost int) passhash.Hasher {
+	h, err := passhash.New(algorithm, cost)
+	if err != nil {
+		panic(err)
+	}
+	return h
+}
+
+func mustHtpasswd(user, algorithm string) passhash.Hasher {
+	h, err := passhash.NewHtpasswd(user, algorithm, 0)
+	if err != nil {
+		panic(err)
+	}
+	return h
+}
+
+func TestPasswordHandler_ServeHTTP_WiFi(t *testing.T) {
+	testCases := []struct {
+		desc              string
+		query             string
+		expectedMinLength int
+		returnedPasswords []string
+		expectedResponse  int
+		expectedType      string
+	}{
+		{
+			desc:              "Wi-Fi QR code raises the minimum length",
+			query:             "format=qr&ssid=guest",
+			expectedMinLength: 8,
+			returnedPasswords: []string{"abcdefgh"},
+			expectedResponse:  http.StatusOK,
+			expectedType:      "image/png",
+		},
+		{
+			desc:              "Wi-Fi profile keeps longer minimum length",
+			query:             "profile=wifi&minLength=20",
+			expectedMinLength: 20,
+			returnedPasswords: []string{strings.Repeat("a", 20)},
+			expectedResponse:  http.StatusOK,
+			expectedType:      "application/json",
+		},
+		{
+			desc:              "Wi-Fi profile rejects too long passwords",
+			query:             "profile=wifi&minLength=64",
+			expectedMinLength: 64,
+			returnedPasswords: []string{strings.Repeat("a", 64)},
+			expectedResponse:  http.StatusBadRequest,
+		},
+		{
+			desc:              "QR code for more than one password",
+			query:             "format=qr&amount=2",
+			expectedMinLength: 0,
+			returnedPasswords: []string{"a", "b"},
+			expectedResponse:  http.StatusBadRequest,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// given a mocked password generator
+			mockPassworder := mock.NewMockPassworder(gomock.NewController(t))
+
+			// and our handler
+			ph := &PasswordHandler{Passworder: mockPassworder}
+
+			// and a recorder for our response
+			rc := httptest.NewRecorder()
+
+			// and a test request
+			req, _ := http.NewRequest(http.MethodGet, "?"+tC.query, nil)
+
+			// expect calls to the password generator with the profiles' minimum length
+			mockPassworder.EXPECT().Passwords(gomock.Any(), gomock.Any(), password.Policy{MinLength: tC.expectedMinLength}).Return(tC.returnedPasswords, nil).Times(1)
+
+			// when
+			ph.ServeHTTP(rc, req)
+
+			// then
+			assert.Equal(t, tC.expectedResponse, rc.Code)
+			if tC.expectedType != "" {
+				assert.Equal(t, tC.expectedType, rc.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"regexp"
+
+	"github.com/domano/pwgen/internal/password"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// PolicySchemaPath is the route of the JSON Schema for policies posted to the PasswordHandler
+const PolicySchemaPath = "/schemas/password-policy.json"
+
+// maxPolicySize limits the size of posted policies in bytes
+const maxPolicySize = 64 * 1024
+
+// Errors of posted policies which are not caused by their fields
+var (
+	errPolicyTooLarge        = errors.New("policy too large")
+	errUnsupportedPolicyType = errors.New("policy must be sent as application/json")
+)
+
+// printableASCII matches character sets which only hold printable ASCII characters
+const printableASCII = `^[ -~]*$`
+
+// Separators are a few printable ASCII characters which need no quoting in any format,
+// spaces, quotes, commas and backslashes are not allowed
+const (
+	maxSeparatorLength = 4
+	separatorChars     = `^[!#-&(-+\--\[\]-~]*$`
+)
+
+var separatorPattern = regexp.MustCompile(separatorChars)
+
+// policySchema describes the JSON document accepted by POST requests for passwords
+var policySchema = &jsonSchema{
+	Schema:               "https://json-schema.org/draft/2020-12/schema",
+	ID:                   PolicySchemaPath,
+	Title:                "Password policy",
+	Description:          "Policy for the passwords generated by POST /passwords. Output options like format, spelling and hash are still read from the query parameters.",
+	Type:                 "object",
+	AdditionalProperties: boolPtr(false),
+	Properties: map[string]*jsonSchema{
+		"amount":          {Type: "integer", Minimum: int64Ptr(1), Description: "Number of passwords, 1 or the number of keys by default."},
+		"minLength":       {Type: "integer", Minimum: int64Ptr(0), Description: "Minimum length of a password."},
+		"maxLength":       {Type: "integer", Minimum: int64Ptr(0), Description: "Maximum length of a password, lengths are chosen randomly from minLength to maxLength."},
+		"specialChars":    {Type: "integer", Minimum: int64Ptr(0), Description: "Exact amount of special characters."},
+		"numbers":         {Type: "integer", Minimum: int64Ptr(0), Description: "Exact amount of numbers."},
+		"swap":            {Type: "boolean", Description: "Swap random vowels for numbers."},
+		"specialCharSet":  {Type: "string", Pattern: printableASCII, Description: "Special characters to choose from instead of all printable ASCII special characters."},
+		"exclude":         {Type: "string", Pattern: printableASCII, Description: "Characters which must not appear in passwords."},
+		"profile":         {Type: "string", Enum: password.ProfileNames(), Description: "Rules of a password consumer."},
+		"group":           {Type: "integer", Minimum: int64Ptr(0), Description: "Split passwords into chunks of this many characters."},
+		"separator":       {Type: "string", MaxLength: intPtr(maxSeparatorLength), Pattern: separatorChars, Description: "Separator between chunks of up to 4 printable ASCII characters without spaces, quotes, commas or backslashes, - by default."},
+		"countSeparators": {Type: "boolean", Description: "Count separators towards minLength."},
+	},
+}
+
+// policyDocument is a posted policy after it was validated against the schema
+type policyDocument struct {
+	Amount          int     `json:"amount"`
+	MinLength       int     `json:"minLength"`
+	MaxLength       int     `json:"maxLength"`
+	SpecialChars    int     `json:"specialChars"`
+	Numbers         int     `json:"numbers"`
+	Swap            bool    `json:"swap"`
+	SpecialCharSet  string  `json:"specialCharSet"`
+	Exclude         string  `json:"exclude"`
+	Profile         string  `json:"profile"`
+	Group           int     `json:"group"`
+	Separator       *string `json:"separator"`
+	CountSeparators bool    `json:"countSeparators"`
+}
+
+// requestFromBody reads the policy document and reports all invalid fields as a validationError
+func requestFromBody(r *http.Request) (passwordRequest, error) {
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || mediaType != "application/json" {
+			return passwordRequest{}, errors.Wrapf(errUnsupportedPolicyType, "got %s", contentType)
+		}
+	}
+	var body []byte
+	if r.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(io.LimitReader(r.Body, maxPolicySize+1))
+		if err != nil {
+			return passwordRequest{}, errors.Wrap(err, "Could not read policy")
+		}
+	}
+	if len(body) > maxPolicySize {
+		return passwordRequest{}, errors.Wrapf(errPolicyTooLarge, "policy exceeds %d bytes", maxPolicySize)
+	}
+
+	var document interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&document); err != nil {
+		return passwordRequest{}, validationError{[]fieldError{{Message: "must be valid JSON: " + err.Error()}}}
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return passwordRequest{}, validationError{[]fieldError{{Message: "must be a single JSON document"}}}
+	}
+	if errs := policySchema.validate(document, ""); len(errs) > 0 {
+		return passwordRequest{}, validationError{errs}
+	}
+	var doc policyDocument
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return passwordRequest{}, errors.Wrap(err, "Could not decode validated policy")
+	}
+
+	req := passwordRequest{
+		amount: doc.Amount,
+		policy: password.Policy{
+			MinLength:      doc.MinLength,
+			MaxLength:      doc.MaxLength,
+			SpecialChars:   doc.SpecialChars,
+			Numbers:        doc.Numbers,
+			Swap:           doc.Swap,
+			SpecialCharSet: doc.SpecialCharSet,
+			Exclude:        doc.Exclude,
+		},
+		group:           doc.Group,
+		separator:       password.DefaultSeparator,
+		countSeparators: doc.CountSeparators,
+	}
+	if doc.Separator != nil {
+		req.separator = *doc.Separator
+	}
+	if doc.Profile != "" {
+		profile, _ := password.LookupProfile(doc.Profile)
+		req.profile = &profile
+	}
+	if err := req.policy.Validate(); err != nil {
+		fe, ok := errors.Cause(err).(password.FieldError)
+		if !ok {
+			return passwordRequest{}, errors.Wrap(err, "Policy is invalid")
+		}
+		return passwordRequest{}, validationError{[]fieldError{{Field: fe.Field, Message: fe.Message}}}
+	}
+	return req, nil
+}
+
+// limitedPolicySchema returns a copy of the policySchema with the maximums of the limits
+func limitedPolicySchema(limits Limits) *jsonSchema {
+	l := limits.withDefaults()
+	schema := *policySchema
+	schema.Properties = map[string]*jsonSchema{}
+	for name, property := range policySchema.Properties {
+		schema.Properties[name] = property
+	}
+	for name, maximum := range map[string]int{"amount": l.MaxAmount, "minLength": l.MaxLength, "maxLength": l.MaxLength} {
+		property := *policySchema.Properties[name]
+		property.Maximum = int64Ptr(int64(maximum))
+		schema.Properties[name] = &property
+	}
+	return &schema
+}
+
+// PolicySchemaHandler publishes the JSON Schema of policies with the limits of the password handlers
+type PolicySchemaHandler struct {
+	Limits Limits
+}
+
+// NewPolicySchemaHandler constructs a new PolicySchemaHandler
+func NewPolicySchemaHandler(limits Limits) *PolicySchemaHandler {
+	return &PolicySchemaHandler{Limits: limits}
+}
+
+func (sh *PolicySchemaHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		methodNotAllowed(w, r, http.MethodGet, http.MethodHead)
+		return
+	}
+	body, err := json.MarshalIndent(limitedPolicySchema(sh.Limits), "", "  ")
+	if err != nil {
+		writeProblem(w, r, http.StatusInternalServerError, err)
+		log.WithError(err).Errorln("Error while marshalling json")
+		return
+	}
+	writeBody(w, r, "application/schema+json", body)
+}
@@ -0,0 +1,216 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/domano/pwgen/internal/mock"
+	"github.com/domano/pwgen/internal/password"
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestFromBody(t *testing.T) {
+	db := password.Database
+	testCases := []struct {
+		desc           string
+		contentType    string
+		body           string
+		expected       passwordRequest
+		expectedFields []fieldError
+		expectedCause  error
+	}{
+		{
+			desc: "Empty policy",
+			body: `{}`,
+			expected: passwordRequest{
+				separator: password.DefaultSeparator,
+			},
+		},
+		{
+			desc:        "Full policy",
+			contentType: "application/json; charset=utf-8",
+			body: `{"amount": 2, "minLength": 12, "maxLength": 16, "specialChars": 2, "numbers": 2, "swap": true,
+				"specialCharSet": "!?", "exclude": "0O", "profile": "db", "group": 4, "separator": "_", "countSeparators": true}`,
+			expected: passwordRequest{
+				amount:          2,
+				policy:          password.Policy{MinLength: 12, MaxLength: 16, SpecialChars: 2, Numbers: 2, Swap: true, SpecialCharSet: "!?", Exclude: "0O"},
+				group:           4,
+				separator:       "_",
+				countSeparators: true,
+				profile:         &db,
+			},
+		},
+		{
+			desc:           "Invalid fields",
+			body:           `{"amount": 0, "minLength": -1, "profile": "ldap", "swap": "yes", "length": 5}`,
+			expectedFields: []fieldError{{"amount", "must be at least 1"}, {"length", "is not a known field"}, {"minLength", "must be at least 0"}, {"profile", "must be one of [db wifi]"}, {"swap", "must be a boolean"}},
+		},
+		{
+			desc:           "Contradicting fields",
+			body:           `{"minLength": 16, "maxLength": 8}`,
+			expectedFields: []fieldError{{"maxLength", "must not be less than minLength"}},
+		},
+		{
+			desc:           "Non ASCII characters",
+			body:           `{"exclude": "€"}`,
+			expectedFields: []fieldError{{"exclude", "must match ^[ -~]*$"}},
+		},
+		{
+			desc:           "Separator with line break",
+			body:           `{"separator": "\n"}`,
+			expectedFields: []fieldError{{"separator", "must match " + separatorChars}},
+		},
+		{
+			desc:           "Long separator",
+			body:           `{"separator": "-----"}`,
+			expectedFields: []fieldError{{"separator", "must be at most 4 characters long"}},
+		},
+		{
+			desc:           "Invalid JSON",
+			body:           `{"minLength": 16`,
+			expectedFields: []fieldError{{"", "must be valid JSON: unexpected EOF"}},
+		},
+		{
+			desc:           "Multiple documents",
+			body:           `{} {}`,
+			expectedFields: []fieldError{{"", "must be a single JSON document"}},
+		},
+		{
+			desc:          "Form instead of JSON",
+			contentType:   "application/x-www-form-urlencoded",
+			body:          `minLength=16`,
+			expectedCause: errUnsupportedPolicyType,
+		},
+		{
+			desc:          "Too large",
+			body:          `{"exclude": "` + strings.Repeat("a", maxPolicySize) + `"}`,
+			expectedCause: errPolicyTooLarge,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// given
+			req := httptest.NewRequest(http.MethodPost, "/passwords", strings.NewReader(tC.body))
+			req.Header.Set("Content-Type", tC.contentType)
+
+			// when
+			result, err := requestFromBody(req)
+
+			// then
+			switch {
+			case tC.expectedFields != nil:
+				assert.Equal(t, validationError{tC.expectedFields}, err)
+			case tC.expectedCause != nil:
+				assert.Equal(t, tC.expectedCause, errors.Cause(err))
+			default:
+				assert.NoError(t, err)
+				assert.Equal(t, tC.expected, result)
+			}
+		})
+	}
+}
+
+func TestPasswordHandler_ServeHTTP_Post(t *testing.T) {
+	testCases := []struct {
+		desc             string
+		query            string
+		body             string
+		expectedPolicy   password.Policy
+		expectedAmount   int
+		expectedResponse int
+		expectedBody     string
+	}{
+		{
+			desc:             "Policy with a profile and query params for the output",
+			query:            "?format=text",
+			body:             `{"amount": 2, "minLength": 8, "profile": "db", "exclude": "0O"}`,
+			expectedPolicy:   password.Policy{MinLength: 8, Exclude: "0O" + password.Database.Exclude},
+			expectedAmount:   2,
+			expectedResponse: http.StatusOK,
+			expectedBody:     "secret\nsecret\n",
+		},
+		{
+			desc:             "Amount from the keys of a manifest",
+			query:            "?format=dotenv&keys=A,B",
+			body:             `{"minLength": 6}`,
+			expectedPolicy:   password.Policy{MinLength: 6},
+			expectedAmount:   2,
+			expectedResponse: http.StatusOK,
+			expectedBody:     "A='secret'\nB='secret'\n",
+		},
+		{
+			desc:             "Invalid field",
+			body:             `{"numbers": "2"}`,
+			expectedResponse: http.StatusBadRequest,
+			expectedBody: `{"type":"urn:pwgen:problem:invalid-policy","title":"Bad Request","status":400,"detail":"Policy has 1 invalid fields","instance":"/passwords",` +
+				`"errors":[{"field":"numbers","message":"must be an integer"}]}`,
+		},
+		{
+			desc:             "Too large",
+			body:             `{"exclude": "` + strings.Repeat(" ", maxPolicySize) + `"}`,
+			expectedResponse: http.StatusRequestEntityTooLarge,
+			expectedBody:     `{"type":"urn:pwgen:problem:request-too-large","title":"Request Entity Too Large","status":413,"detail":"policy exceeds 65536 bytes: policy too large","instance":"/passwords"}`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// given a handler with a mocked password generator
+			mockPassworder := mock.NewMockPassworder(gomock.NewController(t))
+			ph := &PasswordHandler{Passworder: mockPassworder}
+			rc := httptest.NewRecorder()
+
+			// and a posted policy
+			req := httptest.NewRequest(http.MethodPost, "/passwords"+tC.query, strings.NewReader(tC.body))
+			req.Header.Set("Content-Type", "application/json")
+
+			// expect the policy to reach the generator
+			passwords := make([]string, tC.expectedAmount)
+			for i := range passwords {
+				passwords[i] = "secret"
+			}
+			mockPassworder.EXPECT().Passwords(gomock.Any(), tC.expectedAmount, tC.expectedPolicy).Return(passwords, nil).AnyTimes()
+
+			// when
+			ph.ServeHTTP(rc, req)
+
+			// then
+			assert.Equal(t, tC.expectedResponse, rc.Code)
+			assert.Equal(t, tC.expectedBody, rc.Body.String())
+		})
+	}
+}
+
+func TestPolicySchemaHandler_ServeHTTP(t *testing.T) {
+	// given
+	rc := httptest.NewRecorder()
+
+	// when
+	NewPolicySchemaHandler(Limits{MaxAmount: 50}).ServeHTTP(rc, httptest.NewRequest(http.MethodGet, PolicySchemaPath, nil))
+
+	// then the schema lists every field of the policy document
+	assert.Equal(t, http.StatusOK, rc.Code)
+	assert.Equal(t, "application/schema+json", rc.Header().Get("Content-Type"))
+	var schema jsonSchema
+	assert.NoError(t, json.Unmarshal(rc.Body.Bytes(), &schema))
+	var fields []string
+	for field := range schema.Properties {
+		fields = append(fields, field)
+	}
+	var documentFields []string
+	documentType := reflect.TypeOf(policyDocument{})
+	for i := 0; i < documentType.NumField(); i++ {
+		documentFields = append(documentFields, documentType.Field(i).Tag.Get("json"))
+	}
+	assert.ElementsMatch(t, documentFields, fields)
+
+	// and publishes the limits without changing the schema used for validation
+	assert.Equal(t, int64(50), *schema.Properties["amount"].Maximum)
+	assert.Equal(t, int64(1024), *schema.Properties["maxLength"].Maximum)
+	assert.Nil(t, policySchema.Properties["amount"].Maximum)
+}
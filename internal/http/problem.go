@@ -0,0 +1,171 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// problemTypeBase prefixes the stable types of problems, clients should match the full type instead of the title
+const problemTypeBase = "urn:pwgen:problem:"
+
+// problemInvalidPolicy is the type of problems listing the invalid fields of a posted policy
+const problemInvalidPolicy = problemTypeBase + "invalid-policy"
+
+// problemTypes maps status codes to the type of their problems
+var problemTypes = map[int]string{
+	http.StatusBadRequest:            problemTypeBase + "invalid-parameter",
+	http.StatusUnauthorized:          problemTypeBase + "unauthorized",
+	http.StatusForbidden:             problemTypeBase + "forbidden",
+	http.StatusNotFound:              problemTypeBase + "not-found",
+	http.StatusMethodNotAllowed:      problemTypeBase + "method-not-allowed",
+	http.StatusNotAcceptable:         problemTypeBase + "not-acceptable",
+	http.StatusRequestEntityTooLarge: problemTypeBase + "request-too-large",
+	http.StatusUnsupportedMediaType:  problemTypeBase + "unsupported-media-type",
+	http.StatusInternalServerError:   problemTypeBase + "internal-error",
+	http.StatusServiceUnavailable:    problemTypeBase + "unavailable",
+}
+
+// problem describes why a request failed as defined by RFC 7807
+type problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// Param names the query parameter which caused the problem
+	Param string `json:"param,omitempty"`
+	// Errors lists the invalid fields of a posted policy
+	Errors []fieldError `json:"errors,omitempty"`
+}
+
+// paramError attributes an error to the query parameter which caused it
+type paramError struct {
+	param string
+	error
+}
+
+// Cause allows errors.Cause to find the error behind the parameter
+func (e paramError) Cause() error {
+	return e.error
+}
+
+// invalidParam attributes the error to a query parameter, nil errors stay nil
+func invalidParam(param string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return paramError{param, err}
+}
+
+// paramOf returns the query parameter an error was attributed to
+func paramOf(err error) string {
+	for err != nil {
+		if pe, ok := err.(paramError); ok {
+			return pe.param
+		}
+		causer, ok := err.(interface{ Cause() error })
+		if !ok {
+			return ""
+		}
+		err = causer.Cause()
+	}
+	return ""
+}
+
+// newProblem describes the error of the request, details of server errors are only logged
+func newProblem(r *http.Request, status int, err error) problem {
+	p := problem{Type: "about:blank", Title: http.StatusText(status), Status: status, Instance: r.URL.Path}
+	if t, ok := problemTypes[status]; ok {
+		p.Type = t
+	}
+	if err == nil || status >= http.StatusInternalServerError {
+		return p
+	}
+	p.Detail = err.Error()
+	p.Param = paramOf(err)
+	if ve, ok := errors.Cause(err).(validationError); ok {
+		p.Type = problemInvalidPolicy
+		p.Detail = fmt.Sprintf("Policy has %d invalid fields", len(ve.Errors))
+		p.Errors = ve.Errors
+	}
+	return p
+}
+
+// writeProblem answers with an application/problem+json body describing the error
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, err error) {
+	body, marshalErr := json.Marshal(newProblem(r, status, err))
+	if marshalErr != nil {
+		w.WriteHeader(status)
+		log.WithError(marshalErr).Errorln("Error while marshalling json")
+		return
+	}
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(status)
+	if r.Method == http.MethodHead {
+		return
+	}
+	if _, err := w.Write(body); err != nil {
+		log.WithError(err).Errorln("Error while writing body")
+	}
+}
+
+// methodNotAllowed answers requests with unsupported methods and lists the allowed ones
+func methodNotAllowed(w http.ResponseWriter, r *http.Request, allowed ...string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	writeProblem(w, r, http.StatusMethodNotAllowed, errors.Errorf("Method %s is not allowed, use %s", r.Method, strings.Join(allowed, ", ")))
+}
+
+// NotFoundHandler answers requests for unknown routes with a problem
+func NotFoundHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeProblem(w, r, http.StatusNotFound, errors.Errorf("No route for %s", r.URL.Path))
+	})
+}
+
+// ProblemHandlerFunc wraps a given http.Handler with a middleware which adds
+// problem bodies to error responses without a body, like the ones of the recovery handler
+func ProblemHandlerFunc(next http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if next != nil {
+			next.ServeHTTP(&problemWriter{ResponseWriter: w, r: r}, r)
+		}
+	}
+}
+
+type problemWriter struct {
+	http.ResponseWriter
+	r           *http.Request
+	wroteHeader bool
+	replaced    bool
+}
+
+func (w *problemWriter) WriteHeader(statusCode int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	if statusCode >= http.StatusBadRequest && w.Header().Get("Content-Type") == "" {
+		w.replaced = true
+		writeProblem(w.ResponseWriter, w.r, statusCode, nil)
+		return
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write drops the body of replaced responses
+func (w *problemWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.replaced {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
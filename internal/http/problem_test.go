@@ -0,0 +1,180 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/domano/pwgen/internal/passhash"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewProblem(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		status        int
+		err           error
+		expectedType  string
+		expectedParam string
+		detailed      bool
+	}{
+		{
+			desc:          "Wrapped parameter error",
+			status:        http.StatusBadRequest,
+			err:           errors.Wrap(invalidParam(paramAmount, errors.New("no number")), "Could not read amount"),
+			expectedType:  "urn:pwgen:problem:invalid-parameter",
+			expectedParam: paramAmount,
+			detailed:      true,
+		},
+		{
+			desc:         "Validation error",
+			status:       http.StatusBadRequest,
+			err:          validationError{[]fieldError{{"swap", "must be a boolean"}}},
+			expectedType: "urn:pwgen:problem:invalid-policy",
+			detailed:     true,
+		},
+		{
+			desc:         "Server errors are not detailed",
+			status:       http.StatusInternalServerError,
+			err:          errors.New("secret internals"),
+			expectedType: "urn:pwgen:problem:internal-error",
+		},
+		{
+			desc:         "Unknown status",
+			status:       http.StatusTeapot,
+			expectedType: "about:blank",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// when
+			p := newProblem(httptest.NewRequest(http.MethodGet, "/passwords?amount=x", nil), tC.status, tC.err)
+
+			// then
+			assert.Equal(t, tC.expectedType, p.Type)
+			assert.Equal(t, tC.status, p.Status)
+			assert.Equal(t, http.StatusText(tC.status), p.Title)
+			assert.Equal(t, "/passwords", p.Instance)
+			assert.Equal(t, tC.expectedParam, p.Param)
+			assert.Equal(t, tC.detailed, p.Detail != "")
+		})
+	}
+}
+
+func TestInvalidParam_Keeps_Cause(t *testing.T) {
+	// given
+	err := errors.Wrap(invalidParam(paramHash, errors.Wrap(passhash.ErrTooLong, "too long")), "Could not hash")
+
+	// then sentinel errors can still be found
+	assert.Equal(t, passhash.ErrTooLong, errors.Cause(err))
+	assert.Equal(t, paramHash, paramOf(err))
+	assert.Nil(t, invalidParam(paramHash, nil))
+}
+
+func TestParams_Are_Attributed(t *testing.T) {
+	testCases := []struct {
+		query         string
+		expectedParam string
+	}{
+		{query: "format=gif", expectedParam: paramFormat},
+		{query: "image=gif", expectedParam: paramImage},
+		{query: "spelling=xx", expectedParam: paramSpelling},
+		{query: "hash=md5", expectedParam: paramHash},
+		{query: "hash=bcrypt&cost=99", expectedParam: paramCost},
+		{query: "hash=apr1&cost=10", expectedParam: paramCost},
+		{query: "hash=apr1&cost=99&user=alice", expectedParam: paramCost},
+		{query: "hash=argon2id&user=alice", expectedParam: paramHash},
+		{query: "hash=bcrypt&user=a:b", expectedParam: paramUser},
+		{query: "user=alice", expectedParam: paramUser},
+		{query: "format=dotenv", expectedParam: paramKeys},
+		{query: "format=kubernetes&keys=a&name=A", expectedParam: paramName},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.query, func(t *testing.T) {
+			// given
+			vals, _ := url.ParseQuery(tC.query)
+
+			// when
+			_, err := outputFromParams(vals, "")
+
+			// then
+			assert.Error(t, err)
+			assert.Equal(t, tC.expectedParam, paramOf(err))
+		})
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	// given
+	rc := httptest.NewRecorder()
+
+	// when
+	NewOTPHandler().ServeHTTP(rc, httptest.NewRequest(http.MethodDelete, "/otp", nil))
+
+	// then the allowed methods are listed
+	assert.Equal(t, http.StatusMethodNotAllowed, rc.Code)
+	assert.Equal(t, "GET, HEAD", rc.Header().Get("Allow"))
+	assert.Equal(t, "application/problem+json", rc.Header().Get("Content-Type"))
+	assert.Contains(t, rc.Body.String(), `"type":"urn:pwgen:problem:method-not-allowed"`)
+}
+
+func TestProblemHandlerFunc(t *testing.T) {
+	testCases := []struct {
+		desc                string
+		next                http.HandlerFunc
+		expectedStatus      int
+		expectedContentType string
+		expectedBody        string
+	}{
+		{
+			desc:                "Error without body",
+			next:                func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusNotFound) },
+			expectedStatus:      http.StatusNotFound,
+			expectedContentType: "application/problem+json",
+			expectedBody:        `{"type":"urn:pwgen:problem:not-found","title":"Not Found","status":404,"instance":"/passwords"}`,
+		},
+		{
+			desc: "Plain text body of an error is replaced",
+			next: func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+				_, _ = w.Write([]byte("oops"))
+			},
+			expectedStatus:      http.StatusInternalServerError,
+			expectedContentType: "application/problem+json",
+			expectedBody:        `{"type":"urn:pwgen:problem:internal-error","title":"Internal Server Error","status":500,"instance":"/passwords"}`,
+		},
+		{
+			desc:                "Problems are kept",
+			next:                func(w http.ResponseWriter, r *http.Request) { writeProblem(w, r, http.StatusBadRequest, nil) },
+			expectedStatus:      http.StatusBadRequest,
+			expectedContentType: "application/problem+json",
+			expectedBody:        `{"type":"urn:pwgen:problem:invalid-parameter","title":"Bad Request","status":400,"instance":"/passwords"}`,
+		},
+		{
+			desc:           "Success",
+			next:           func(w http.ResponseWriter, _ *http.Request) { _, _ = w.Write([]byte("[]")) },
+			expectedStatus: http.StatusOK,
+			expectedBody:   "[]",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// given
+			rc := httptest.NewRecorder()
+
+			// when
+			ProblemHandlerFunc(tC.next)(rc, httptest.NewRequest(http.MethodGet, "/passwords", nil))
+
+			// then
+			assert.Equal(t, tC.expectedStatus, rc.Code)
+			assert.Equal(t, tC.expectedBody, rc.Body.String())
+			if tC.expectedContentType != "" {
+				assert.Equal(t, tC.expectedContentType, rc.Header().Get("Content-Type"))
+				assert.True(t, json.Valid(rc.Body.Bytes()))
+			}
+		})
+	}
+}
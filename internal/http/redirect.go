@@ -0,0 +1,45 @@
+package http
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// HTTPSRedirectHandler redirects every plain HTTP request to the same URL on the HTTPS port
+type HTTPSRedirectHandler struct {
+	Port int
+}
+
+// NewHTTPSRedirectHandler constructs a new HTTPSRedirectHandler redirecting to the given port
+func NewHTTPSRedirectHandler(port int) *HTTPSRedirectHandler {
+	return &HTTPSRedirectHandler{port}
+}
+
+func (rh *HTTPSRedirectHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	host := strings.TrimSuffix(strings.TrimPrefix(r.Host, "["), "]")
+	if h, _, err := net.SplitHostPort(r.Host); err == nil {
+		host = h
+	}
+	if host == "" {
+		err := errors.New("Request has no Host header to redirect to")
+		writeProblem(w, r, http.StatusBadRequest, err)
+		log.WithError(err).Warnln("Received a bad request.")
+		return
+	}
+	// The default port of HTTPS is implied, IPv6 addresses still need their brackets
+	if rh.Port != 443 {
+		host = net.JoinHostPort(host, strconv.Itoa(rh.Port))
+	} else if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+
+	// A permanent redirect keeps the method and body of the request
+	target := url.URL{Scheme: "https", Host: host, Path: r.URL.Path, RawQuery: r.URL.RawQuery}
+	http.Redirect(w, r, target.String(), http.StatusPermanentRedirect)
+}
@@ -0,0 +1,43 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPSRedirectHandler_ServeHTTP(t *testing.T) {
+	testCases := []struct {
+		desc             string
+		port             int
+		host             string
+		target           string
+		expectedStatus   int
+		expectedLocation string
+	}{
+		{desc: "Host with port", port: 8443, host: "pwgen.local:8080", target: "/passwords?amount=2", expectedStatus: http.StatusPermanentRedirect, expectedLocation: "https://pwgen.local:8443/passwords?amount=2"},
+		{desc: "Host without port", port: 8443, host: "pwgen.local", target: "/otp", expectedStatus: http.StatusPermanentRedirect, expectedLocation: "https://pwgen.local:8443/otp"},
+		{desc: "Default port", port: 443, host: "pwgen.local:80", target: "/", expectedStatus: http.StatusPermanentRedirect, expectedLocation: "https://pwgen.local/"},
+		{desc: "IPv6 with default port", port: 443, host: "[::1]:80", target: "/", expectedStatus: http.StatusPermanentRedirect, expectedLocation: "https://[::1]/"},
+		{desc: "IPv6", port: 8443, host: "[::1]:80", target: "/", expectedStatus: http.StatusPermanentRedirect, expectedLocation: "https://[::1]:8443/"},
+		{desc: "IPv6 without port", port: 8443, host: "[::1]", target: "/", expectedStatus: http.StatusPermanentRedirect, expectedLocation: "https://[::1]:8443/"},
+		{desc: "No host", port: 8443, host: "", target: "/", expectedStatus: http.StatusBadRequest},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// given
+			rc := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, tC.target, nil)
+			req.Host = tC.host
+
+			// when
+			NewHTTPSRedirectHandler(tC.port).ServeHTTP(rc, req)
+
+			// then
+			assert.Equal(t, tC.expectedStatus, rc.Code)
+			assert.Equal(t, tC.expectedLocation, rc.Header().Get("Location"))
+		})
+	}
+}
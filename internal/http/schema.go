@@ -0,0 +1,141 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// jsonSchema is the subset of JSON Schema used to publish and validate request documents
+type jsonSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	ID                   string                 `json:"$id,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Ref                  string                 `json:"$ref,omitempty"`
+	Type                 string                 `json:"type,omitempty"`
+	Format               string                 `json:"format,omitempty"`
+	Properties           map[string]*jsonSchema `json:"properties,omitempty"`
+	AdditionalProperties *bool                  `json:"additionalProperties,omitempty"`
+	Items                *jsonSchema            `json:"items,omitempty"`
+	OneOf                []*jsonSchema          `json:"oneOf,omitempty"`
+	Minimum              *int64                 `json:"minimum,omitempty"`
+	Maximum              *int64                 `json:"maximum,omitempty"`
+	MaxLength            *int                   `json:"maxLength,omitempty"`
+	Pattern              string                 `json:"pattern,omitempty"`
+	Enum                 []string               `json:"enum,omitempty"`
+	Default              interface{}            `json:"default,omitempty"`
+}
+
+// fieldError describes why the value of a field is invalid
+type fieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// validationError collects the field errors of a document
+type validationError struct {
+	Errors []fieldError `json:"errors"`
+}
+
+func (e validationError) Error() string {
+	return fmt.Sprintf("%d invalid fields, first %s: %s", len(e.Errors), e.Errors[0].Field, e.Errors[0].Message)
+}
+
+func int64Ptr(i int64) *int64 {
+	return &i
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+// validate checks a value decoded with json.Decoder.UseNumber against the schema, references are not resolved
+func (s *jsonSchema) validate(value interface{}, field string) []fieldError {
+	invalid := func(format string, args ...interface{}) []fieldError {
+		return []fieldError{{Field: field, Message: fmt.Sprintf(format, args...)}}
+	}
+
+	switch s.Type {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return invalid("must be an object")
+		}
+		return s.validateProperties(object, field)
+	case "integer":
+		number, ok := value.(json.Number)
+		if !ok {
+			return invalid("must be an integer")
+		}
+		i, err := number.Int64()
+		if err != nil {
+			return invalid("must be an integer")
+		}
+		if s.Minimum != nil && i < *s.Minimum {
+			return invalid("must be at least %d", *s.Minimum)
+		}
+		if s.Maximum != nil && i > *s.Maximum {
+			return invalid("must be at most %d", *s.Maximum)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return invalid("must be a boolean")
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return invalid("must be a string")
+		}
+		if s.MaxLength != nil && len([]rune(str)) > *s.MaxLength {
+			return invalid("must be at most %d characters long", *s.MaxLength)
+		}
+		if s.Pattern != "" && !regexp.MustCompile(s.Pattern).MatchString(str) {
+			return invalid("must match %s", s.Pattern)
+		}
+		if len(s.Enum) > 0 && !contains(s.Enum, str) {
+			return invalid("must be one of %v", s.Enum)
+		}
+	}
+	return nil
+}
+
+// validateProperties validates the properties in alphabetical order to report errors in a stable order
+func (s *jsonSchema) validateProperties(object map[string]interface{}, field string) []fieldError {
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []fieldError
+	for _, name := range names {
+		path := name
+		if field != "" {
+			path = field + "." + name
+		}
+		property, ok := s.Properties[name]
+		if !ok {
+			if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				errs = append(errs, fieldError{Field: path, Message: "is not a known field"})
+			}
+			continue
+		}
+		errs = append(errs, property.validate(object[name], path)...)
+	}
+	return errs
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
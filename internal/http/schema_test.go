@@ -0,0 +1,61 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONSchema_Validate(t *testing.T) {
+	// given
+	schema := &jsonSchema{
+		Type:                 "object",
+		AdditionalProperties: boolPtr(false),
+		Properties: map[string]*jsonSchema{
+			"count": {Type: "integer", Minimum: int64Ptr(1), Maximum: int64Ptr(10)},
+			"flag":  {Type: "boolean"},
+			"name":  {Type: "string", MaxLength: intPtr(3), Pattern: `^[a-z]*$`},
+			"kind":  {Type: "string", Enum: []string{"a", "b"}},
+			"inner": {Type: "object", Properties: map[string]*jsonSchema{"n": {Type: "integer"}}},
+		},
+	}
+	testCases := []struct {
+		desc     string
+		document string
+		expected []fieldError
+	}{
+		{desc: "Valid document", document: `{"count": 5, "flag": true, "name": "abc", "kind": "a", "inner": {"n": 1}}`},
+		{desc: "Empty object", document: `{}`},
+		{desc: "No object", document: `[]`, expected: []fieldError{{"", "must be an object"}}},
+		{desc: "Fraction", document: `{"count": 1.5}`, expected: []fieldError{{"count", "must be an integer"}}},
+		{desc: "Below minimum", document: `{"count": 0}`, expected: []fieldError{{"count", "must be at least 1"}}},
+		{desc: "Above maximum", document: `{"count": 11}`, expected: []fieldError{{"count", "must be at most 10"}}},
+		{desc: "String instead of boolean", document: `{"flag": "true"}`, expected: []fieldError{{"flag", "must be a boolean"}}},
+		{desc: "Too long", document: `{"name": "abcd"}`, expected: []fieldError{{"name", "must be at most 3 characters long"}}},
+		{desc: "Pattern", document: `{"name": "A"}`, expected: []fieldError{{"name", "must match ^[a-z]*$"}}},
+		{desc: "Enum", document: `{"kind": "c"}`, expected: []fieldError{{"kind", "must be one of [a b]"}}},
+		{desc: "Nested field", document: `{"inner": {"n": "1"}}`, expected: []fieldError{{"inner.n", "must be an integer"}}},
+		{
+			desc:     "Every error in alphabetical order",
+			document: `{"unknown": 1, "count": null, "flag": 1}`,
+			expected: []fieldError{{"count", "must be an integer"}, {"flag", "must be a boolean"}, {"unknown", "is not a known field"}},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// given
+			var document interface{}
+			decoder := json.NewDecoder(bytes.NewReader([]byte(tC.document)))
+			decoder.UseNumber()
+			assert.NoError(t, decoder.Decode(&document))
+
+			// when
+			errs := schema.validate(document, "")
+
+			// then
+			assert.Equal(t, tC.expected, errs)
+		})
+	}
+}
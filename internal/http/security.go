@@ -0,0 +1,28 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// SecurityHeadersHandlerFunc wraps a given http.Handler with headers keeping passwords out of caches, sniffers and referrers.
+// Responses to HTTPS requests tell browsers to only use HTTPS for the given time, zero disables it.
+func SecurityHeadersHandlerFunc(next http.Handler, hstsMaxAge time.Duration, includeSubDomains bool) http.HandlerFunc {
+	hsts := fmt.Sprintf("max-age=%d", int64(hstsMaxAge.Seconds()))
+	if includeSubDomains {
+		hsts += "; includeSubDomains"
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Referrer-Policy", "no-referrer")
+		// Browsers ignore HSTS over plain HTTP, e.g. on unix sockets
+		if r.TLS != nil && hstsMaxAge > 0 {
+			w.Header().Set("Strict-Transport-Security", hsts)
+		}
+		if next != nil {
+			next.ServeHTTP(w, r)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package http
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecurityHeadersHandlerFunc(t *testing.T) {
+	testCases := []struct {
+		desc              string
+		tls               bool
+		maxAge            time.Duration
+		includeSubDomains bool
+		expectedHSTS      string
+	}{
+		{desc: "HTTPS", tls: true, maxAge: 365 * 24 * time.Hour, expectedHSTS: "max-age=31536000"},
+		{desc: "HTTPS with subdomains", tls: true, maxAge: time.Hour, includeSubDomains: true, expectedHSTS: "max-age=3600; includeSubDomains"},
+		{desc: "HSTS disabled", tls: true},
+		{desc: "Plain HTTP", maxAge: time.Hour},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// given a test handler to check if it was called as next
+			var called bool
+			next := http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {
+				called = true
+			})
+			rc := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/passwords", nil)
+			if tC.tls {
+				req.TLS = &tls.ConnectionState{}
+			}
+
+			// when
+			SecurityHeadersHandlerFunc(next, tC.maxAge, tC.includeSubDomains)(rc, req)
+
+			// then
+			assert.True(t, called)
+			assert.Equal(t, "no-store", rc.Header().Get("Cache-Control"))
+			assert.Equal(t, "nosniff", rc.Header().Get("X-Content-Type-Options"))
+			assert.Equal(t, "no-referrer", rc.Header().Get("Referrer-Policy"))
+			assert.Equal(t, tC.expectedHSTS, rc.Header().Get("Strict-Transport-Security"))
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/domano/pwgen/internal/password"
+	"github.com/domano/pwgen/internal/sshkey"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// SSHKeyHandler generates SSH key pairs and uses
+// the included Passworder for passphrases of encrypted keys
+type SSHKeyHandler struct {
+	Passworder
+}
+
+// Constants for the available query params
+const paramType = "type"
+const paramBits = "bits"
+const paramComment = "comment"
+const paramEncrypt = "encrypt"
+
+// Policy for generated passphrases of encrypted private keys
+const passphraseLength = 24
+const passphraseNumbers = 4
+
+// sshKeyResponse contains a key pair in OpenSSH formats
+type sshKeyResponse struct {
+	PublicKey   string `json:"publicKey"`
+	PrivateKey  string `json:"privateKey"`
+	Fingerprint string `json:"fingerprint"`
+	Passphrase  string `json:"passphrase,omitempty"`
+}
+
+// NewSSHKeyHandler constructs a new SSHKeyHandler using the given Passworder
+func NewSSHKeyHandler(p Passworder) *SSHKeyHandler {
+	return &SSHKeyHandler{p}
+}
+
+func (sh *SSHKeyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Keys are only generated, so nothing except GET is supported
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		methodNotAllowed(w, r, http.MethodGet, http.MethodHead)
+		return
+	}
+
+	req, err := sh.request(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, err)
+		log.WithError(err).Warnln("Received a bad request.")
+		return
+	}
+	resp, err := sh.keyPair(r.Context(), req)
+	if err != nil {
+		writeProblem(w, r, http.StatusInternalServerError, err)
+		log.WithError(err).Errorln("Error while generating key pair")
+		return
+	}
+
+	body, err := json.Marshal(resp)
+	if err != nil {
+		writeProblem(w, r, http.StatusInternalServerError, err)
+		log.WithError(err).Errorln("Error while marshalling json")
+		return
+	}
+	writeBody(w, r, "application/json", body)
+}
+
+// sshKeyRequest holds the validated params of a request for a key pair
+type sshKeyRequest struct {
+	keyType sshkey.Type
+	bits    int
+	comment string
+	encrypt bool
+}
+
+// request reads the params of a key pair, they are validated before anything is generated
+func (sh *SSHKeyHandler) request(r *http.Request) (sshKeyRequest, error) {
+	// Get parameters from URL & validate them
+	params := r.URL.Query()
+
+	req := sshKeyRequest{keyType: sshkey.Ed25519, comment: params.Get(paramComment)}
+	if val := params.Get(paramType); val != "" {
+		req.keyType = sshkey.Type(val)
+	}
+	if err := sshkey.ValidType(req.keyType); err != nil {
+		return sshKeyRequest{}, invalidParam(paramType, err)
+	}
+	bits, err := numberFromParams(params, paramBits)
+	if err != nil {
+		return sshKeyRequest{}, errors.Wrap(err, "Could not read bits parameter")
+	}
+	if err := sshkey.ValidBits(req.keyType, bits); err != nil {
+		return sshKeyRequest{}, invalidParam(paramBits, err)
+	}
+	req.bits = bits
+	if err := sshkey.ValidComment(req.comment); err != nil {
+		return sshKeyRequest{}, invalidParam(paramComment, err)
+	}
+	if req.encrypt, err = boolFromParams(params, paramEncrypt); err != nil {
+		return sshKeyRequest{}, errors.Wrap(err, "Could not read encrypt parameter")
+	}
+	return req, nil
+}
+
+// keyPair generates the key pair and the passphrase of encrypted keys, all errors are failures of the server
+func (sh *SSHKeyHandler) keyPair(ctx context.Context, req sshKeyRequest) (sshKeyResponse, error) {
+	var passphrase string
+	if req.encrypt {
+		passphrases, err := sh.Passwords(ctx, 1, password.Policy{MinLength: passphraseLength, Numbers: passphraseNumbers})
+		if err != nil {
+			return sshKeyResponse{}, errors.Wrap(err, "Could not generate passphrase")
+		}
+		passphrase = passphrases[0]
+	}
+	kp, err := sshkey.Generate(req.keyType, req.bits, req.comment, passphrase)
+	if err != nil {
+		return sshKeyResponse{}, errors.Wrap(err, "Could not generate key pair")
+	}
+	return sshKeyResponse{
+		PublicKey:   kp.PublicKey,
+		PrivateKey:  string(kp.PrivateKey),
+		Fingerprint: kp.Fingerprint,
+		Passphrase:  passphrase,
+	}, nil
+}
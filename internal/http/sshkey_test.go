@@ -0,0 +1,148 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/domano/pwgen/internal/mock"
+	"github.com/domano/pwgen/internal/password"
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSSHKeyHandler(t *testing.T) {
+	// given a Passworder
+	pw := mock.NewMockPassworder(gomock.NewController(t))
+
+	// when
+	sh := NewSSHKeyHandler(pw)
+
+	// then
+	assert.Equal(t, pw, sh.Passworder)
+}
+
+func TestSSHKeyHandler_ServeHTTP(t *testing.T) {
+	testCases := []struct {
+		desc string
+
+		//given
+		method string
+		query  string
+
+		// expect
+		expectedResponse   int
+		expectedPrefix     string
+		expectedPassphrase string
+		expectedParam      string
+	}{
+		{
+			desc:   "GET, no params",
+			method: http.MethodGet, query: "",
+			expectedResponse: http.StatusOK, expectedPrefix: "ssh-ed25519 ",
+		},
+		{
+			desc:   "GET, ecdsa 384 with comment",
+			method: http.MethodGet, query: "type=ecdsa&bits=384&comment=deploy",
+			expectedResponse: http.StatusOK, expectedPrefix: "ecdsa-sha2-nistp384 ",
+		},
+		{
+			desc:   "GET, encrypted",
+			method: http.MethodGet, query: "encrypt=true",
+			expectedResponse: http.StatusOK, expectedPrefix: "ssh-ed25519 ", expectedPassphrase: "passphrase",
+		},
+		{
+			desc:   "GET, unknown type",
+			method: http.MethodGet, query: "type=dsa",
+			expectedResponse: http.StatusBadRequest, expectedParam: paramType,
+		},
+		{
+			desc:   "GET, invalid bits",
+			method: http.MethodGet, query: "type=rsa&bits=abc",
+			expectedResponse: http.StatusBadRequest, expectedParam: paramBits,
+		},
+		{
+			desc:   "GET, rsa too small",
+			method: http.MethodGet, query: "type=rsa&bits=1024",
+			expectedResponse: http.StatusBadRequest, expectedParam: paramBits,
+		},
+		{
+			desc:   "GET, unknown ecdsa curve",
+			method: http.MethodGet, query: "type=ecdsa&bits=512",
+			expectedResponse: http.StatusBadRequest, expectedParam: paramBits,
+		},
+		{
+			desc:   "GET, invalid encrypt",
+			method: http.MethodGet, query: "encrypt=abc",
+			expectedResponse: http.StatusBadRequest,
+		},
+		{
+			desc:   "GET, comment smuggling a second key",
+			method: http.MethodGet, query: "comment=x%0Assh-ed25519+AAAAC3NzaC1lZDI1NTE5AAAAIA+attacker",
+			expectedResponse: http.StatusBadRequest, expectedParam: paramComment,
+		},
+		{
+			desc:   "GET, comment with control character",
+			method: http.MethodGet, query: "comment=deploy%1B%5B2J",
+			expectedResponse: http.StatusBadRequest, expectedParam: paramComment,
+		},
+		{
+			desc:   "POST",
+			method: http.MethodPost, query: "",
+			expectedResponse: http.StatusMethodNotAllowed,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// given a mocked password generator for passphrases
+			mockPassworder := mock.NewMockPassworder(gomock.NewController(t))
+			mockPassworder.EXPECT().Passwords(gomock.Any(), 1, password.Policy{MinLength: passphraseLength, Numbers: passphraseNumbers}).Return([]string{"passphrase"}, nil).AnyTimes()
+
+			// and our handler
+			sh := NewSSHKeyHandler(mockPassworder)
+
+			// and a recorder for our response
+			rc := httptest.NewRecorder()
+
+			// and a test request
+			req, _ := http.NewRequest(tC.method, "?"+tC.query, nil)
+
+			// when our endpoint is called
+			sh.ServeHTTP(rc, req)
+
+			// then
+			assert.Equal(t, tC.expectedResponse, rc.Code)
+			if tC.expectedResponse != http.StatusOK {
+				var p problem
+				if tC.expectedParam != "" && assert.NoError(t, json.NewDecoder(rc.Body).Decode(&p)) {
+					assert.Equal(t, tC.expectedParam, p.Param)
+				}
+				return
+			}
+			var resp sshKeyResponse
+			assert.NoError(t, json.NewDecoder(rc.Body).Decode(&resp))
+			assert.True(t, strings.HasPrefix(resp.PublicKey, tC.expectedPrefix))
+			assert.Contains(t, resp.PrivateKey, "OPENSSH PRIVATE KEY")
+			assert.Equal(t, tC.expectedPassphrase, resp.Passphrase)
+		})
+	}
+}
+
+func TestSSHKeyHandler_ServeHTTP_Passphrase_Error(t *testing.T) {
+	// given a failing password generator
+	mockPassworder := mock.NewMockPassworder(gomock.NewController(t))
+	mockPassworder.EXPECT().Passwords(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errors.New("no randomness"))
+	rc := httptest.NewRecorder()
+
+	// when an encrypted key is requested
+	NewSSHKeyHandler(mockPassworder).ServeHTTP(rc, httptest.NewRequest(http.MethodGet, "/ssh-keys?encrypt=true", nil))
+
+	// then the server is blamed instead of a parameter
+	assert.Equal(t, http.StatusInternalServerError, rc.Code)
+	var p problem
+	assert.NoError(t, json.Unmarshal(rc.Body.Bytes(), &p))
+	assert.Empty(t, p.Param)
+}
@@ -0,0 +1,115 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/domano/pwgen/internal/x25519"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// X25519Handler generates Curve25519 key pairs for WireGuard and age
+type X25519Handler struct{}
+
+// Constants for the available query params
+const paramPresharedKey = "psk"
+
+// Formats which can be requested with the format query param
+const (
+	formatWireGuard = "wireguard"
+	formatAge       = "age"
+)
+
+// x25519Response contains the key pair in the encoding of the requested format
+type x25519Response struct {
+	PrivateKey   string `json:"privateKey,omitempty"`
+	PublicKey    string `json:"publicKey,omitempty"`
+	PresharedKey string `json:"presharedKey,omitempty"`
+	Identity     string `json:"identity,omitempty"`
+	Recipient    string `json:"recipient,omitempty"`
+}
+
+// NewX25519Handler constructs a new X25519Handler
+func NewX25519Handler() *X25519Handler {
+	return &X25519Handler{}
+}
+
+func (xh *X25519Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Keys are only generated, so nothing except GET is supported
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		methodNotAllowed(w, r, http.MethodGet, http.MethodHead)
+		return
+	}
+
+	req, err := xh.request(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, err)
+		log.WithError(err).Warnln("Received a bad request.")
+		return
+	}
+	resp, err := xh.keyPair(req)
+	if err != nil {
+		writeProblem(w, r, http.StatusInternalServerError, err)
+		log.WithError(err).Errorln("Error while generating key pair")
+		return
+	}
+
+	body, err := json.Marshal(resp)
+	if err != nil {
+		writeProblem(w, r, http.StatusInternalServerError, err)
+		log.WithError(err).Errorln("Error while marshalling json")
+		return
+	}
+	writeBody(w, r, "application/json", body)
+}
+
+// x25519Request holds the validated params of a request for a key pair
+type x25519Request struct {
+	format string
+	psk    bool
+}
+
+// request reads the params of a key pair, they are validated before anything is generated
+func (xh *X25519Handler) request(r *http.Request) (x25519Request, error) {
+	// Get parameters from URL & validate them
+	params := r.URL.Query()
+
+	req := x25519Request{format: formatWireGuard}
+	if val := params.Get(paramFormat); val != "" {
+		req.format = val
+	}
+	if req.format != formatWireGuard && req.format != formatAge {
+		return x25519Request{}, invalidParam(paramFormat, errors.Errorf("Query Parameter %s was no known key format, got %s instead", paramFormat, req.format))
+	}
+	psk, err := boolFromParams(params, paramPresharedKey)
+	if err != nil {
+		return x25519Request{}, errors.Wrap(err, "Could not read psk parameter")
+	}
+	if psk && req.format != formatWireGuard {
+		return x25519Request{}, invalidParam(paramPresharedKey, errors.Errorf("Query Parameter %s is only supported for %s keys", paramPresharedKey, formatWireGuard))
+	}
+	req.psk = psk
+	return req, nil
+}
+
+// keyPair generates the key pair and the preshared key, all errors are failures of the server
+func (xh *X25519Handler) keyPair(req x25519Request) (x25519Response, error) {
+	k, err := x25519.Generate()
+	if err != nil {
+		return x25519Response{}, errors.Wrap(err, "Could not generate key pair")
+	}
+	var resp x25519Response
+	if req.format == formatAge {
+		resp.Identity, resp.Recipient = k.Age()
+		return resp, nil
+	}
+	resp.PrivateKey, resp.PublicKey = k.WireGuard()
+	if req.psk {
+		resp.PresharedKey, err = x25519.PresharedKey()
+		if err != nil {
+			return x25519Response{}, errors.Wrap(err, "Could not generate preshared key")
+		}
+	}
+	return resp, nil
+}
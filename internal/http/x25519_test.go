@@ -0,0 +1,95 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestX25519Handler_ServeHTTP(t *testing.T) {
+	testCases := []struct {
+		desc string
+
+		//given
+		method string
+		query  string
+
+		// expect
+		expectedResponse int
+		expectWireGuard  bool
+		expectPSK        bool
+		expectAge        bool
+		expectedParam    string
+	}{
+		{
+			desc:   "GET, no params",
+			method: http.MethodGet, query: "",
+			expectedResponse: http.StatusOK, expectWireGuard: true,
+		},
+		{
+			desc:   "GET, wireguard with preshared key",
+			method: http.MethodGet, query: "format=wireguard&psk=true",
+			expectedResponse: http.StatusOK, expectWireGuard: true, expectPSK: true,
+		},
+		{
+			desc:   "GET, age",
+			method: http.MethodGet, query: "format=age",
+			expectedResponse: http.StatusOK, expectAge: true,
+		},
+		{
+			desc:   "GET, age with preshared key",
+			method: http.MethodGet, query: "format=age&psk=true",
+			expectedResponse: http.StatusBadRequest, expectedParam: paramPresharedKey,
+		},
+		{
+			desc:   "GET, unknown format",
+			method: http.MethodGet, query: "format=pem",
+			expectedResponse: http.StatusBadRequest, expectedParam: paramFormat,
+		},
+		{
+			desc:   "GET, invalid psk",
+			method: http.MethodGet, query: "psk=abc",
+			expectedResponse: http.StatusBadRequest, expectedParam: paramPresharedKey,
+		},
+		{
+			desc:   "DELETE",
+			method: http.MethodDelete, query: "",
+			expectedResponse: http.StatusMethodNotAllowed,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// given our handler
+			xh := NewX25519Handler()
+
+			// and a recorder for our response
+			rc := httptest.NewRecorder()
+
+			// and a test request
+			req, _ := http.NewRequest(tC.method, "?"+tC.query, nil)
+
+			// when our endpoint is called
+			xh.ServeHTTP(rc, req)
+
+			// then
+			assert.Equal(t, tC.expectedResponse, rc.Code)
+			if tC.expectedResponse == http.StatusBadRequest {
+				var p problem
+				assert.NoError(t, json.NewDecoder(rc.Body).Decode(&p))
+				assert.Equal(t, tC.expectedParam, p.Param)
+			}
+			if tC.expectedResponse != http.StatusOK {
+				return
+			}
+			var resp x25519Response
+			assert.NoError(t, json.NewDecoder(rc.Body).Decode(&resp))
+			assert.Equal(t, tC.expectWireGuard, resp.PrivateKey != "" && resp.PublicKey != "")
+			assert.Equal(t, tC.expectPSK, resp.PresharedKey != "")
+			assert.Equal(t, tC.expectAge, strings.HasPrefix(resp.Identity, "AGE-SECRET-KEY-1") && strings.HasPrefix(resp.Recipient, "age1"))
+		})
+	}
+}
@@ -0,0 +1,234 @@
+// Package jwk generates key material as JSON Web Keys (RFC 7517) with RFC 7638 thumbprints as key IDs.
+package jwk
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Algorithm is the JWS algorithm a key is generated for
+type Algorithm string
+
+// Supported algorithms
+const (
+	HS256 Algorithm = "HS256"
+	HS384 Algorithm = "HS384"
+	HS512 Algorithm = "HS512"
+	ES256 Algorithm = "ES256"
+	ES384 Algorithm = "ES384"
+	EdDSA Algorithm = "EdDSA"
+	RS256 Algorithm = "RS256"
+)
+
+// DefaultRSABits is the RSA key size if none is requested
+const DefaultRSABits = 3072
+
+// Key types as registered for the kty member
+const (
+	ktyOct = "oct"
+	ktyEC  = "EC"
+	ktyOKP = "OKP"
+	ktyRSA = "RSA"
+)
+
+// JWK is a JSON Web Key, members that do not belong to its key type are empty
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	D   string `json:"d,omitempty"`
+	P   string `json:"p,omitempty"`
+	Q   string `json:"q,omitempty"`
+	DP  string `json:"dp,omitempty"`
+	DQ  string `json:"dq,omitempty"`
+	QI  string `json:"qi,omitempty"`
+	K   string `json:"k,omitempty"`
+}
+
+// Set is a JSON Web Key Set
+type Set struct {
+	Keys []JWK `json:"keys"`
+}
+
+var b64 = base64.RawURLEncoding
+
+// Generate creates a new signing key for the algorithm. bits is only used for RSA keys,
+// where zero selects the default.
+func Generate(alg Algorithm, bits int) (JWK, error) {
+	var k JWK
+	var err error
+	switch alg {
+	case HS256, HS384, HS512:
+		k, err = generateOct(alg)
+	case ES256:
+		k, err = generateEC(elliptic.P256(), "P-256")
+	case ES384:
+		k, err = generateEC(elliptic.P384(), "P-384")
+	case EdDSA:
+		k, err = generateOKP()
+	case RS256:
+		k, err = generateRSA(bits)
+	default:
+		return JWK{}, errors.Errorf("algorithm must be one of %s, %s, %s, %s, %s, %s or %s, got %s",
+			HS256, HS384, HS512, ES256, ES384, EdDSA, RS256, alg)
+	}
+	if err != nil {
+		return JWK{}, err
+	}
+	k.Alg = string(alg)
+	k.Use = "sig"
+	k.Kid, err = k.Thumbprint()
+	if err != nil {
+		return JWK{}, err
+	}
+	return k, nil
+}
+
+// Thumbprint computes the SHA-256 JWK thumbprint as defined by RFC 7638.
+func (k JWK) Thumbprint() (string, error) {
+	var members map[string]string
+	switch k.Kty {
+	case ktyOct:
+		members = map[string]string{"k": k.K}
+	case ktyEC:
+		members = map[string]string{"crv": k.Crv, "x": k.X, "y": k.Y}
+	case ktyOKP:
+		members = map[string]string{"crv": k.Crv, "x": k.X}
+	case ktyRSA:
+		members = map[string]string{"e": k.E, "n": k.N}
+	default:
+		return "", errors.Errorf("unknown key type %s", k.Kty)
+	}
+	members["kty"] = k.Kty
+	// encoding/json sorts map keys and adds no whitespace, which is the required canonical form
+	canonical, err := json.Marshal(members)
+	if err != nil {
+		return "", errors.Wrap(err, "Could not marshal thumbprint members")
+	}
+	sum := sha256.Sum256(canonical)
+	return b64.EncodeToString(sum[:]), nil
+}
+
+// Public returns the key without its private members. Symmetric keys have no public part.
+func (k JWK) Public() (JWK, error) {
+	if k.Kty == ktyOct {
+		return JWK{}, errors.New("symmetric keys have no public part")
+	}
+	pub := k
+	pub.D, pub.P, pub.Q, pub.DP, pub.DQ, pub.QI = "", "", "", "", "", ""
+	return pub, nil
+}
+
+func generateOct(alg Algorithm) (JWK, error) {
+	// Keys as long as the hash output, e.g. 256 bits for HS256
+	size := map[Algorithm]int{HS256: 32, HS384: 48, HS512: 64}[alg]
+	key := make([]byte, size)
+	if _, err := rand.Read(key); err != nil {
+		return JWK{}, errors.Wrap(err, "Could not read random key")
+	}
+	return JWK{Kty: ktyOct, K: b64.EncodeToString(key)}, nil
+}
+
+func generateEC(curve elliptic.Curve, name string) (JWK, error) {
+	priv, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		return JWK{}, errors.Wrap(err, "Could not generate ec key")
+	}
+	size := (curve.Params().BitSize + 7) / 8
+	return JWK{
+		Kty: ktyEC,
+		Crv: name,
+		X:   b64.EncodeToString(padded(priv.X, size)),
+		Y:   b64.EncodeToString(padded(priv.Y, size)),
+		D:   b64.EncodeToString(padded(priv.D, size)),
+	}, nil
+}
+
+func generateOKP() (JWK, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return JWK{}, errors.Wrap(err, "Could not generate ed25519 key")
+	}
+	return JWK{
+		Kty: ktyOKP,
+		Crv: "Ed25519",
+		X:   b64.EncodeToString(pub),
+		D:   b64.EncodeToString(priv.Seed()),
+	}, nil
+}
+
+// ValidBits checks whether keys for the algorithm can have the size. Only RSA keys have a configurable size,
+// zero selects the default and the size is ignored for the other algorithms.
+func ValidBits(alg Algorithm, bits int) error {
+	if alg != RS256 || bits == 0 {
+		return nil
+	}
+	if bits != 2048 && bits != 3072 && bits != 4096 {
+		return errors.Errorf("rsa keys must have 2048, 3072 or 4096 bits, got %d", bits)
+	}
+	return nil
+}
+
+// Symmetric reports whether keys for the algorithm are shared secrets without a public part
+func (alg Algorithm) Symmetric() bool {
+	return alg == HS256 || alg == HS384 || alg == HS512
+}
+
+func generateRSA(bits int) (JWK, error) {
+	if err := ValidBits(RS256, bits); err != nil {
+		return JWK{}, err
+	}
+	if bits == 0 {
+		bits = DefaultRSABits
+	}
+	priv, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		return JWK{}, errors.Wrap(err, "Could not generate rsa key")
+	}
+	return JWK{
+		Kty: ktyRSA,
+		N:   b64.EncodeToString(priv.N.Bytes()),
+		E:   b64.EncodeToString(big.NewInt(int64(priv.E)).Bytes()),
+		D:   b64.EncodeToString(priv.D.Bytes()),
+		P:   b64.EncodeToString(priv.Primes[0].Bytes()),
+		Q:   b64.EncodeToString(priv.Primes[1].Bytes()),
+		DP:  b64.EncodeToString(priv.Precomputed.Dp.Bytes()),
+		DQ:  b64.EncodeToString(priv.Precomputed.Dq.Bytes()),
+		QI:  b64.EncodeToString(priv.Precomputed.Qinv.Bytes()),
+	}, nil
+}
+
+// Big endian bytes left padded with zeros to the given size as required for EC members
+func padded(n *big.Int, size int) []byte {
+	b := n.Bytes()
+	if len(b) >= size {
+		return b
+	}
+	return append(make([]byte, size-len(b)), b...)
+}
+
+// ParseAlgorithm returns the algorithm for a case insensitive name.
+func ParseAlgorithm(name string) (Algorithm, bool) {
+	for _, alg := range []Algorithm{HS256, HS384, HS512, ES256, ES384, EdDSA, RS256} {
+		if strings.EqualFold(name, string(alg)) {
+			return alg, true
+		}
+	}
+	return "", false
+}
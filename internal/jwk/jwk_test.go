@@ -0,0 +1,132 @@
+package jwk
+
+import (
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJWK_Thumbprint(t *testing.T) {
+	// given the example key from RFC 7638 section 3.1
+	k := JWK{
+		Kty: "RSA",
+		N:   "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw",
+		E:   "AQAB",
+		Alg: "RS256",
+		Kid: "2011-04-29",
+	}
+
+	// when
+	thumbprint, err := k.Thumbprint()
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs", thumbprint)
+}
+
+func TestGenerate(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		alg         Algorithm
+		expectedKty string
+		expectedCrv string
+	}{
+		{desc: "HS256", alg: HS256, expectedKty: "oct"},
+		{desc: "HS512", alg: HS512, expectedKty: "oct"},
+		{desc: "ES256", alg: ES256, expectedKty: "EC", expectedCrv: "P-256"},
+		{desc: "ES384", alg: ES384, expectedKty: "EC", expectedCrv: "P-384"},
+		{desc: "EdDSA", alg: EdDSA, expectedKty: "OKP", expectedCrv: "Ed25519"},
+		{desc: "RS256", alg: RS256, expectedKty: "RSA"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// when
+			k, err := Generate(tC.alg, 2048)
+
+			// then
+			assert.NoError(t, err)
+			assert.Equal(t, tC.expectedKty, k.Kty)
+			assert.Equal(t, tC.expectedCrv, k.Crv)
+			assert.Equal(t, string(tC.alg), k.Alg)
+			thumbprint, _ := k.Thumbprint()
+			assert.Equal(t, thumbprint, k.Kid)
+		})
+	}
+}
+
+func TestGenerate_Invalid(t *testing.T) {
+	// when
+	_, algErr := Generate("none", 0)
+	_, bitsErr := Generate(RS256, 1024)
+
+	// then
+	assert.Error(t, algErr)
+	assert.Error(t, bitsErr)
+}
+
+func TestGenerate_EC_Key_Matches(t *testing.T) {
+	// when
+	k, _ := Generate(ES384, 0)
+
+	// then the coordinates are padded and the private key belongs to the public key
+	x, y, d := decode(t, k.X), decode(t, k.Y), decode(t, k.D)
+	assert.Equal(t, 48, len(x))
+	assert.Equal(t, 48, len(y))
+	px, py := elliptic.P384().ScalarBaseMult(d)
+	assert.Equal(t, new(big.Int).SetBytes(x), px)
+	assert.Equal(t, new(big.Int).SetBytes(y), py)
+}
+
+func TestGenerate_OKP_Key_Matches(t *testing.T) {
+	// when
+	k, _ := Generate(EdDSA, 0)
+
+	// then
+	priv := ed25519.NewKeyFromSeed(decode(t, k.D))
+	assert.Equal(t, decode(t, k.X), []byte(priv.Public().(ed25519.PublicKey)))
+}
+
+func TestJWK_Public(t *testing.T) {
+	// given
+	rsaKey, _ := Generate(RS256, 2048)
+	octKey, _ := Generate(HS256, 0)
+
+	// when
+	pub, err := rsaKey.Public()
+	_, octErr := octKey.Public()
+
+	// then private members are gone but the key id stays the same
+	assert.NoError(t, err)
+	assert.Empty(t, pub.D+pub.P+pub.Q+pub.DP+pub.DQ+pub.QI)
+	assert.Equal(t, rsaKey.N, pub.N)
+	assert.Equal(t, rsaKey.Kid, pub.Kid)
+	assert.Error(t, octErr)
+}
+
+func TestValidBits(t *testing.T) {
+	// expect only rsa sizes to be checked
+	assert.NoError(t, ValidBits(RS256, 0))
+	assert.NoError(t, ValidBits(RS256, 4096))
+	assert.Error(t, ValidBits(RS256, 1024))
+	assert.NoError(t, ValidBits(ES256, 1024))
+}
+
+func TestParseAlgorithm(t *testing.T) {
+	// when
+	alg, ok := ParseAlgorithm("eddsa")
+	_, unknownOk := ParseAlgorithm("none")
+
+	// then
+	assert.True(t, ok)
+	assert.Equal(t, EdDSA, alg)
+	assert.False(t, unknownOk)
+}
+
+func decode(t *testing.T, s string) []byte {
+	b, err := b64.DecodeString(s)
+	assert.NoError(t, err)
+	return b
+}
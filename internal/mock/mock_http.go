@@ -4,6 +4,9 @@
 package mock
 
 import (
+	"context"
+
+	"github.com/domano/pwgen/internal/password"
 	"github.com/golang/mock/gomock"
 )
 
@@ -28,12 +31,13 @@ func (_m *MockPassworder) EXPECT() *_MockPassworderRecorder {
 	return _m.recorder
 }
 
-func (_m *MockPassworder) Passwords(amount int, minLength int, specialChars int, numbers int, swap bool) []string {
-	ret := _m.ctrl.Call(_m, "Passwords", amount, minLength, specialChars, numbers, swap)
+func (_m *MockPassworder) Passwords(ctx context.Context, amount int, policy password.Policy) ([]string, error) {
+	ret := _m.ctrl.Call(_m, "Passwords", ctx, amount, policy)
 	ret0, _ := ret[0].([]string)
-	return ret0
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-func (_mr *_MockPassworderRecorder) Passwords(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
-	return _mr.mock.ctrl.RecordCall(_mr.mock, "Passwords", arg0, arg1, arg2, arg3, arg4)
+func (_mr *_MockPassworderRecorder) Passwords(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "Passwords", arg0, arg1, arg2)
 }
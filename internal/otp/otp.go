@@ -0,0 +1,275 @@
+// Package otp provides provisioning and verification of time-based one-time passwords as defined by RFC 6238.
+package otp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Algorithm is the HMAC hash function used to compute codes
+type Algorithm string
+
+// Algorithms supported by RFC 6238
+const (
+	SHA1   Algorithm = "SHA1"
+	SHA256 Algorithm = "SHA256"
+	SHA512 Algorithm = "SHA512"
+)
+
+// Defaults used by most authenticator apps
+const (
+	DefaultDigits    = 6
+	DefaultPeriod    = 30 * time.Second
+	DefaultAlgorithm = SHA1
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// Key is a shared secret together with the parameters needed to compute codes from it.
+type Key struct {
+	Secret          []byte
+	Issuer, Account string
+	Digits          int
+	Period          time.Duration
+	Algorithm       Algorithm
+}
+
+// Option is the functional option type to configure keys on creation.
+type Option func(*Key)
+
+// NewKey creates a key with a random secret as long as the output of its hash function.
+// A number of Options can be passed to configure the resulting Key.
+func NewKey(options ...Option) (Key, error) {
+	k := Key{Digits: DefaultDigits, Period: DefaultPeriod, Algorithm: DefaultAlgorithm}
+	for i := range options {
+		options[i](&k)
+	}
+	if err := k.validate(); err != nil {
+		return Key{}, err
+	}
+	k.Secret = make([]byte, k.hash()().Size())
+	if _, err := rand.Read(k.Secret); err != nil {
+		return Key{}, errors.Wrap(err, "Could not read random secret")
+	}
+	return k, nil
+}
+
+// Digits configures the number of digits of a code, from 6 to 8.
+func Digits(digits int) Option {
+	return func(k *Key) {
+		k.Digits = digits
+	}
+}
+
+// Period configures how long a code is valid.
+func Period(period time.Duration) Option {
+	return func(k *Key) {
+		k.Period = period
+	}
+}
+
+// WithAlgorithm configures the hash function used to compute codes.
+func WithAlgorithm(algorithm Algorithm) Option {
+	return func(k *Key) {
+		k.Algorithm = Algorithm(strings.ToUpper(string(algorithm)))
+	}
+}
+
+// Label configures the issuer and account shown in authenticator apps.
+func Label(issuer, account string) Option {
+	return func(k *Key) {
+		k.Issuer = issuer
+		k.Account = account
+	}
+}
+
+func (k Key) validate() error {
+	if err := ValidDigits(k.Digits); err != nil {
+		return err
+	}
+	if err := ValidPeriod(k.Period); err != nil {
+		return err
+	}
+	return ValidAlgorithm(k.Algorithm)
+}
+
+// ValidDigits checks whether codes can have the number of digits
+func ValidDigits(digits int) error {
+	if digits < 6 || digits > 8 {
+		return errors.Errorf("digits must be between 6 and 8, got %d", digits)
+	}
+	return nil
+}
+
+// ValidPeriod checks whether codes can be valid for the period, which must be a positive number of seconds
+func ValidPeriod(period time.Duration) error {
+	if period < time.Second || period%time.Second != 0 {
+		return errors.Errorf("period must be a positive number of seconds, got %s", period)
+	}
+	return nil
+}
+
+// ValidAlgorithm checks whether the algorithm is supported, names are case sensitive
+func ValidAlgorithm(algorithm Algorithm) error {
+	if hashOf(algorithm) == nil {
+		return errors.Errorf("algorithm must be one of %s, %s or %s, got %s", SHA1, SHA256, SHA512, algorithm)
+	}
+	return nil
+}
+
+func (k Key) hash() func() hash.Hash {
+	return hashOf(k.Algorithm)
+}
+
+func hashOf(algorithm Algorithm) func() hash.Hash {
+	switch algorithm {
+	case SHA1:
+		return sha1.New
+	case SHA256:
+		return sha256.New
+	case SHA512:
+		return sha512.New
+	}
+	return nil
+}
+
+// Base32 returns the secret in the unpadded base32 encoding expected by authenticator apps.
+func (k Key) Base32() string {
+	return encoding.EncodeToString(k.Secret)
+}
+
+// URI returns the otpauth URI used to provision authenticator apps, usually shown as QR code.
+func (k Key) URI() string {
+	label := labelEscape(k.Account)
+	if k.Issuer != "" {
+		label = labelEscape(k.Issuer) + ":" + label
+	}
+	params := url.Values{}
+	params.Set("secret", k.Base32())
+	if k.Issuer != "" {
+		params.Set("issuer", k.Issuer)
+	}
+	params.Set("algorithm", string(k.Algorithm))
+	params.Set("digits", strconv.Itoa(k.Digits))
+	params.Set("period", strconv.Itoa(int(k.Period/time.Second)))
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+// labelEscape escapes a part of the label, including colons which separate the issuer from the account
+func labelEscape(s string) string {
+	return strings.Replace(url.PathEscape(s), ":", "%3A", -1)
+}
+
+// Code computes the code which is valid at the given time.
+func (k Key) Code(t time.Time) string {
+	return k.code(k.counter(t))
+}
+
+// Verify checks the code against the codes valid at the given time and
+// skew periods before and after it to allow for clock drift.
+func (k Key) Verify(code string, t time.Time, skew int) bool {
+	counter := k.counter(t)
+	valid := false
+	for i := -int64(skew); i <= int64(skew); i++ {
+		// Compare all candidates in constant time to not leak which one matched
+		if hmac.Equal([]byte(k.code(counter+i)), []byte(code)) {
+			valid = true
+		}
+	}
+	return valid
+}
+
+// ParseURI reads a key from an otpauth URI as returned by Key.URI.
+// Missing parameters take the defaults, just like authenticator apps do.
+func ParseURI(uri string) (Key, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return Key{}, errors.Wrap(err, "URI is invalid")
+	}
+	if u.Scheme != "otpauth" || u.Host != "totp" {
+		return Key{}, errors.Errorf("URI must start with otpauth://totp/, got %s", uri)
+	}
+	params := u.Query()
+	k := Key{Digits: DefaultDigits, Period: DefaultPeriod, Algorithm: DefaultAlgorithm, Issuer: params.Get("issuer")}
+	// Only an unescaped colon separates the issuer from the account
+	label := strings.TrimPrefix(u.EscapedPath(), "/")
+	if i := strings.Index(label, ":"); i >= 0 {
+		issuer, err := url.PathUnescape(label[:i])
+		if err != nil {
+			return Key{}, errors.Wrap(err, "Issuer of the label is invalid")
+		}
+		if k.Issuer == "" {
+			k.Issuer = issuer
+		}
+		label = label[i+1:]
+	}
+	if k.Account, err = url.PathUnescape(label); err != nil {
+		return Key{}, errors.Wrap(err, "Account of the label is invalid")
+	}
+
+	if k.Secret, err = encoding.DecodeString(strings.ToUpper(strings.TrimRight(params.Get("secret"), "="))); err != nil || len(k.Secret) == 0 {
+		return Key{}, errors.New("Secret was no valid base32")
+	}
+	if v := params.Get("algorithm"); v != "" {
+		k.Algorithm = Algorithm(strings.ToUpper(v))
+	}
+	if v := params.Get("digits"); v != "" {
+		if k.Digits, err = strconv.Atoi(v); err != nil {
+			return Key{}, errors.Wrap(err, "digits must be a number")
+		}
+	}
+	if v := params.Get("period"); v != "" {
+		seconds, err := strconv.Atoi(v)
+		if err != nil {
+			return Key{}, errors.Wrap(err, "period must be a number of seconds")
+		}
+		k.Period = time.Duration(seconds) * time.Second
+	}
+	if err := k.validate(); err != nil {
+		return Key{}, err
+	}
+	return k, nil
+}
+
+// Verify checks a code against the key of an otpauth URI with all of its parameters,
+// allowing one period of clock drift. It is meant for tests of provisioned secrets.
+func Verify(uri, code string, t time.Time) (bool, error) {
+	k, err := ParseURI(uri)
+	if err != nil {
+		return false, err
+	}
+	return k.Verify(code, t, 1), nil
+}
+
+func (k Key) counter(t time.Time) int64 {
+	return t.Unix() / int64(k.Period/time.Second)
+}
+
+// HOTP as defined by RFC 4226 with dynamic truncation
+func (k Key) code(counter int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(counter))
+	mac := hmac.New(k.hash(), k.Secret)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0xf
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	mod := uint32(1)
+	for i := 0; i < k.Digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", k.Digits, value%mod)
+}
@@ -0,0 +1,164 @@
+package otp
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Test vectors from RFC 6238 Appendix B
+func TestKey_Code(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		algorithm Algorithm
+		secret    string
+		time      int64
+		expected  string
+	}{
+		{desc: "SHA1 at 59", algorithm: SHA1, secret: "12345678901234567890", time: 59, expected: "94287082"},
+		{desc: "SHA256 at 59", algorithm: SHA256, secret: "12345678901234567890123456789012", time: 59, expected: "46119246"},
+		{desc: "SHA512 at 59", algorithm: SHA512, secret: strings.Repeat("1234567890", 6) + "1234", time: 59, expected: "90693936"},
+		{desc: "SHA1 at 1111111109", algorithm: SHA1, secret: "12345678901234567890", time: 1111111109, expected: "07081804"},
+		{desc: "SHA256 at 1234567890", algorithm: SHA256, secret: "12345678901234567890123456789012", time: 1234567890, expected: "91819424"},
+		{desc: "SHA512 at 20000000000", algorithm: SHA512, secret: strings.Repeat("1234567890", 6) + "1234", time: 20000000000, expected: "47863826"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// given
+			k := Key{Secret: []byte(tC.secret), Digits: 8, Period: DefaultPeriod, Algorithm: tC.algorithm}
+
+			// when
+			code := k.Code(time.Unix(tC.time, 0))
+
+			// then
+			assert.Equal(t, tC.expected, code)
+		})
+	}
+}
+
+func TestNewKey(t *testing.T) {
+	testCases := []struct {
+		desc           string
+		options        []Option
+		expectedSecret int
+		expectedErr    bool
+	}{
+		{desc: "Defaults", options: nil, expectedSecret: 20},
+		{desc: "SHA256", options: []Option{WithAlgorithm("sha256")}, expectedSecret: 32},
+		{desc: "SHA512 with 8 digits", options: []Option{WithAlgorithm(SHA512), Digits(8)}, expectedSecret: 64},
+		{desc: "Unknown algorithm", options: []Option{WithAlgorithm("MD5")}, expectedErr: true},
+		{desc: "Too few digits", options: []Option{Digits(4)}, expectedErr: true},
+		{desc: "Period below a second", options: []Option{Period(time.Millisecond)}, expectedErr: true},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// when
+			k, err := NewKey(tC.options...)
+
+			// then
+			assert.Equal(t, tC.expectedErr, err != nil)
+			assert.Equal(t, tC.expectedSecret, len(k.Secret))
+		})
+	}
+}
+
+func TestKey_URI(t *testing.T) {
+	// given
+	k := Key{Secret: []byte("12345678901234567890"), Issuer: "ACME Co", Account: "svc@acme.test", Digits: 6, Period: DefaultPeriod, Algorithm: SHA1}
+
+	// when
+	uri := k.URI()
+
+	// then
+	assert.Equal(t, "otpauth://totp/ACME%20Co:svc@acme.test?algorithm=SHA1&digits=6&issuer=ACME+Co&period=30&secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", uri)
+}
+
+func TestKey_Verify(t *testing.T) {
+	// given
+	k, _ := NewKey()
+	now := time.Now()
+
+	// then codes within the skew are accepted
+	assert.True(t, k.Verify(k.Code(now), now, 1))
+	assert.True(t, k.Verify(k.Code(now.Add(-DefaultPeriod)), now, 1))
+	assert.False(t, k.Verify(k.Code(now.Add(-3*DefaultPeriod)), now, 1))
+	assert.False(t, k.Verify("", now, 1))
+}
+
+func TestVerify(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		options []Option
+	}{
+		{desc: "Defaults"},
+		{desc: "SHA256 with 60 seconds", options: []Option{WithAlgorithm(SHA256), Period(time.Minute)}},
+		{desc: "SHA512 with 8 digits", options: []Option{WithAlgorithm(SHA512), Digits(8), Label("ACME Co", "svc@acme.test")}},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// given
+			k, err := NewKey(tC.options...)
+			assert.NoError(t, err)
+			now := time.Now()
+
+			// when
+			valid, err := Verify(k.URI(), k.Code(now), now)
+
+			// then
+			assert.NoError(t, err)
+			assert.True(t, valid)
+		})
+	}
+}
+
+func TestParseURI_Colon(t *testing.T) {
+	// given a label with colons in the issuer and the account
+	k, _ := NewKey(Label("ACME:Prod", "svc:backup@acme.test"))
+
+	// when
+	uri := k.URI()
+	parsed, err := ParseURI(uri)
+
+	// then only the separator is a colon
+	assert.Contains(t, uri, "/ACME%3AProd:svc%3Abackup@acme.test?")
+	assert.NoError(t, err)
+	assert.Equal(t, k, parsed)
+}
+
+func TestParseURI_Without_Issuer_Param(t *testing.T) {
+	// when
+	k, err := ParseURI("otpauth://totp/ACME%3AProd:svc%3Abackup?secret=GEZDGNBV")
+
+	// then the issuer is read from the label
+	assert.NoError(t, err)
+	assert.Equal(t, "ACME:Prod", k.Issuer)
+	assert.Equal(t, "svc:backup", k.Account)
+}
+
+func TestParseURI(t *testing.T) {
+	// given
+	k, _ := NewKey(WithAlgorithm(SHA256), Digits(8), Period(time.Minute), Label("ACME Co", "svc@acme.test"))
+
+	// when
+	parsed, err := ParseURI(k.URI())
+
+	// then the key is restored
+	assert.NoError(t, err)
+	assert.Equal(t, k, parsed)
+
+	// and invalid URIs are rejected
+	for _, uri := range []string{
+		"https://totp/x?secret=GEZDGNBV",
+		"otpauth://hotp/x?secret=GEZDGNBV",
+		"otpauth://totp/x?secret=not+base32!",
+		"otpauth://totp/x",
+		"otpauth://totp/x?secret=GEZDGNBV&digits=10",
+		"otpauth://totp/x?secret=GEZDGNBV&period=soon",
+		"otpauth://totp/x?secret=GEZDGNBV&algorithm=MD5",
+	} {
+		_, err := ParseURI(uri)
+		assert.Error(t, err, uri)
+	}
+}
@@ -0,0 +1,221 @@
+package passhash
+
+import (
+	"crypto/md5"
+	"crypto/sha512"
+	"fmt"
+	"hash"
+	"strings"
+)
+
+// crypt(3) uses its own base64 alphabet with a little endian bit order
+const cryptAlphabet = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// Salt lengths in characters and rounds of the crypt(3) schemes.
+// glibc accepts up to 999999999 rounds, which would take minutes per password.
+const (
+	sha512CryptSaltLength = 16
+	apr1SaltLength        = 8
+
+	sha512CryptDefaultRounds, sha512CryptMinRounds, sha512CryptMaxRounds = 5000, 1000, 500000
+	apr1Rounds                                                           = 1000
+)
+
+// Order in which the digest bytes are encoded, three bytes form four characters.
+// The last group holds the remaining bytes, missing bytes are marked with -1.
+var sha512CryptOrder = [][3]int{
+	{0, 21, 42}, {22, 43, 1}, {44, 2, 23}, {3, 24, 45}, {25, 46, 4}, {47, 5, 26}, {6, 27, 48},
+	{28, 49, 7}, {50, 8, 29}, {9, 30, 51}, {31, 52, 10}, {53, 11, 32}, {12, 33, 54}, {34, 55, 13},
+	{56, 14, 35}, {15, 36, 57}, {37, 58, 16}, {59, 17, 38}, {18, 39, 60}, {40, 61, 19}, {62, 20, 41},
+	{-1, -1, 63},
+}
+
+var sha256CryptOrder = [][3]int{
+	{0, 10, 20}, {21, 1, 11}, {12, 22, 2}, {3, 13, 23}, {24, 4, 14}, {15, 25, 5}, {6, 16, 26},
+	{27, 7, 17}, {18, 28, 8}, {9, 19, 29}, {-1, 31, 30},
+}
+
+var apr1Order = [][3]int{{0, 6, 12}, {1, 7, 13}, {2, 8, 14}, {3, 9, 15}, {4, 10, 5}, {-1, -1, 11}}
+
+// cryptSalt returns a random salt of crypt(3) characters
+func cryptSalt(length int) (string, error) {
+	s, err := salt(length)
+	if err != nil {
+		return "", err
+	}
+	for i := range s {
+		s[i] = cryptAlphabet[s[i]&0x3f]
+	}
+	return string(s), nil
+}
+
+// cryptEncode encodes the digest bytes in the given order, each byte contributes 8 bits to the 6 bit characters
+func cryptEncode(digest []byte, order [][3]int) string {
+	var b strings.Builder
+	for _, group := range order {
+		var w uint
+		bits := 0
+		for _, i := range group {
+			w <<= 8
+			if i >= 0 {
+				w |= uint(digest[i])
+				bits += 8
+			}
+		}
+		for ; bits > 0; bits -= 6 {
+			b.WriteByte(cryptAlphabet[w&0x3f])
+			w >>= 6
+		}
+	}
+	return b.String()
+}
+
+// repeated fills length bytes by repeating the digest
+func repeated(digest []byte, length int) []byte {
+	result := make([]byte, length)
+	for i := range result {
+		result[i] = digest[i%len(digest)]
+	}
+	return result
+}
+
+func sum(h hash.Hash, parts ...[]byte) []byte {
+	for _, p := range parts {
+		h.Write(p)
+	}
+	return h.Sum(nil)
+}
+
+type sha512CryptHasher struct {
+	rounds int
+}
+
+func (h sha512CryptHasher) Hash(password string) (string, error) {
+	s, err := cryptSalt(sha512CryptSaltLength)
+	if err != nil {
+		return "", err
+	}
+	return sha512Crypt(password, s, h.rounds), nil
+}
+
+// sha512Crypt implements the SHA-512 based crypt(3) scheme "$6$" by Ulrich Drepper
+func sha512Crypt(password, salt string, rounds int) string {
+	s := []byte(salt)
+	if len(s) > sha512CryptSaltLength {
+		s = s[:sha512CryptSaltLength]
+	}
+	digest := shaCrypt(sha512.New, []byte(password), s, rounds)
+
+	// The rounds are implicit if they match the default
+	prefix := "$6$"
+	if rounds != sha512CryptDefaultRounds {
+		prefix += fmt.Sprintf("rounds=%d$", rounds)
+	}
+	return prefix + string(s) + "$" + cryptEncode(digest, sha512CryptOrder)
+}
+
+// shaCrypt computes the digest of the SHA-256 and SHA-512 based crypt(3) schemes
+func shaCrypt(newHash func() hash.Hash, pw, s []byte, rounds int) []byte {
+	alternate := sum(newHash(), pw, s, pw)
+	a := newHash()
+	a.Write(pw)
+	a.Write(s)
+	a.Write(repeated(alternate, len(pw)))
+	for i := len(pw); i > 0; i >>= 1 {
+		if i&1 != 0 {
+			a.Write(alternate)
+		} else {
+			a.Write(pw)
+		}
+	}
+	digest := a.Sum(nil)
+
+	p := newHash()
+	for range pw {
+		p.Write(pw)
+	}
+	pBytes := repeated(p.Sum(nil), len(pw))
+	ds := newHash()
+	for i := 0; i < 16+int(digest[0]); i++ {
+		ds.Write(s)
+	}
+	sBytes := repeated(ds.Sum(nil), len(s))
+
+	for i := 0; i < rounds; i++ {
+		c := newHash()
+		if i&1 != 0 {
+			c.Write(pBytes)
+		} else {
+			c.Write(digest)
+		}
+		if i%3 != 0 {
+			c.Write(sBytes)
+		}
+		if i%7 != 0 {
+			c.Write(pBytes)
+		}
+		if i&1 != 0 {
+			c.Write(digest)
+		} else {
+			c.Write(pBytes)
+		}
+		digest = c.Sum(nil)
+	}
+	return digest
+}
+
+type apr1Hasher struct{}
+
+func (h apr1Hasher) Hash(password string) (string, error) {
+	s, err := cryptSalt(apr1SaltLength)
+	if err != nil {
+		return "", err
+	}
+	return apr1(password, s), nil
+}
+
+// apr1 implements the MD5 based crypt(3) scheme of the Apache htpasswd tool
+func apr1(password, salt string) string {
+	const magic = "$apr1$"
+	pw, s := []byte(password), []byte(salt)
+	if len(s) > apr1SaltLength {
+		s = s[:apr1SaltLength]
+	}
+
+	alternate := sum(md5.New(), pw, s, pw)
+	a := md5.New()
+	a.Write(pw)
+	a.Write([]byte(magic))
+	a.Write(s)
+	a.Write(repeated(alternate, len(pw)))
+	for i := len(pw); i > 0; i >>= 1 {
+		if i&1 != 0 {
+			a.Write([]byte{0})
+		} else {
+			a.Write(pw[:1])
+		}
+	}
+	digest := a.Sum(nil)
+
+	for i := 0; i < apr1Rounds; i++ {
+		c := md5.New()
+		if i&1 != 0 {
+			c.Write(pw)
+		} else {
+			c.Write(digest)
+		}
+		if i%3 != 0 {
+			c.Write(s)
+		}
+		if i%7 != 0 {
+			c.Write(pw)
+		}
+		if i&1 != 0 {
+			c.Write(digest)
+		} else {
+			c.Write(pw)
+		}
+		digest = c.Sum(nil)
+	}
+	return magic + string(s) + "$" + cryptEncode(digest, apr1Order)
+}
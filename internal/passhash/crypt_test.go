@@ -0,0 +1,71 @@
+package passhash
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSHA512Crypt(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		password string
+		salt     string
+		rounds   int
+		expected string
+	}{
+		{
+			desc:     "Default rounds",
+			password: "Hello world!", salt: "saltstring", rounds: 5000,
+			expected: "$6$saltstring$svn8UoSVapNtMuq1ukKS4tPQd8iKwSMHWjl/O817G3uBnIFNjnQJuesI68u4OTLiBFdcbYEdFCoEOfaS35inz1",
+		},
+		{
+			desc:     "Explicit rounds and truncated salt",
+			password: "Hello world!", salt: "saltstringsaltstring", rounds: 10000,
+			expected: "$6$rounds=10000$saltstringsaltst$OW1/O6BYHV6BcXZu8QVeXbDWra3Oeqh0sbHbbMCVNSnCM/UrjmM0Dp8vOuZeHBy/YTBmSK6H9qs/y3RnOaw5v.",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// when
+			hash := sha512Crypt(tC.password, tC.salt, tC.rounds)
+
+			// then the hash matches the reference implementation
+			assert.Equal(t, tC.expected, hash)
+		})
+	}
+}
+
+func TestAPR1(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		password string
+		salt     string
+		expected string
+	}{
+		{desc: "Full salt", password: "Hello world!", salt: "abcdefgh", expected: "$apr1$abcdefgh$Unf1zc.jsgCbBQDCL104q."},
+		{desc: "Empty password", password: "", salt: "x", expected: "$apr1$x$tMwYqBfQwi3FYAr0aJc8M/"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// when
+			hash := apr1(tC.password, tC.salt)
+
+			// then the hash matches the reference implementation
+			assert.Equal(t, tC.expected, hash)
+		})
+	}
+}
+
+func TestCryptHashers_Hash(t *testing.T) {
+	// when
+	sha512Hash, sha512Err := sha512CryptHasher{sha512CryptMinRounds}.Hash("correct horse")
+	apr1Hash, apr1Err := apr1Hasher{}.Hash("correct horse")
+
+	// then a random salt is used
+	assert.NoError(t, sha512Err)
+	assert.Regexp(t, regexp.MustCompile(`^\$6\$rounds=1000\$[./0-9A-Za-z]{16}\$[./0-9A-Za-z]{86}$`), sha512Hash)
+	assert.NoError(t, apr1Err)
+	assert.Regexp(t, regexp.MustCompile(`^\$apr1\$[./0-9A-Za-z]{8}\$[./0-9A-Za-z]{22}$`), apr1Hash)
+}
@@ -0,0 +1,77 @@
+package passhash
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// Parameters of the PostgreSQL SCRAM-SHA-256 verifiers and MySQL caching_sha2_password hashes,
+// SCRAM iterations are capped like the ones of pbkdf2-sha256
+const (
+	scramDefaultIterations, scramMinIterations, scramMaxIterations = 4096, 4096, 2000000
+
+	cachingSHA2SaltLength = 20
+	cachingSHA2Rounds     = 5000
+)
+
+type scramHasher struct {
+	iterations int
+}
+
+// Hash creates a verifier for CREATE ROLE and ALTER ROLE statements of PostgreSQL.
+// Passwords are not normalized with SASLprep, which does not change ASCII passwords.
+func (h scramHasher) Hash(password string) (string, error) {
+	s, err := salt(saltSize)
+	if err != nil {
+		return "", err
+	}
+	return scramSHA256(password, s, h.iterations), nil
+}
+
+func scramSHA256(password string, s []byte, iterations int) string {
+	salted := pbkdf2.Key([]byte(password), s, iterations, sha256.Size, sha256.New)
+	clientKey := hmacSHA256(salted, "Client Key")
+	storedKey := sha256.Sum256(clientKey)
+	serverKey := hmacSHA256(salted, "Server Key")
+	enc := base64.StdEncoding
+	return fmt.Sprintf("SCRAM-SHA-256$%d:%s$%s:%s", iterations, enc.EncodeToString(s), enc.EncodeToString(storedKey[:]), enc.EncodeToString(serverKey))
+}
+
+func hmacSHA256(key []byte, message string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(message))
+	return mac.Sum(nil)
+}
+
+// mysqlNativeHasher creates hashes of the deprecated mysql_native_password plugin, which are unsalted
+type mysqlNativeHasher struct{}
+
+func (h mysqlNativeHasher) Hash(password string) (string, error) {
+	first := sha1.Sum([]byte(password))
+	second := sha1.Sum(first[:])
+	return "*" + strings.ToUpper(hex.EncodeToString(second[:])), nil
+}
+
+// cachingSHA2Hasher creates hashes of the caching_sha2_password plugin for CREATE USER ... IDENTIFIED WITH ... AS statements
+type cachingSHA2Hasher struct{}
+
+func (h cachingSHA2Hasher) Hash(password string) (string, error) {
+	s, err := cryptSalt(cachingSHA2SaltLength)
+	if err != nil {
+		return "", err
+	}
+	return cachingSHA2(password, s), nil
+}
+
+// cachingSHA2 uses the SHA-256 based crypt(3) scheme with a longer salt and stores the rounds in thousands as hex
+func cachingSHA2(password, salt string) string {
+	digest := shaCrypt(sha256.New, []byte(password), []byte(salt), cachingSHA2Rounds)
+	return fmt.Sprintf("$A$%03X$%s%s", cachingSHA2Rounds/1000, salt, cryptEncode(digest, sha256CryptOrder))
+}
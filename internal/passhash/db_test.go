@@ -0,0 +1,55 @@
+package passhash
+
+import (
+	"crypto/sha256"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScramSHA256(t *testing.T) {
+	// when
+	verifier := scramSHA256("secret", []byte("saltsaltsaltsalt"), 4096)
+
+	// then
+	assert.Equal(t, "SCRAM-SHA-256$4096:c2FsdHNhbHRzYWx0c2FsdA==$Ce3wZiZ+yIBCjltccfRiqM0+XDsLE3qPdkEeZKe3hus=:k3q4nlLsA09ST5FLo9zNmfyXR+Ci1J4KmBK5JRVSxeI=", verifier)
+}
+
+func TestScramHasher_Hash(t *testing.T) {
+	// when
+	verifier, err := scramHasher{scramMinIterations}.Hash("secret")
+
+	// then a random salt is used
+	assert.NoError(t, err)
+	assert.Regexp(t, regexp.MustCompile(`^SCRAM-SHA-256\$4096:[A-Za-z0-9+/]{22}==\$[A-Za-z0-9+/]{43}=:[A-Za-z0-9+/]{43}=$`), verifier)
+}
+
+func TestMySQLNativeHasher_Hash(t *testing.T) {
+	// when
+	hash, err := mysqlNativeHasher{}.Hash("secret")
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, "*14E65567ABDB5135D0CFD9A70B3032C179A49EE7", hash)
+}
+
+func TestShaCrypt_SHA256(t *testing.T) {
+	// when
+	digest := shaCrypt(sha256.New, []byte("Hello world!"), []byte("saltstring"), 5000)
+
+	// then the digest matches the "$5$" reference implementation
+	assert.Equal(t, "5B8vYYiY.CVt1RlTTf8KbXBH3hsxY/GNooZaBBGWEc5", cryptEncode(digest, sha256CryptOrder))
+}
+
+func TestCachingSHA2Hasher_Hash(t *testing.T) {
+	// when
+	hash, err := cachingSHA2Hasher{}.Hash("secret")
+
+	// then the whole 20 character salt is used
+	assert.NoError(t, err)
+	assert.Regexp(t, regexp.MustCompile(`^\$A\$005\$[./0-9A-Za-z]{63}$`), hash)
+	salt := strings.TrimPrefix(hash, "$A$005$")[:cachingSHA2SaltLength]
+	assert.Equal(t, cachingSHA2("secret", salt), hash)
+}
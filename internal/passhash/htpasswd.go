@@ -0,0 +1,41 @@
+package passhash
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// htpasswdHasher prefixes hashes with the user name to form a line of an htpasswd file
+type htpasswdHasher struct {
+	user   string
+	hasher Hasher
+}
+
+// NewHtpasswd returns a hasher creating "user:hash" lines for htpasswd files used by Apache and nginx.
+// Only the APR1 and bcrypt algorithms are supported, as those are understood by both servers on every platform.
+func NewHtpasswd(user, algorithm string, cost int) (Hasher, error) {
+	if user == "" || strings.ContainsAny(user, ":\r\n") {
+		return nil, errors.Wrapf(ErrUser, "htpasswd user must not be empty or contain colons and line breaks, got %q", user)
+	}
+	if algorithm != APR1 && algorithm != Bcrypt {
+		return nil, errors.Wrapf(ErrUnknownAlgorithm, "htpasswd algorithm must be %s or %s, got %s", APR1, Bcrypt, algorithm)
+	}
+	h, err := New(algorithm, cost)
+	if err != nil {
+		return nil, err
+	}
+	return htpasswdHasher{user, h}, nil
+}
+
+func (h htpasswdHasher) Hash(password string) (string, error) {
+	hash, err := h.hasher.Hash(password)
+	if err != nil {
+		return "", err
+	}
+	// The htpasswd tool marks its bcrypt hashes with the 2y prefix, they are identical to 2a hashes of Go
+	if strings.HasPrefix(hash, "$2a$") {
+		hash = "$2y$" + strings.TrimPrefix(hash, "$2a$")
+	}
+	return h.user + ":" + hash, nil
+}
@@ -0,0 +1,53 @@
+package passhash
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestNewHtpasswd(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		user        string
+		algorithm   string
+		cost        int
+		expected    Hasher
+		expectedErr error
+	}{
+		{desc: "apr1", user: "alice", algorithm: APR1, expected: htpasswdHasher{"alice", apr1Hasher{}}},
+		{desc: "bcrypt with cost", user: "alice", algorithm: Bcrypt, cost: 10, expected: htpasswdHasher{"alice", bcryptHasher{10}}},
+		{desc: "bcrypt with invalid cost", user: "alice", algorithm: Bcrypt, cost: 4, expectedErr: ErrCost},
+		{desc: "unsupported algorithm", user: "alice", algorithm: Argon2id, expectedErr: ErrUnknownAlgorithm},
+		{desc: "empty user", user: "", algorithm: APR1, expectedErr: ErrUser},
+		{desc: "user with colon", user: "al:ice", algorithm: APR1, expectedErr: ErrUser},
+		{desc: "user with line break", user: "alice\nbob", algorithm: APR1, expectedErr: ErrUser},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// when
+			h, err := NewHtpasswd(tC.user, tC.algorithm, tC.cost)
+
+			// then
+			assert.Equal(t, tC.expectedErr, errors.Cause(err))
+			assert.Equal(t, tC.expected, h)
+		})
+	}
+}
+
+func TestHtpasswdHasher_Hash(t *testing.T) {
+	// given
+	h, _ := NewHtpasswd("alice", Bcrypt, bcryptMinCost)
+
+	// when
+	line, err := h.Hash("correct horse")
+
+	// then the line uses the bcrypt prefix of the htpasswd tool
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(line, "alice:$2y$10$"))
+	hash := strings.TrimPrefix(line, "alice:")
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(hash), []byte("correct horse")))
+}
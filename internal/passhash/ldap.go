@@ -0,0 +1,42 @@
+package passhash
+
+import (
+	"crypto/sha1"
+	"crypto/sha512"
+	"encoding/base64"
+	"hash"
+)
+
+// Salts of LDAP hashes are appended to the digest, OpenLDAP accepts any length
+const ldapSaltSize = 8
+
+// LDAP password schemes of RFC 2307 userPassword values
+const (
+	schemeSSHA    = "{SSHA}"
+	schemeSSHA512 = "{SSHA512}"
+)
+
+// ldapHasher creates salted userPassword values like slappasswd does
+type ldapHasher struct {
+	scheme string
+}
+
+func (h ldapHasher) Hash(password string) (string, error) {
+	s, err := salt(ldapSaltSize)
+	if err != nil {
+		return "", err
+	}
+	return h.salted(password, s), nil
+}
+
+func (h ldapHasher) salted(password string, s []byte) string {
+	digest := sum(h.newHash(), []byte(password), s)
+	return h.scheme + base64.StdEncoding.EncodeToString(append(digest, s...))
+}
+
+func (h ldapHasher) newHash() hash.Hash {
+	if h.scheme == schemeSSHA512 {
+		return sha512.New()
+	}
+	return sha1.New()
+}
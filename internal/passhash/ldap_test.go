@@ -0,0 +1,52 @@
+package passhash
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLDAPHasher_Salted(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		hasher   ldapHasher
+		expected string
+	}{
+		{
+			desc:     "SSHA",
+			hasher:   ldapHasher{schemeSSHA},
+			expected: "{SSHA}1G904nLkTkGWjKNnQuB/hpWXC/hzYWx0c2FsdA==",
+		},
+		{
+			desc:     "SSHA512",
+			hasher:   ldapHasher{schemeSSHA512},
+			expected: "{SSHA512}aCu7JRc+kLsuEmFs1zTY+AiP7DSGnjjG+dH28Dp+E5usqoAixeTPihKqZmkWal4mUfp63tqvCAkFV1LKTDFH6XNhbHRzYWx0",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// when
+			hash := tC.hasher.salted("secret", []byte("saltsalt"))
+
+			// then
+			assert.Equal(t, tC.expected, hash)
+		})
+	}
+}
+
+func TestLDAPHasher_Hash(t *testing.T) {
+	// when
+	hash, err := ldapHasher{schemeSSHA}.Hash("secret")
+
+	// then the salt is appended to the digest
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "{SSHA}"))
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(hash, "{SSHA}"))
+	assert.NoError(t, err)
+	assert.Len(t, decoded, sha1.Size+ldapSaltSize)
+	digest := sha1.Sum(append([]byte("secret"), decoded[sha1.Size:]...))
+	assert.Equal(t, digest[:], decoded[:sha1.Size])
+}
@@ -0,0 +1,218 @@
+// Package passhash hashes passwords for storage in the formats expected by common consumers,
+// e.g. the PHC string format.
+package passhash
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Hasher hashes passwords with a random salt
+type Hasher interface {
+	Hash(password string) (string, error)
+}
+
+// Names of the supported algorithms
+const (
+	Bcrypt       = "bcrypt"
+	Scrypt       = "scrypt"
+	Argon2id     = "argon2id"
+	PBKDF2SHA256 = "pbkdf2-sha256"
+	SHA512Crypt  = "sha512-crypt"
+	APR1         = "apr1"
+	SSHA         = "ssha"
+	SSHA512      = "ssha512"
+	ScramSHA256  = "scram-sha-256"
+	MySQLNative  = "mysql_native_password"
+	CachingSHA2  = "caching_sha2_password"
+)
+
+// Algorithms lists the names of all supported algorithms
+var Algorithms = []string{Bcrypt, Scrypt, Argon2id, PBKDF2SHA256, SHA512Crypt, APR1, SSHA, SSHA512, ScramSHA256, MySQLNative, CachingSHA2}
+
+// Hashers of algorithms without an adjustable cost
+var fixedCostHashers = map[string]Hasher{
+	APR1:    apr1Hasher{},
+	SSHA:    ldapHasher{schemeSSHA},
+	SSHA512: ldapHasher{schemeSSHA512},
+
+	MySQLNative: mysqlNativeHasher{},
+	CachingSHA2: cachingSHA2Hasher{},
+}
+
+// BcryptMaxLength is the number of bytes bcrypt uses, anything beyond is silently ignored by most implementations
+const BcryptMaxLength = 72
+
+// Causes of the errors of New, NewHtpasswd and Hash, which callers can tell apart with errors.Cause
+var (
+	// ErrTooLong is returned when a password is longer than the algorithm supports
+	ErrTooLong = errors.New("password too long for algorithm")
+	// ErrUnknownAlgorithm is returned when no hasher supports the algorithm
+	ErrUnknownAlgorithm = errors.New("unknown algorithm")
+	// ErrCost is returned when the cost is out of range or the algorithm has a fixed cost
+	ErrCost = errors.New("invalid cost")
+	// ErrUser is returned when the user can not be part of an htpasswd line
+	ErrUser = errors.New("invalid user")
+)
+
+// Sizes of random salts and derived keys in bytes
+const (
+	saltSize = 16
+	keySize  = 32
+)
+
+// Default, minimum and maximum cost as well as fixed parameters of each algorithm.
+// The maximum keeps a single hash around a second and scrypt at 128 MiB of memory,
+// since the service computes them for unauthenticated requests with many passwords.
+const (
+	bcryptDefaultCost, bcryptMinCost, bcryptMaxCost = 12, 10, 14
+
+	scryptDefaultLogN, scryptMinLogN, scryptMaxLogN = 15, 14, 17
+	scryptR, scryptP                                = 8, 1
+
+	argon2DefaultPasses, argon2MinPasses, argon2MaxPasses = 3, 1, 10
+	argon2Memory                                          = 64 * 1024 // KiB
+	argon2Threads                                         = 4
+	argon2Version                                         = 19
+
+	pbkdf2DefaultIterations, pbkdf2MinIterations, pbkdf2MaxIterations = 600000, 100000, 2000000
+)
+
+// PHC strings use standard base64 without padding
+var b64 = base64.RawStdEncoding
+
+// New returns the hasher for the algorithm with the given cost, where zero selects the default.
+// The meaning of the cost depends on the algorithm: the bcrypt cost, the base 2 logarithm of the
+// scrypt N, the Argon2id number of passes or the PBKDF2, SHA-512-crypt and SCRAM number of iterations.
+// APR1, the LDAP schemes and the MySQL plugins have a fixed cost.
+func New(algorithm string, cost int) (Hasher, error) {
+	switch algorithm {
+	case Bcrypt:
+		cost, err := costInRange(cost, bcryptDefaultCost, bcryptMinCost, bcryptMaxCost)
+		if err != nil {
+			return nil, err
+		}
+		return bcryptHasher{cost}, nil
+	case Scrypt:
+		logN, err := costInRange(cost, scryptDefaultLogN, scryptMinLogN, scryptMaxLogN)
+		if err != nil {
+			return nil, err
+		}
+		return scryptHasher{logN}, nil
+	case Argon2id:
+		passes, err := costInRange(cost, argon2DefaultPasses, argon2MinPasses, argon2MaxPasses)
+		if err != nil {
+			return nil, err
+		}
+		return argon2Hasher{passes}, nil
+	case PBKDF2SHA256:
+		iterations, err := costInRange(cost, pbkdf2DefaultIterations, pbkdf2MinIterations, pbkdf2MaxIterations)
+		if err != nil {
+			return nil, err
+		}
+		return pbkdf2Hasher{iterations}, nil
+	case SHA512Crypt:
+		rounds, err := costInRange(cost, sha512CryptDefaultRounds, sha512CryptMinRounds, sha512CryptMaxRounds)
+		if err != nil {
+			return nil, err
+		}
+		return sha512CryptHasher{rounds}, nil
+	case ScramSHA256:
+		iterations, err := costInRange(cost, scramDefaultIterations, scramMinIterations, scramMaxIterations)
+		if err != nil {
+			return nil, err
+		}
+		return scramHasher{iterations}, nil
+	case APR1, SSHA, SSHA512, MySQLNative, CachingSHA2:
+		if cost != 0 {
+			return nil, errors.Wrapf(ErrCost, "%s has no adjustable cost, got %d", algorithm, cost)
+		}
+		return fixedCostHashers[algorithm], nil
+	}
+	return nil, errors.Wrapf(ErrUnknownAlgorithm, "algorithm must be one of %s, got %s", strings.Join(Algorithms, ", "), algorithm)
+}
+
+func costInRange(cost, defaultCost, min, max int) (int, error) {
+	if cost == 0 {
+		return defaultCost, nil
+	}
+	if cost < min || cost > max {
+		return 0, errors.Wrapf(ErrCost, "cost must be between %d and %d, got %d", min, max, cost)
+	}
+	return cost, nil
+}
+
+func salt(size int) ([]byte, error) {
+	s := make([]byte, size)
+	if _, err := rand.Read(s); err != nil {
+		return nil, errors.Wrap(err, "Could not read random salt")
+	}
+	return s, nil
+}
+
+type bcryptHasher struct {
+	cost int
+}
+
+// Hash refuses passwords above 72 bytes instead of silently truncating them
+func (h bcryptHasher) Hash(password string) (string, error) {
+	if len(password) > BcryptMaxLength {
+		return "", errors.Wrapf(ErrTooLong, "bcrypt only uses %d bytes, got %d", BcryptMaxLength, len(password))
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
+	if err != nil {
+		return "", errors.Wrap(err, "Could not hash password with bcrypt")
+	}
+	return string(hash), nil
+}
+
+type scryptHasher struct {
+	logN int
+}
+
+func (h scryptHasher) Hash(password string) (string, error) {
+	s, err := salt(saltSize)
+	if err != nil {
+		return "", err
+	}
+	key, err := scrypt.Key([]byte(password), s, 1<<uint(h.logN), scryptR, scryptP, keySize)
+	if err != nil {
+		return "", errors.Wrap(err, "Could not hash password with scrypt")
+	}
+	return fmt.Sprintf("$scrypt$ln=%d,r=%d,p=%d$%s$%s", h.logN, scryptR, scryptP, b64.EncodeToString(s), b64.EncodeToString(key)), nil
+}
+
+type argon2Hasher struct {
+	passes int
+}
+
+func (h argon2Hasher) Hash(password string) (string, error) {
+	s, err := salt(saltSize)
+	if err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), s, uint32(h.passes), argon2Memory, argon2Threads, keySize)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2Version, argon2Memory, h.passes, argon2Threads, b64.EncodeToString(s), b64.EncodeToString(key)), nil
+}
+
+type pbkdf2Hasher struct {
+	iterations int
+}
+
+func (h pbkdf2Hasher) Hash(password string) (string, error) {
+	s, err := salt(saltSize)
+	if err != nil {
+		return "", err
+	}
+	key := pbkdf2.Key([]byte(password), s, h.iterations, keySize, sha256.New)
+	return fmt.Sprintf("$pbkdf2-sha256$i=%d,l=%d$%s$%s", h.iterations, keySize, b64.EncodeToString(s), b64.EncodeToString(key)), nil
+}
@@ -0,0 +1,128 @@
+package passhash
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+)
+
+func TestNew(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		algorithm   string
+		cost        int
+		expected    Hasher
+		expectedErr error
+	}{
+		{desc: "bcrypt default", algorithm: Bcrypt, expected: bcryptHasher{12}},
+		{desc: "bcrypt cost 10", algorithm: Bcrypt, cost: 10, expected: bcryptHasher{10}},
+		{desc: "bcrypt maximum cost", algorithm: Bcrypt, cost: 14, expected: bcryptHasher{14}},
+		{desc: "bcrypt cost too high", algorithm: Bcrypt, cost: 15, expectedErr: ErrCost},
+		{desc: "scrypt default", algorithm: Scrypt, expected: scryptHasher{15}},
+		{desc: "scrypt cost too low", algorithm: Scrypt, cost: 1, expectedErr: ErrCost},
+		{desc: "scrypt maximum cost", algorithm: Scrypt, cost: 17, expected: scryptHasher{17}},
+		{desc: "scrypt cost too high", algorithm: Scrypt, cost: 18, expectedErr: ErrCost},
+		{desc: "argon2id default", algorithm: Argon2id, expected: argon2Hasher{3}},
+		{desc: "pbkdf2-sha256 default", algorithm: PBKDF2SHA256, expected: pbkdf2Hasher{600000}},
+		{desc: "pbkdf2-sha256 too few iterations", algorithm: PBKDF2SHA256, cost: 1000, expectedErr: ErrCost},
+		{desc: "pbkdf2-sha256 too many iterations", algorithm: PBKDF2SHA256, cost: 2000001, expectedErr: ErrCost},
+		{desc: "sha512-crypt default", algorithm: SHA512Crypt, expected: sha512CryptHasher{5000}},
+		{desc: "sha512-crypt too few rounds", algorithm: SHA512Crypt, cost: 999, expectedErr: ErrCost},
+		{desc: "sha512-crypt too many rounds", algorithm: SHA512Crypt, cost: 500001, expectedErr: ErrCost},
+		{desc: "apr1", algorithm: APR1, expected: apr1Hasher{}},
+		{desc: "apr1 with cost", algorithm: APR1, cost: 1000, expectedErr: ErrCost},
+		{desc: "ssha", algorithm: SSHA, expected: ldapHasher{"{SSHA}"}},
+		{desc: "ssha512", algorithm: SSHA512, expected: ldapHasher{"{SSHA512}"}},
+		{desc: "scram-sha-256 default", algorithm: ScramSHA256, expected: scramHasher{4096}},
+		{desc: "scram-sha-256 too few iterations", algorithm: ScramSHA256, cost: 1000, expectedErr: ErrCost},
+		{desc: "scram-sha-256 too many iterations", algorithm: ScramSHA256, cost: 2000001, expectedErr: ErrCost},
+		{desc: "mysql_native_password", algorithm: MySQLNative, expected: mysqlNativeHasher{}},
+		{desc: "caching_sha2_password", algorithm: CachingSHA2, expected: cachingSHA2Hasher{}},
+		{desc: "caching_sha2_password with cost", algorithm: CachingSHA2, cost: 10, expectedErr: ErrCost},
+		{desc: "unknown algorithm", algorithm: "md5", expectedErr: ErrUnknownAlgorithm},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// when
+			h, err := New(tC.algorithm, tC.cost)
+
+			// then
+			assert.Equal(t, tC.expectedErr, errors.Cause(err))
+			assert.Equal(t, tC.expected, h)
+		})
+	}
+}
+
+func TestBcryptHasher_Hash(t *testing.T) {
+	// when
+	hash, err := bcryptHasher{bcryptMinCost}.Hash("correct horse")
+
+	// then
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$2a$10$"))
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(hash), []byte("correct horse")))
+}
+
+func TestBcryptHasher_Hash_TooLong(t *testing.T) {
+	// when
+	_, fits := bcryptHasher{bcryptMinCost}.Hash(strings.Repeat("a", 72))
+	_, tooLong := bcryptHasher{bcryptMinCost}.Hash(strings.Repeat("a", 73))
+
+	// then
+	assert.NoError(t, fits)
+	assert.Equal(t, ErrTooLong, errors.Cause(tooLong))
+}
+
+func TestScryptHasher_Hash(t *testing.T) {
+	// when
+	hash, err := scryptHasher{scryptMinLogN}.Hash("correct horse")
+
+	// then
+	assert.NoError(t, err)
+	params, salt, key := splitPHC(t, hash, "scrypt")
+	assert.Equal(t, "ln=14,r=8,p=1", params)
+	expected, _ := scrypt.Key([]byte("correct horse"), salt, 1<<14, 8, 1, 32)
+	assert.Equal(t, expected, key)
+}
+
+func TestArgon2Hasher_Hash(t *testing.T) {
+	// when
+	hash, err := argon2Hasher{1}.Hash("correct horse")
+
+	// then
+	assert.NoError(t, err)
+	params, salt, key := splitPHC(t, hash, "argon2id")
+	assert.Equal(t, "v=19$m=65536,t=1,p=4", params)
+	assert.Equal(t, argon2.IDKey([]byte("correct horse"), salt, 1, 64*1024, 4, 32), key)
+}
+
+func TestPBKDF2Hasher_Hash(t *testing.T) {
+	// when
+	hash, err := pbkdf2Hasher{pbkdf2MinIterations}.Hash("correct horse")
+
+	// then
+	assert.NoError(t, err)
+	params, salt, key := splitPHC(t, hash, "pbkdf2-sha256")
+	assert.Equal(t, "i=100000,l=32", params)
+	assert.Equal(t, pbkdf2.Key([]byte("correct horse"), salt, 100000, 32, sha256.New), key)
+}
+
+// Split a PHC string into its parameters, salt and hash
+func splitPHC(t *testing.T, hash, id string) (string, []byte, []byte) {
+	prefix := fmt.Sprintf("$%s$", id)
+	assert.True(t, strings.HasPrefix(hash, prefix))
+	parts := strings.Split(strings.TrimPrefix(hash, prefix), "$")
+	salt, err := b64.DecodeString(parts[len(parts)-2])
+	assert.NoError(t, err)
+	key, err := b64.DecodeString(parts[len(parts)-1])
+	assert.NoError(t, err)
+	return strings.Join(parts[:len(parts)-2], "$"), salt, key
+}
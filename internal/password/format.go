@@ -0,0 +1,53 @@
+package password
+
+import (
+	"strings"
+)
+
+// DefaultSeparator is used between groups when no other separator is requested.
+const DefaultSeparator = "-"
+
+// Group formats a password for easier transcription by splitting it into chunks of
+// size characters joined by separator, e.g. "abcdefghi" becomes "abc-def-ghi".
+// A size of zero or below leaves the password untouched.
+func Group(password string, size int, separator string) string {
+	if size <= 0 || len(password) <= size {
+		return password
+	}
+	var b strings.Builder
+	b.Grow(GroupedLength(len(password), size, separator))
+	for i := 0; i < len(password); i += size {
+		if i > 0 {
+			b.WriteString(separator)
+		}
+		end := i + size
+		if end > len(password) {
+			end = len(password)
+		}
+		b.WriteString(password[i:end])
+	}
+	return b.String()
+}
+
+// UngroupedLength returns the smallest password length which reaches at least
+// the given length once it is grouped with size and separator.
+// It is used when separators should count towards the minimum length of a password.
+func UngroupedLength(length, size int, separator string) int {
+	if length <= 0 || size <= 0 || separator == "" {
+		return length
+	}
+	// Start at a lower bound and walk up to the exact length, this takes at most a few steps
+	ungrouped := length * size / (size + len(separator))
+	for GroupedLength(ungrouped, size, separator) < length {
+		ungrouped++
+	}
+	return ungrouped
+}
+
+// GroupedLength returns the length of a password with the given length after grouping it.
+func GroupedLength(length, size int, separator string) int {
+	if length <= 0 || size <= 0 {
+		return length
+	}
+	return length + (length-1)/size*len(separator)
+}
@@ -0,0 +1,108 @@
+package password
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroup(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		password  string
+		size      int
+		separator string
+		expected  string
+	}{
+		{
+			desc:     "No grouping",
+			password: "abcdefghi", size: 0, separator: "-",
+			expected: "abcdefghi",
+		},
+		{
+			desc:     "Even groups",
+			password: "abcdefghi", size: 3, separator: "-",
+			expected: "abc-def-ghi",
+		},
+		{
+			desc:     "Uneven groups",
+			password: "abcdefgh", size: 3, separator: "-",
+			expected: "abc-def-gh",
+		},
+		{
+			desc:     "Multi character separator",
+			password: "abcdef", size: 2, separator: "::",
+			expected: "ab::cd::ef",
+		},
+		{
+			desc:     "Password shorter than group",
+			password: "ab", size: 6, separator: "-",
+			expected: "ab",
+		},
+		{
+			desc:     "Empty password",
+			password: "", size: 6, separator: "-",
+			expected: "",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// when
+			grouped := Group(tC.password, tC.size, tC.separator)
+
+			// then
+			assert.Equal(t, tC.expected, grouped)
+		})
+	}
+}
+
+func TestUngroupedLength(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		length    int
+		size      int
+		separator string
+		expected  int
+	}{
+		{
+			desc:   "No grouping",
+			length: 10, size: 0, separator: "-",
+			expected: 10,
+		},
+		{
+			desc:   "No separator",
+			length: 10, size: 3, separator: "",
+			expected: 10,
+		},
+		{
+			desc:   "Apple style password",
+			length: 20, size: 6, separator: "-",
+			expected: 18,
+		},
+		{
+			desc:   "Length ending on a separator",
+			length: 4, size: 3, separator: "-",
+			expected: 4,
+		},
+		{
+			desc:   "Multi character separator",
+			length: 10, size: 2, separator: "::",
+			expected: 6,
+		},
+		{
+			desc:   "Zero length",
+			length: 0, size: 6, separator: "-",
+			expected: 0,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// when
+			length := UngroupedLength(tC.length, tC.size, tC.separator)
+
+			// then the grouped password reaches the requested length with the shortest possible password
+			assert.Equal(t, tC.expected, length)
+			assert.True(t, GroupedLength(length, tC.size, tC.separator) >= tC.length)
+		})
+	}
+}
@@ -3,13 +3,15 @@ package password
 
 import (
 	"bytes"
+	"strings"
 )
 
 // Generator can generate passwords with a given configuration
 // passed via functional Options in its constructor.
 type Generator struct {
-	minLength, specialChars, nums int
-	swap                          bool
+	minLength, maxLength, specialChars, nums int
+	swap                                     bool
+	specialCharSet, exclude                  string
 }
 
 // Option is the functional option type to allow variadic and
@@ -19,17 +21,52 @@ type Option func(*Generator)
 // The sets of letters used to generate our passwords
 const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
 const numbers = "0123456789"
-const specialChars = " !\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~"
+const specialChars = " !\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~"
 const vowels, vowelNums = "aAeEiIoO", "4310" // Character sets used for the vowel swap feature, uU does not have a number and is therefore missing"
 
 // NewGenerator will create a Generator which can generate passwords.
 // A number of Options can be passed to configure the resulting Generator.
-func NewGenerator(options ...Option) Generator {
+// A FieldError is returned if the Options are negative or contradict each other.
+func NewGenerator(options ...Option) (Generator, error) {
 	g := Generator{}
 	for i := range options {
 		options[i](&g)
 	}
-	return g
+	if err := g.validate(); err != nil {
+		return Generator{}, err
+	}
+	return g, nil
+}
+
+// validate rejects configurations which cannot be generated, like character sets emptied by exclusions
+func (g Generator) validate() error {
+	for _, v := range []struct {
+		field string
+		value int
+	}{{"minLength", g.minLength}, {"maxLength", g.maxLength}, {"specialChars", g.specialChars}, {"numbers", g.nums}} {
+		if v.value < 0 {
+			return FieldError{v.field, "must not be negative"}
+		}
+	}
+	if g.maxLength > 0 && g.maxLength < g.minLength {
+		return FieldError{"maxLength", "must not be less than minLength"}
+	}
+	if g.maxLength > 0 && g.maxLength < g.specialChars+g.nums {
+		return FieldError{"maxLength", "must leave room for specialChars and numbers"}
+	}
+	if g.specialChars > 0 && g.allowed(g.specialSet()) == "" {
+		return FieldError{"exclude", "must not exclude every special character"}
+	}
+	if g.nums > 0 && g.allowed(numbers) == "" {
+		return FieldError{"exclude", "must not exclude every number"}
+	}
+	if (g.minLength > g.specialChars+g.nums || g.maxLength > g.specialChars+g.nums) && g.allowed(letters) == "" {
+		return FieldError{"exclude", "must not exclude every letter"}
+	}
+	if strings.ContainsAny(g.specialCharSet, letters+numbers) {
+		return FieldError{"specialCharSet", "must not contain letters or numbers"}
+	}
+	return nil
 }
 
 // MinLength configures a minimum length for generated passwords.
@@ -39,6 +76,14 @@ func MinLength(length int) Option {
 	}
 }
 
+// MaxLength configures a maximum length, passwords get a random length between minimum and maximum.
+// It must not be below the minimum length, zero disables it.
+func MaxLength(length int) Option {
+	return func(g *Generator) {
+		g.maxLength = length
+	}
+}
+
 // SpecialChars configures the exact amount of special characters in generated passwords.
 func SpecialChars(amount int) Option {
 	return func(g *Generator) {
@@ -60,6 +105,20 @@ func Swap(shouldSwap bool) Option {
 	}
 }
 
+// SpecialCharSet configures the special characters to choose from instead of all printable ASCII special characters.
+func SpecialCharSet(set string) Option {
+	return func(g *Generator) {
+		g.specialCharSet = set
+	}
+}
+
+// Exclude configures characters which must not appear in generated passwords.
+func Exclude(chars string) Option {
+	return func(g *Generator) {
+		g.exclude = chars
+	}
+}
+
 // Password generates a password with the generators' configuration
 func (g Generator) Password() string {
 	var passwordBytes []byte
@@ -74,14 +133,43 @@ func (g Generator) Password() string {
 }
 
 func (g Generator) generate(pw []byte) []byte {
-	pw = append(pw, randomBytes(numbers, g.nums)...)
-	pw = append(pw, randomBytes(specialChars, g.specialChars)...)
-	if g.minLength > len(pw) {
-		pw = append(pw, randomBytes(letters, g.minLength-len(pw))...)
+	pw = append(pw, randomBytes(g.allowed(numbers), g.nums)...)
+	pw = append(pw, randomBytes(g.allowed(g.specialSet()), g.specialChars)...)
+	if length := g.length(); length > len(pw) {
+		pw = append(pw, randomBytes(g.allowed(letters), length-len(pw))...)
 	}
 	return pw
 }
 
+// specialSet returns the special characters to choose from
+func (g Generator) specialSet() string {
+	if g.specialCharSet != "" {
+		return g.specialCharSet
+	}
+	return specialChars
+}
+
+// length picks the length of the next password from the configured range
+func (g Generator) length() int {
+	if g.maxLength <= g.minLength {
+		return g.minLength
+	}
+	return g.minLength + random.Intn(g.maxLength-g.minLength+1)
+}
+
+// allowed removes the excluded characters from a character set
+func (g Generator) allowed(set string) string {
+	if g.exclude == "" {
+		return set
+	}
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(g.exclude, r) {
+			return -1
+		}
+		return r
+	}, set)
+}
+
 func (g Generator) shuffle(passwordBytes []byte) []byte {
 	var password = make([]byte, len(passwordBytes))
 	for i, v := range random.Perm(len(passwordBytes)) {
@@ -97,7 +185,10 @@ func (g Generator) shuffle(passwordBytes []byte) []byte {
 func (g Generator) swapVowel(char byte) byte {
 	index := bytes.IndexByte([]byte(vowels), char)
 	if index > 0 && random.Intn(2) == 1 {
-		return vowelNums[index/2] // map index of vowel to index of vowelNums
+		num := vowelNums[index/2] // map index of vowel to index of vowelNums
+		if strings.IndexByte(g.exclude, num) < 0 {
+			return num
+		}
 	}
 	return char
 }
@@ -9,9 +9,10 @@ import (
 
 func TestNewGenerator(t *testing.T) {
 	testCases := []struct {
-		desc     string
-		options  []Option
-		expected Generator
+		desc          string
+		options       []Option
+		expected      Generator
+		expectedField string
 	}{
 		{
 			desc:     "Generator without configuration",
@@ -43,13 +44,43 @@ func TestNewGenerator(t *testing.T) {
 			options:  []Option{SpecialChars(4), SpecialChars(5)},
 			expected: Generator{specialChars: 5},
 		},
+		{
+			desc:     "Generator excluding quotes",
+			options:  []Option{Exclude(`'"`)},
+			expected: Generator{exclude: `'"`},
+		},
+		{
+			desc:          "Negative min length",
+			options:       []Option{MinLength(-1)},
+			expectedField: "minLength",
+		},
+		{
+			desc:          "Negative numbers",
+			options:       []Option{Numbers(-3)},
+			expectedField: "numbers",
+		},
+		{
+			desc:          "Max length below min length",
+			options:       []Option{MinLength(8), MaxLength(4)},
+			expectedField: "maxLength",
+		},
+		{
+			desc:          "Letters needed for the max length, but all excluded",
+			options:       []Option{MinLength(2), MaxLength(4), Numbers(2), Exclude(letters)},
+			expectedField: "exclude",
+		},
 	}
 	for _, tC := range testCases {
 		t.Run(tC.desc, func(t *testing.T) {
 			// when
-			generator := NewGenerator(tC.options...)
+			generator, err := NewGenerator(tC.options...)
 
 			// then
+			if tC.expectedField != "" {
+				assert.Equal(t, tC.expectedField, err.(FieldError).Field)
+				return
+			}
+			assert.NoError(t, err)
 			assert.Equal(t, tC.expected, generator)
 		})
 	}
@@ -135,3 +166,42 @@ func countAny(s, chars string) int {
 	}
 	return count
 }
+
+func TestPassword_Exclude(t *testing.T) {
+	// given a generator which may only use a single special character
+	exclude := strings.Replace(specialChars, "!", "", 1) + "aeiouAEIOU"
+	generator, _ := NewGenerator(MinLength(40), SpecialChars(20), Numbers(10), Swap(true), Exclude(exclude))
+
+	// when
+	password := generator.Password()
+
+	// then
+	assert.Len(t, password, 40)
+	assert.False(t, strings.ContainsAny(password, exclude))
+	assert.Equal(t, 20, strings.Count(password, "!"))
+}
+
+func TestPassword_MaxLength(t *testing.T) {
+	// given
+	generator, _ := NewGenerator(MinLength(8), MaxLength(10))
+
+	// when
+	lengths := map[int]bool{}
+	for i := 0; i < 200; i++ {
+		lengths[len(generator.Password())] = true
+	}
+
+	// then every length of the range is used
+	assert.Equal(t, map[int]bool{8: true, 9: true, 10: true}, lengths)
+}
+
+func TestPassword_SpecialCharSet(t *testing.T) {
+	// given
+	generator, _ := NewGenerator(MinLength(20), SpecialChars(20), SpecialCharSet("-_"))
+
+	// when
+	password := generator.Password()
+
+	// then
+	assert.Equal(t, "", strings.Trim(password, "-_"))
+}
@@ -0,0 +1,80 @@
+package password
+
+import (
+	"math"
+)
+
+// Policy holds the configuration of a Generator as plain values,
+// so it can be passed around and compared before creating a Generator.
+type Policy struct {
+	MinLength, MaxLength, SpecialChars, Numbers int
+	Swap                                        bool
+	// SpecialCharSet replaces the default special characters if set
+	SpecialCharSet string
+	// Exclude lists characters which must not appear in passwords
+	Exclude string
+}
+
+// Options returns the Options to create a Generator with this policy.
+func (p Policy) Options() []Option {
+	return []Option{
+		MinLength(p.MinLength),
+		MaxLength(p.MaxLength),
+		SpecialChars(p.SpecialChars),
+		Numbers(p.Numbers),
+		Swap(p.Swap),
+		SpecialCharSet(p.SpecialCharSet),
+		Exclude(p.Exclude),
+	}
+}
+
+// FieldError names the field of a Policy which is invalid, using the names of the request parameters
+type FieldError struct {
+	Field, Message string
+}
+
+func (e FieldError) Error() string {
+	return e.Field + " " + e.Message
+}
+
+// Validate returns a FieldError if the settings of the policy are negative or contradict each other.
+func (p Policy) Validate() error {
+	_, err := NewGenerator(p.Options()...)
+	return err
+}
+
+// Entropy estimates the bits of entropy of a password with the given length generated with this policy.
+// It counts the choices for every character and the arrangements of numbers, special characters and letters.
+// Swapped vowels are not counted, so the estimate errs on the low side.
+func (p Policy) Entropy(length int) float64 {
+	g := Generator{specialCharSet: p.SpecialCharSet, exclude: p.Exclude}
+	set := g.specialSet()
+	nums, specials := p.Numbers, p.SpecialChars
+	chars := length - nums - specials
+	if chars < 0 {
+		chars = 0
+	}
+	// log2 of the multinomial coefficient length! / (nums! * specials! * chars!)
+	arrangements := (lgamma(length+1) - lgamma(nums+1) - lgamma(specials+1) - lgamma(chars+1)) / math.Ln2
+	return arrangements +
+		float64(nums)*bits(g.allowed(numbers)) +
+		float64(specials)*bits(g.allowed(set)) +
+		float64(chars)*bits(g.allowed(letters))
+}
+
+func lgamma(n int) float64 {
+	l, _ := math.Lgamma(float64(n))
+	return l
+}
+
+// bits returns the entropy of a character chosen uniformly from the set
+func bits(set string) float64 {
+	distinct := map[rune]bool{}
+	for _, r := range set {
+		distinct[r] = true
+	}
+	if len(distinct) == 0 {
+		return 0
+	}
+	return math.Log2(float64(len(distinct)))
+}
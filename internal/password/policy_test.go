@@ -0,0 +1,80 @@
+package password
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicy_Options(t *testing.T) {
+	// given
+	policy := Policy{MinLength: 12, MaxLength: 16, SpecialChars: 2, Numbers: 3, Swap: true, SpecialCharSet: "!?", Exclude: "'"}
+
+	// when
+	generator, err := NewGenerator(policy.Options()...)
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, Generator{minLength: 12, maxLength: 16, specialChars: 2, nums: 3, swap: true, specialCharSet: "!?", exclude: "'"}, generator)
+}
+
+func TestPolicy_Validate(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		policy        Policy
+		expectedField string
+	}{
+		{desc: "Empty policy", policy: Policy{}},
+		{desc: "Length range", policy: Policy{MinLength: 8, MaxLength: 12, SpecialChars: 4, Numbers: 4}},
+		{desc: "Maximum below minimum", policy: Policy{MinLength: 8, MaxLength: 6}, expectedField: "maxLength"},
+		{desc: "Maximum too short for the required characters", policy: Policy{MaxLength: 4, SpecialChars: 3, Numbers: 2}, expectedField: "maxLength"},
+		{desc: "Every special character excluded", policy: Policy{SpecialChars: 1, SpecialCharSet: "!?", Exclude: "?!"}, expectedField: "exclude"},
+		{desc: "Every number excluded", policy: Policy{Numbers: 1, Exclude: numbers}, expectedField: "exclude"},
+		{desc: "Every letter excluded", policy: Policy{MinLength: 3, Numbers: 2, Exclude: letters}, expectedField: "exclude"},
+		{desc: "Every letter excluded but none needed", policy: Policy{MinLength: 2, Numbers: 2, Exclude: letters}},
+		{desc: "Letters in special characters", policy: Policy{SpecialCharSet: "!a"}, expectedField: "specialCharSet"},
+		{desc: "Negative special characters", policy: Policy{SpecialChars: -1}, expectedField: "specialChars"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// when
+			err := tC.policy.Validate()
+
+			// then
+			if tC.expectedField == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.IsType(t, FieldError{}, err)
+			assert.Equal(t, tC.expectedField, err.(FieldError).Field)
+		})
+	}
+}
+
+func TestPolicy_Entropy(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		policy   Policy
+		length   int
+		expected float64
+	}{
+		{desc: "Letters only", policy: Policy{}, length: 10, expected: 10 * math.Log2(52)},
+		{desc: "Numbers only", policy: Policy{Numbers: 4}, length: 4, expected: 4 * math.Log2(10)},
+		// 3!/(1!*1!*1!) = 6 arrangements of one number, one special character and one letter
+		{desc: "Mixed", policy: Policy{Numbers: 1, SpecialChars: 1}, length: 3, expected: math.Log2(6) + math.Log2(10) + math.Log2(33) + math.Log2(52)},
+		{desc: "Special character set", policy: Policy{SpecialChars: 2, SpecialCharSet: "!?"}, length: 2, expected: 2},
+		{desc: "Duplicate special characters", policy: Policy{SpecialChars: 1, SpecialCharSet: "!!?"}, length: 1, expected: 1},
+		{desc: "Excluded characters", policy: Policy{Numbers: 1, Exclude: "01"}, length: 1, expected: 3},
+		{desc: "Empty password", policy: Policy{}, length: 0, expected: 0},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// when
+			entropy := tC.policy.Entropy(tC.length)
+
+			// then
+			assert.InDelta(t, tC.expected, entropy, 1e-9)
+		})
+	}
+}
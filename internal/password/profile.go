@@ -0,0 +1,68 @@
+package password
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Profile describes constraints that consumers of passwords put on them,
+// e.g. the rules for Wi-Fi pre-shared keys.
+type Profile struct {
+	Name                 string
+	MinLength, MaxLength int
+	// PrintableASCII restricts passwords to the characters from space to tilde
+	PrintableASCII bool
+	// Exclude lists characters the consumer mishandles, they are not generated
+	Exclude string
+}
+
+// WiFi enforces the rules for WPA2 pre-shared key passphrases, which are 8 to 63 printable ASCII characters.
+var WiFi = Profile{Name: "wifi", MinLength: 8, MaxLength: 63, PrintableASCII: true}
+
+// Database avoids quotes and backslashes, which break SQL literals and shell invocations of database clients
+// like psql and mysql.
+var Database = Profile{Name: "db", Exclude: "'\"`\\"}
+
+var profiles = map[string]Profile{
+	WiFi.Name:     WiFi,
+	Database.Name: Database,
+}
+
+// LookupProfile returns the profile with the given name.
+func LookupProfile(name string) (Profile, bool) {
+	p, ok := profiles[name]
+	return p, ok
+}
+
+// ProfileNames returns the names of all profiles in alphabetical order.
+func ProfileNames() []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Validate returns an error if the password violates the profile.
+func (p Profile) Validate(password string) error {
+	if len(password) < p.MinLength {
+		return errors.Errorf("password is shorter than %d characters required by profile %s", p.MinLength, p.Name)
+	}
+	if p.MaxLength > 0 && len(password) > p.MaxLength {
+		return errors.Errorf("password is longer than %d characters allowed by profile %s", p.MaxLength, p.Name)
+	}
+	if p.PrintableASCII {
+		for i := 0; i < len(password); i++ {
+			if password[i] < ' ' || password[i] > '~' {
+				return errors.Errorf("password contains characters other than printable ASCII, which profile %s does not allow", p.Name)
+			}
+		}
+	}
+	if strings.ContainsAny(password, p.Exclude) {
+		return errors.Errorf("password contains one of the characters %s, which profile %s does not allow", p.Exclude, p.Name)
+	}
+	return nil
+}
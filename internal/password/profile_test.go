@@ -0,0 +1,87 @@
+package password
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProfile_Validate(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		profile  Profile
+		password string
+		valid    bool
+	}{
+		{
+			desc:    "Wi-Fi minimum length",
+			profile: WiFi, password: "abcdefgh",
+			valid: true,
+		},
+		{
+			desc:    "Wi-Fi maximum length",
+			profile: WiFi, password: strings.Repeat("a", 63),
+			valid: true,
+		},
+		{
+			desc:    "Wi-Fi too short",
+			profile: WiFi, password: "abcdefg",
+			valid: false,
+		},
+		{
+			desc:    "Wi-Fi too long",
+			profile: WiFi, password: strings.Repeat("a", 64),
+			valid: false,
+		},
+		{
+			desc:    "Wi-Fi with all special characters",
+			profile: WiFi, password: specialChars,
+			valid: true,
+		},
+		{
+			desc:    "Wi-Fi with non ASCII character",
+			profile: WiFi, password: "abcdefgh€",
+			valid: false,
+		},
+		{
+			desc:    "Database without quotes",
+			profile: Database, password: "a!b#c$d%",
+			valid: true,
+		},
+		{
+			desc:    "Database with single quote",
+			profile: Database, password: "a'b",
+			valid: false,
+		},
+		{
+			desc:    "Database with backslash",
+			profile: Database, password: `a\b`,
+			valid: false,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// when
+			err := tC.profile.Validate(tC.password)
+
+			// then
+			assert.Equal(t, tC.valid, err == nil)
+		})
+	}
+}
+
+func TestLookupProfile(t *testing.T) {
+	// when
+	wifi, wifiOk := LookupProfile("wifi")
+	_, unknownOk := LookupProfile("unknown")
+
+	// then
+	assert.True(t, wifiOk)
+	assert.Equal(t, WiFi, wifi)
+	assert.False(t, unknownOk)
+}
+
+func TestProfileNames(t *testing.T) {
+	assert.Equal(t, []string{"db", "wifi"}, ProfileNames())
+}
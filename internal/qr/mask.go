@@ -0,0 +1,158 @@
+package qr
+
+// Penalty weights for the mask evaluation as defined by the QR code specification
+const (
+	penaltyRun     = 3
+	penaltyBlock   = 3
+	penaltyFinder  = 40
+	penaltyBalance = 10
+)
+
+// Try all masks and keep the one with the lowest penalty
+func (c *Code) applyBestMask() {
+	best, minPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		c.applyMask(mask)
+		c.drawFormatBits(mask)
+		penalty := c.penalty()
+		if minPenalty < 0 || penalty < minPenalty {
+			best, minPenalty = mask, penalty
+		}
+		// Masks are XOR based, so applying a mask twice undoes it
+		c.applyMask(mask)
+	}
+	c.applyMask(best)
+	c.drawFormatBits(best)
+}
+
+func (c *Code) applyMask(mask int) {
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if !c.function[y][x] && masked(mask, x, y) {
+				c.modules[y][x] = !c.modules[y][x]
+			}
+		}
+	}
+}
+
+func masked(mask, x, y int) bool {
+	switch mask {
+	case 0:
+		return (x+y)%2 == 0
+	case 1:
+		return y%2 == 0
+	case 2:
+		return x%3 == 0
+	case 3:
+		return (x+y)%3 == 0
+	case 4:
+		return (x/3+y/2)%2 == 0
+	case 5:
+		return x*y%2+x*y%3 == 0
+	case 6:
+		return (x*y%2+x*y%3)%2 == 0
+	default:
+		return ((x+y)%2+x*y%3)%2 == 0
+	}
+}
+
+func (c *Code) penalty() int {
+	result := 0
+
+	// Runs of the same color and finder like patterns in rows and columns
+	for y := 0; y < c.Size; y++ {
+		result += c.linePenalty(func(i int) bool { return c.modules[y][i] })
+	}
+	for x := 0; x < c.Size; x++ {
+		result += c.linePenalty(func(i int) bool { return c.modules[i][x] })
+	}
+
+	// 2x2 blocks of the same color
+	for y := 0; y < c.Size-1; y++ {
+		for x := 0; x < c.Size-1; x++ {
+			color := c.modules[y][x]
+			if color == c.modules[y][x+1] && color == c.modules[y+1][x] && color == c.modules[y+1][x+1] {
+				result += penaltyBlock
+			}
+		}
+	}
+
+	// Balance of dark and light modules
+	dark := 0
+	for _, row := range c.modules {
+		for _, module := range row {
+			if module {
+				dark++
+			}
+		}
+	}
+	total := c.Size * c.Size
+	k := (abs(dark*20-total*10)+total-1)/total - 1
+	result += k * penaltyBalance
+	return result
+}
+
+func (c *Code) linePenalty(module func(i int) bool) int {
+	result := 0
+	runColor := false
+	runLength := 0
+	h := runHistory{size: c.Size}
+	for i := 0; i < c.Size; i++ {
+		if module(i) == runColor {
+			runLength++
+			if runLength == 5 {
+				result += penaltyRun
+			} else if runLength > 5 {
+				result++
+			}
+			continue
+		}
+		h.add(runLength)
+		if !runColor {
+			result += h.countFinderPatterns() * penaltyFinder
+		}
+		runColor = module(i)
+		runLength = 1
+	}
+	return result + h.terminate(runColor, runLength)*penaltyFinder
+}
+
+// runHistory keeps the lengths of the last seven runs to detect finder like patterns
+type runHistory struct {
+	runs [7]int
+	size int
+}
+
+func (h *runHistory) add(length int) {
+	// The light quiet zone extends the first run
+	if h.runs[0] == 0 {
+		length += h.size
+	}
+	copy(h.runs[1:], h.runs[:6])
+	h.runs[0] = length
+}
+
+// Count dark-light-dark-dark-dark-light-dark patterns with light space of four modules on either side
+func (h *runHistory) countFinderPatterns() int {
+	r := h.runs
+	n := r[1]
+	core := n > 0 && r[2] == n && r[3] == n*3 && r[4] == n && r[5] == n
+	count := 0
+	if core && r[0] >= n*4 && r[6] >= n {
+		count++
+	}
+	if core && r[6] >= n*4 && r[0] >= n {
+		count++
+	}
+	return count
+}
+
+// Finish the line with the light quiet zone and count remaining finder like patterns
+func (h *runHistory) terminate(runColor bool, runLength int) int {
+	if runColor {
+		h.add(runLength)
+		runLength = 0
+	}
+	h.add(runLength + h.size)
+	return h.countFinderPatterns()
+}
@@ -0,0 +1,122 @@
+package qr
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncode_Version(t *testing.T) {
+	testCases := []struct {
+		desc         string
+		length       int
+		level        Level
+		expectedSize int
+	}{
+		{
+			desc:   "Smallest code",
+			length: 1, level: Medium,
+			expectedSize: 21,
+		},
+		{
+			desc:   "Full version 1 with low error correction",
+			length: 17, level: Low,
+			expectedSize: 21,
+		},
+		{
+			desc:   "Version 2 after version 1 is full",
+			length: 18, level: Low,
+			expectedSize: 25,
+		},
+		{
+			desc:   "Version 7 with version information",
+			length: 110, level: Medium,
+			expectedSize: 45,
+		},
+		{
+			desc:   "Largest code",
+			length: 2953, level: Low,
+			expectedSize: 177,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// when
+			c, err := Encode(bytes.Repeat([]byte("a"), tC.length), tC.level)
+
+			// then
+			assert.NoError(t, err)
+			assert.Equal(t, tC.expectedSize, c.Size)
+		})
+	}
+}
+
+func TestEncode_TooLong(t *testing.T) {
+	// when
+	_, err := Encode(bytes.Repeat([]byte("a"), 2954), Low)
+
+	// then
+	assert.Equal(t, ErrTooLong, errors.Cause(err))
+}
+
+func TestEncode_FunctionPatterns(t *testing.T) {
+	// when
+	c, err := Encode([]byte("WIFI:T:WPA;S:guest;P:secret;;"), Medium)
+
+	// then
+	assert.NoError(t, err)
+	for _, corner := range [][2]int{{0, 0}, {c.Size - 7, 0}, {0, c.Size - 7}} {
+		// finder patterns have a dark border and center with a light ring in between
+		assert.True(t, c.Dark(corner[0], corner[1]))
+		assert.False(t, c.Dark(corner[0]+1, corner[1]+1))
+		assert.True(t, c.Dark(corner[0]+3, corner[1]+3))
+	}
+	// and the dark module is always set
+	assert.True(t, c.Dark(8, c.Size-8))
+	// and both copies of the format information match
+	for i := 0; i < 8; i++ {
+		assert.Equal(t, c.Dark(8, formatPositions[i]), c.Dark(c.Size-1-i, 8))
+	}
+}
+
+// Rows of the first format information copy in column 8, skipping the timing pattern
+var formatPositions = []int{0, 1, 2, 3, 4, 5, 7, 8}
+
+func TestReedSolomonDivisor(t *testing.T) {
+	// when
+	divisor := reedSolomonDivisor(7)
+
+	// then it matches the generator polynomial from the specification
+	assert.Equal(t, []byte{127, 122, 154, 164, 11, 68, 117}, divisor)
+}
+
+func TestCode_PNG(t *testing.T) {
+	// given
+	c, _ := Encode([]byte("secret"), Medium)
+
+	// when
+	b, err := c.PNG(2)
+
+	// then
+	assert.NoError(t, err)
+	img, err := png.Decode(bytes.NewReader(b))
+	assert.NoError(t, err)
+	assert.Equal(t, (c.Size+2*QuietZone)*2, img.Bounds().Dx())
+}
+
+func TestCode_SVG(t *testing.T) {
+	// given
+	c, _ := Encode([]byte("secret"), Medium)
+
+	// when
+	svg := string(c.SVG())
+
+	// then
+	assert.True(t, strings.HasPrefix(svg, "<svg"))
+	assert.Contains(t, svg, "viewBox=\"0 0 29 29\"")
+	assert.Contains(t, svg, "M4,4h1v1h-1z")
+}
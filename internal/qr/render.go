@@ -0,0 +1,55 @@
+package qr
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+// QuietZone is the number of light modules rendered around the code
+const QuietZone = 4
+
+// Image renders the code with the quiet zone, each module being scale pixels wide.
+func (c *Code) Image(scale int) image.Image {
+	if scale < 1 {
+		scale = 1
+	}
+	dim := (c.Size + 2*QuietZone) * scale
+	img := image.NewPaletted(image.Rect(0, 0, dim, dim), color.Palette{color.White, color.Black})
+	for py := 0; py < dim; py++ {
+		for px := 0; px < dim; px++ {
+			if c.Dark(px/scale-QuietZone, py/scale-QuietZone) {
+				img.SetColorIndex(px, py, 1)
+			}
+		}
+	}
+	return img
+}
+
+// PNG renders the code as a PNG image, each module being scale pixels wide.
+func (c *Code) PNG(scale int) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, c.Image(scale)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// SVG renders the code as a scalable SVG image with the quiet zone, measured in modules.
+func (c *Code) SVG() []byte {
+	dim := c.Size + 2*QuietZone
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" version="1.1" viewBox="0 0 %d %d" stroke="none">`, dim, dim)
+	buf.WriteString(`<rect width="100%" height="100%" fill="#FFFFFF"/><path d="`)
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if c.modules[y][x] {
+				fmt.Fprintf(&buf, "M%d,%dh1v1h-1z", x+QuietZone, y+QuietZone)
+			}
+		}
+	}
+	buf.WriteString(`" fill="#000000"/></svg>`)
+	return buf.Bytes()
+}
@@ -0,0 +1,35 @@
+package qr
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+// MaxSSIDLength is the maximum length of an SSID in bytes as defined by IEEE 802.11
+const MaxSSIDLength = 32
+
+// Characters which need to be escaped with a backslash inside of Wi-Fi payloads
+var wifiEscaper = strings.NewReplacer(`\`, `\\`, `;`, `\;`, `,`, `\,`, `:`, `\:`, `"`, `\"`)
+
+// WiFi builds the payload which lets phones join a WPA/WPA2 network by scanning the QR code.
+func WiFi(ssid, password string) string {
+	return "WIFI:T:WPA;S:" + wifiEscaper.Replace(ssid) + ";P:" + wifiEscaper.Replace(password) + ";;"
+}
+
+// ValidSSID checks whether phones accept the SSID, which must have 1 to 32 bytes without control characters
+func ValidSSID(ssid string) error {
+	if strings.TrimSpace(ssid) == "" {
+		return errors.New("SSID must not be empty")
+	}
+	if len(ssid) > MaxSSIDLength {
+		return errors.Errorf("SSID must be at most %d bytes long, got %d", MaxSSIDLength, len(ssid))
+	}
+	for _, r := range ssid {
+		if unicode.IsControl(r) {
+			return errors.Errorf("SSID must not contain control characters, got %q", r)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package qr
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWiFi(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		ssid     string
+		password string
+		expected string
+	}{
+		{
+			desc: "Plain ssid and password",
+			ssid: "guest", password: "secret12",
+			expected: "WIFI:T:WPA;S:guest;P:secret12;;",
+		},
+		{
+			desc: "Special characters are escaped",
+			ssid: "my;net", password: `a\b:c,d"e;`,
+			expected: `WIFI:T:WPA;S:my\;net;P:a\\b\:c\,d\"e\;;;`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// when
+			payload := WiFi(tC.ssid, tC.password)
+
+			// then
+			assert.Equal(t, tC.expected, payload)
+		})
+	}
+}
+
+func TestValidSSID(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		ssid        string
+		expectedErr bool
+	}{
+		{desc: "Plain", ssid: "guest"},
+		{desc: "Maximum length", ssid: strings.Repeat("a", 32)},
+		{desc: "Empty", ssid: "", expectedErr: true},
+		{desc: "Only spaces", ssid: "   ", expectedErr: true},
+		{desc: "Too long", ssid: strings.Repeat("a", 33), expectedErr: true},
+		{desc: "Too long in bytes", ssid: strings.Repeat("ä", 17), expectedErr: true},
+		{desc: "Line break", ssid: "guest\nnet", expectedErr: true},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// when
+			err := ValidSSID(tC.ssid)
+
+			// then
+			assert.Equal(t, tC.expectedErr, err != nil, err)
+		})
+	}
+}
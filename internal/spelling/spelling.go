@@ -0,0 +1,101 @@
+// Package spelling renders passwords in spelling alphabets so that they can be read aloud without misunderstandings.
+package spelling
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Alphabet maps characters to the words used to spell them aloud.
+// Letters are looked up case insensitive and prefixed with a case marker.
+type Alphabet struct {
+	// Letters contains the spelling words keyed by lower case letter
+	Letters map[rune]string
+	// Words contains the spelling words for everything else, e.g. digits and symbols
+	Words map[rune]string
+	// Upper and Lower mark the case of a spelled letter
+	Upper, Lower string
+}
+
+// Separator is put between the spelled characters of a password
+const Separator = ", "
+
+// Spell renders the password with the alphabet, e.g. "B7&" becomes "Capital Bravo, seven, Ampersand".
+// Characters unknown to the alphabet are kept as they are.
+func (a Alphabet) Spell(password string) string {
+	words := make([]string, 0, len(password))
+	for _, c := range password {
+		words = append(words, a.word(c))
+	}
+	return strings.Join(words, Separator)
+}
+
+func (a Alphabet) word(c rune) string {
+	if word, ok := a.Letters[unicode.ToLower(c)]; ok {
+		if unicode.IsUpper(c) {
+			return a.Upper + " " + word
+		}
+		return a.Lower + " " + strings.ToLower(word)
+	}
+	if word, ok := a.Words[c]; ok {
+		return word
+	}
+	return string(c)
+}
+
+// Lookup returns the alphabet for the given locale, e.g. "en" or "de".
+func Lookup(locale string) (Alphabet, bool) {
+	a, ok := alphabets[strings.ToLower(locale)]
+	return a, ok
+}
+
+var alphabets = map[string]Alphabet{
+	"en": English,
+	"de": German,
+}
+
+// English is the NATO phonetic alphabet with english names for digits and symbols.
+var English = Alphabet{
+	Letters: map[rune]string{
+		'a': "Alfa", 'b': "Bravo", 'c': "Charlie", 'd': "Delta", 'e': "Echo", 'f': "Foxtrot", 'g': "Golf",
+		'h': "Hotel", 'i': "India", 'j': "Juliett", 'k': "Kilo", 'l': "Lima", 'm': "Mike", 'n': "November",
+		'o': "Oscar", 'p': "Papa", 'q': "Quebec", 'r': "Romeo", 's': "Sierra", 't': "Tango", 'u': "Uniform",
+		'v': "Victor", 'w': "Whiskey", 'x': "X-ray", 'y': "Yankee", 'z': "Zulu",
+	},
+	Words: map[rune]string{
+		'0': "zero", '1': "one", '2': "two", '3': "three", '4': "four",
+		'5': "five", '6': "six", '7': "seven", '8': "eight", '9': "nine",
+		' ': "Space", '!': "Exclamation mark", '"': "Double quote", '#': "Hash", '$': "Dollar", '%': "Percent",
+		'&': "Ampersand", '\'': "Apostrophe", '(': "Left parenthesis", ')': "Right parenthesis", '*': "Asterisk",
+		'+': "Plus", ',': "Comma", '-': "Hyphen", '.': "Period", '/': "Slash", ':': "Colon", ';': "Semicolon",
+		'<': "Less than", '=': "Equals", '>': "Greater than", '?': "Question mark", '@': "At sign",
+		'[': "Left bracket", '\\': "Backslash", ']': "Right bracket", '^': "Caret", '_': "Underscore",
+		'`': "Backtick", '{': "Left brace", '|': "Vertical bar", '}': "Right brace", '~': "Tilde",
+	},
+	Upper: "Capital",
+	Lower: "lowercase",
+}
+
+// German is the german spelling alphabet as defined by DIN 5009 with german names for digits and symbols.
+var German = Alphabet{
+	Letters: map[rune]string{
+		'a': "Aachen", 'b': "Berlin", 'c': "Chemnitz", 'd': "Düsseldorf", 'e': "Essen", 'f': "Frankfurt",
+		'g': "Goslar", 'h': "Hamburg", 'i': "Ingelheim", 'j': "Jena", 'k': "Köln", 'l': "Leipzig", 'm': "München",
+		'n': "Nürnberg", 'o': "Offenbach", 'p': "Potsdam", 'q': "Quickborn", 'r': "Rostock", 's': "Salzwedel",
+		't': "Tübingen", 'u': "Unna", 'v': "Völklingen", 'w': "Wuppertal", 'x': "Xanten", 'y': "Ypsilon",
+		'z': "Zwickau",
+	},
+	Words: map[rune]string{
+		'0': "null", '1': "eins", '2': "zwo", '3': "drei", '4': "vier",
+		'5': "fünf", '6': "sechs", '7': "sieben", '8': "acht", '9': "neun",
+		' ': "Leerzeichen", '!': "Ausrufezeichen", '"': "Anführungszeichen", '#': "Raute", '$': "Dollar",
+		'%': "Prozent", '&': "Und-Zeichen", '\'': "Apostroph", '(': "Klammer auf", ')': "Klammer zu",
+		'*': "Stern", '+': "Plus", ',': "Komma", '-': "Bindestrich", '.': "Punkt", '/': "Schrägstrich",
+		':': "Doppelpunkt", ';': "Semikolon", '<': "Kleiner als", '=': "Gleich", '>': "Größer als",
+		'?': "Fragezeichen", '@': "At-Zeichen", '[': "Eckige Klammer auf", '\\': "Backslash",
+		']': "Eckige Klammer zu", '^': "Zirkumflex", '_': "Unterstrich", '`': "Gravis",
+		'{': "Geschweifte Klammer auf", '|': "Senkrechter Strich", '}': "Geschweifte Klammer zu", '~': "Tilde",
+	},
+	Upper: "Groß",
+	Lower: "klein",
+}
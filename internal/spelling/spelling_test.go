@@ -0,0 +1,82 @@
+package spelling
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAlphabet_Spell(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		alphabet Alphabet
+		password string
+		expected string
+	}{
+		{
+			desc:     "English upper case letter, digit and symbol",
+			alphabet: English,
+			password: "B7&",
+			expected: "Capital Bravo, seven, Ampersand",
+		},
+		{
+			desc:     "English lower case letters",
+			alphabet: English,
+			password: "dp",
+			expected: "lowercase delta, lowercase papa",
+		},
+		{
+			desc:     "German upper and lower case letters",
+			alphabet: German,
+			password: "Bd2",
+			expected: "Groß Berlin, klein düsseldorf, zwo",
+		},
+		{
+			desc:     "Unknown characters are kept",
+			alphabet: English,
+			password: "a€",
+			expected: "lowercase alfa, €",
+		},
+		{
+			desc:     "Empty password",
+			alphabet: English,
+			password: "",
+			expected: "",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// when
+			spelled := tC.alphabet.Spell(tC.password)
+
+			// then
+			assert.Equal(t, tC.expected, spelled)
+		})
+	}
+}
+
+func TestAlphabets_Complete(t *testing.T) {
+	// given every character our generator can produce
+	chars := "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789 !\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~"
+
+	for locale, alphabet := range alphabets {
+		for _, c := range chars {
+			// when
+			word := alphabet.word(c)
+
+			// then every character has a spelling word
+			assert.NotEqual(t, string(c), word, "%s alphabet has no word for %q", locale, c)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	// when
+	en, enOk := Lookup("EN")
+	_, xxOk := Lookup("xx")
+
+	// then
+	assert.True(t, enOk)
+	assert.Equal(t, English.Upper, en.Upper)
+	assert.False(t, xxOk)
+}
@@ -0,0 +1,75 @@
+package sshkey
+
+import (
+	"crypto/sha512"
+
+	"golang.org/x/crypto/blowfish"
+)
+
+// Output size of the bcrypt hash used by bcrypt_pbkdf
+const bcryptBlockSize = 32
+
+var bcryptMagic = []byte("OxychromaticBlowfishSwatDynamite")
+
+// bcryptPBKDF derives key material from a passphrase as OpenSSH does for encrypted private keys.
+// It is PBKDF2 with a bcrypt based hash function, see OpenBSD's bcrypt_pbkdf(3).
+func bcryptPBKDF(password, salt []byte, rounds, keyLen int) []byte {
+	numBlocks := (keyLen + bcryptBlockSize - 1) / bcryptBlockSize
+	key := make([]byte, numBlocks*bcryptBlockSize)
+
+	h := sha512.New()
+	h.Write(password)
+	shaPass := h.Sum(nil)
+
+	var shaSalt []byte
+	var count [4]byte
+	tmp := make([]byte, bcryptBlockSize)
+	for block := 1; block <= numBlocks; block++ {
+		count[0], count[1], count[2], count[3] = byte(block>>24), byte(block>>16), byte(block>>8), byte(block)
+		h.Reset()
+		h.Write(salt)
+		h.Write(count[:])
+		shaSalt = h.Sum(shaSalt[:0])
+		bcryptHash(tmp, shaPass, shaSalt)
+
+		out := make([]byte, bcryptBlockSize)
+		copy(out, tmp)
+		for i := 1; i < rounds; i++ {
+			h.Reset()
+			h.Write(tmp)
+			shaSalt = h.Sum(shaSalt[:0])
+			bcryptHash(tmp, shaPass, shaSalt)
+			for j := range out {
+				out[j] ^= tmp[j]
+			}
+		}
+
+		// Spread the output over the key so that every block is needed for any part of it
+		for i, v := range out {
+			key[i*numBlocks+block-1] = v
+		}
+	}
+	return key[:keyLen]
+}
+
+func bcryptHash(out, shaPass, shaSalt []byte) {
+	c, err := blowfish.NewSaltedCipher(shaPass, shaSalt)
+	if err != nil {
+		// Only happens for empty keys, which a sha512 sum never is
+		panic(err)
+	}
+	for i := 0; i < 64; i++ {
+		blowfish.ExpandKey(shaSalt, c)
+		blowfish.ExpandKey(shaPass, c)
+	}
+	copy(out, bcryptMagic)
+	for i := 0; i < bcryptBlockSize; i += 8 {
+		for j := 0; j < 64; j++ {
+			c.Encrypt(out[i:i+8], out[i:i+8])
+		}
+	}
+	// The reference implementation works on little endian words
+	for i := 0; i < bcryptBlockSize; i += 4 {
+		out[i], out[i+1], out[i+2], out[i+3] = out[i+3], out[i+2], out[i+1], out[i]
+	}
+}
@@ -0,0 +1,311 @@
+// Package sshkey generates SSH key pairs and encodes them in the formats used by OpenSSH.
+package sshkey
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
+	"math/big"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+// Type is the algorithm of a key pair
+type Type string
+
+// Supported key types
+const (
+	Ed25519 Type = "ed25519"
+	ECDSA   Type = "ecdsa"
+	RSA     Type = "rsa"
+)
+
+// Default sizes in bits if none are requested
+const (
+	DefaultECDSABits = 256
+	DefaultRSABits   = 3072
+)
+
+// Rounds of the bcrypt key derivation for encrypted private keys, matching ssh-keygen
+const kdfRounds = 16
+
+// KeyPair is a generated key pair in OpenSSH formats
+type KeyPair struct {
+	// PublicKey is a line for authorized_keys files
+	PublicKey string
+	// PrivateKey is a PEM encoded private key in the openssh-key-v1 format
+	PrivateKey []byte
+	// Fingerprint is the SHA256 fingerprint of the public key as shown by ssh-keygen
+	Fingerprint string
+}
+
+// Generate creates a new key pair of the given type and size. Ed25519 keys ignore the size
+// and a size of zero selects the default. If passphrase is not empty the private key is encrypted with it.
+func Generate(t Type, bits int, comment, passphrase string) (KeyPair, error) {
+	if err := ValidComment(comment); err != nil {
+		return KeyPair{}, err
+	}
+	key, err := generateKey(t, bits)
+	if err != nil {
+		return KeyPair{}, err
+	}
+	pub := key.publicBlob()
+	private, err := marshalPrivateKey(key, pub, comment, passphrase)
+	if err != nil {
+		return KeyPair{}, err
+	}
+	line := key.name() + " " + base64.StdEncoding.EncodeToString(pub)
+	if comment != "" {
+		line += " " + comment
+	}
+	fingerprint := sha256.Sum256(pub)
+	return KeyPair{
+		PublicKey:   line,
+		PrivateKey:  private,
+		Fingerprint: "SHA256:" + base64.RawStdEncoding.EncodeToString(fingerprint[:]),
+	}, nil
+}
+
+// ValidComment checks that the comment stays on the line of the public key.
+// Line breaks and other control characters could smuggle further keys into authorized_keys files.
+func ValidComment(comment string) error {
+	for _, r := range comment {
+		if unicode.IsControl(r) {
+			return errors.Errorf("Comment must not contain control characters, got %q", r)
+		}
+	}
+	return nil
+}
+
+// ValidType checks whether key pairs of the type can be generated
+func ValidType(t Type) error {
+	if t != Ed25519 && t != ECDSA && t != RSA {
+		return errors.Errorf("key type must be one of %s, %s or %s, got %s", Ed25519, ECDSA, RSA, t)
+	}
+	return nil
+}
+
+// ValidBits checks whether keys of the type can have the size, zero selects the default and Ed25519 keys ignore it
+func ValidBits(t Type, bits int) error {
+	if bits == 0 {
+		return nil
+	}
+	switch t {
+	case ECDSA:
+		if _, ok := curves[bits]; !ok {
+			return errors.Errorf("ecdsa keys must have 256, 384 or 521 bits, got %d", bits)
+		}
+	case RSA:
+		if bits != 2048 && bits != 3072 && bits != 4096 {
+			return errors.Errorf("rsa keys must have 2048, 3072 or 4096 bits, got %d", bits)
+		}
+	}
+	return nil
+}
+
+// key hides the differences between the key types behind their wire formats
+type key interface {
+	name() string
+	publicBlob() []byte
+	// privateFields are the type specific fields of the private section
+	privateFields() []byte
+}
+
+func generateKey(t Type, bits int) (key, error) {
+	if err := ValidType(t); err != nil {
+		return nil, err
+	}
+	if err := ValidBits(t, bits); err != nil {
+		return nil, err
+	}
+	switch t {
+	case Ed25519:
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, errors.Wrap(err, "Could not generate ed25519 key")
+		}
+		return ed25519Key{pub, priv}, nil
+	case ECDSA:
+		if bits == 0 {
+			bits = DefaultECDSABits
+		}
+		curve := curves[bits]
+		priv, err := ecdsa.GenerateKey(curve.curve, rand.Reader)
+		if err != nil {
+			return nil, errors.Wrap(err, "Could not generate ecdsa key")
+		}
+		return ecdsaKey{priv, curve.name}, nil
+	case RSA:
+		if bits == 0 {
+			bits = DefaultRSABits
+		}
+		priv, err := rsa.GenerateKey(rand.Reader, bits)
+		if err != nil {
+			return nil, errors.Wrap(err, "Could not generate rsa key")
+		}
+		return rsaKey{priv}, nil
+	}
+	return nil, ValidType(t)
+}
+
+var curves = map[int]struct {
+	curve elliptic.Curve
+	name  string
+}{
+	256: {elliptic.P256(), "nistp256"},
+	384: {elliptic.P384(), "nistp384"},
+	521: {elliptic.P521(), "nistp521"},
+}
+
+type ed25519Key struct {
+	pub  ed25519.PublicKey
+	priv ed25519.PrivateKey
+}
+
+func (k ed25519Key) name() string { return "ssh-ed25519" }
+
+func (k ed25519Key) publicBlob() []byte {
+	var w wire
+	w.string([]byte(k.name()))
+	w.string(k.pub)
+	return w
+}
+
+func (k ed25519Key) privateFields() []byte {
+	var w wire
+	w.string(k.pub)
+	w.string(k.priv)
+	return w
+}
+
+type ecdsaKey struct {
+	priv  *ecdsa.PrivateKey
+	curve string
+}
+
+func (k ecdsaKey) name() string { return "ecdsa-sha2-" + k.curve }
+
+func (k ecdsaKey) point() []byte {
+	return elliptic.Marshal(k.priv.Curve, k.priv.X, k.priv.Y)
+}
+
+func (k ecdsaKey) publicBlob() []byte {
+	var w wire
+	w.string([]byte(k.name()))
+	w.string([]byte(k.curve))
+	w.string(k.point())
+	return w
+}
+
+func (k ecdsaKey) privateFields() []byte {
+	var w wire
+	w.string([]byte(k.curve))
+	w.string(k.point())
+	w.mpint(k.priv.D)
+	return w
+}
+
+type rsaKey struct {
+	priv *rsa.PrivateKey
+}
+
+func (k rsaKey) name() string { return "ssh-rsa" }
+
+func (k rsaKey) publicBlob() []byte {
+	var w wire
+	w.string([]byte(k.name()))
+	w.mpint(big.NewInt(int64(k.priv.E)))
+	w.mpint(k.priv.N)
+	return w
+}
+
+func (k rsaKey) privateFields() []byte {
+	var w wire
+	w.mpint(k.priv.N)
+	w.mpint(big.NewInt(int64(k.priv.E)))
+	w.mpint(k.priv.D)
+	w.mpint(k.priv.Precomputed.Qinv)
+	w.mpint(k.priv.Primes[0])
+	w.mpint(k.priv.Primes[1])
+	return w
+}
+
+// Encode the private key in the openssh-key-v1 format as described in OpenSSH's PROTOCOL.key
+func marshalPrivateKey(k key, pub []byte, comment, passphrase string) ([]byte, error) {
+	cipherName, kdfName, blockSize := "none", "none", 8
+	var kdfOptions wire
+	var salt [16]byte
+	if passphrase != "" {
+		cipherName, kdfName, blockSize = "aes256-ctr", "bcrypt", aes.BlockSize
+		if _, err := rand.Read(salt[:]); err != nil {
+			return nil, errors.Wrap(err, "Could not read random salt")
+		}
+		kdfOptions.string(salt[:])
+		kdfOptions.uint32(kdfRounds)
+	}
+
+	// Matching check ints allow to detect a wrong passphrase
+	var check [4]byte
+	if _, err := rand.Read(check[:]); err != nil {
+		return nil, errors.Wrap(err, "Could not read random check int")
+	}
+	var private wire
+	private = append(private, check[:]...)
+	private = append(private, check[:]...)
+	private.string([]byte(k.name()))
+	private = append(private, k.privateFields()...)
+	private.string([]byte(comment))
+	for i := byte(1); len(private)%blockSize != 0; i++ {
+		private = append(private, i)
+	}
+
+	if passphrase != "" {
+		material := bcryptPBKDF([]byte(passphrase), salt[:], kdfRounds, 32+aes.BlockSize)
+		block, err := aes.NewCipher(material[:32])
+		if err != nil {
+			return nil, errors.Wrap(err, "Could not create cipher")
+		}
+		cipher.NewCTR(block, material[32:]).XORKeyStream(private, private)
+	}
+
+	w := wire("openssh-key-v1\x00")
+	w.string([]byte(cipherName))
+	w.string([]byte(kdfName))
+	w.string(kdfOptions)
+	w.uint32(1)
+	w.string(pub)
+	w.string(private)
+	return pem.EncodeToMemory(&pem.Block{Type: "OPENSSH PRIVATE KEY", Bytes: w}), nil
+}
+
+// wire builds data in the SSH wire format as described in RFC 4251
+type wire []byte
+
+func (w *wire) uint32(v uint32) {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], v)
+	*w = append(*w, b[:]...)
+}
+
+func (w *wire) string(s []byte) {
+	w.uint32(uint32(len(s)))
+	*w = append(*w, s...)
+}
+
+// Positive integers only, which get a leading zero byte if their highest bit is set
+func (w *wire) mpint(n *big.Int) {
+	b := n.Bytes()
+	if len(b) > 0 && b[0]&0x80 != 0 {
+		b = append([]byte{0}, b...)
+	}
+	w.string(b)
+}
@@ -0,0 +1,97 @@
+// Package systemd provides socket activation and readiness notification for services managed by systemd.
+package systemd
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// States which can be sent with Notify
+const (
+	Ready    = "READY=1"
+	Stopping = "STOPPING=1"
+)
+
+// listenFDsStart is the first file descriptor passed by systemd, following stdin, stdout and stderr
+const listenFDsStart = 3
+
+// Environment variables set by systemd
+const (
+	envListenPID     = "LISTEN_PID"
+	envListenFDs     = "LISTEN_FDS"
+	envListenFDNames = "LISTEN_FDNAMES"
+	envNotifySocket  = "NOTIFY_SOCKET"
+)
+
+// Listener is a socket passed by systemd, named by the FileDescriptorName of its socket unit
+type Listener struct {
+	net.Listener
+	Name string
+}
+
+// Listeners returns the sockets passed by socket activation, none if the process was not activated.
+// The environment variables of the activation are removed, so child processes do not inherit them.
+func Listeners() ([]Listener, error) {
+	defer func() {
+		_ = os.Unsetenv(envListenPID)
+		_ = os.Unsetenv(envListenFDs)
+		_ = os.Unsetenv(envListenFDNames)
+	}()
+	return listeners(os.Getenv, os.Getpid(), listenFDsStart)
+}
+
+func listeners(getenv func(string) string, pid, start int) ([]Listener, error) {
+	// Sockets passed to a parent process are not ours
+	if listenPID, err := strconv.Atoi(getenv(envListenPID)); err != nil || listenPID != pid {
+		return nil, nil
+	}
+	count, err := strconv.Atoi(getenv(envListenFDs))
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s was no number, got %s instead", envListenFDs, getenv(envListenFDs))
+	}
+	names := strings.Split(getenv(envListenFDNames), ":")
+	if len(names) != count {
+		names = make([]string, count)
+	}
+
+	var activated []Listener
+	for i := 0; i < count; i++ {
+		// The listener uses a duplicate of the file descriptor, so the original can be closed
+		f := os.NewFile(uintptr(start+i), names[i])
+		l, err := net.FileListener(f)
+		_ = f.Close()
+		if err != nil {
+			for _, a := range activated {
+				_ = a.Close()
+			}
+			return nil, errors.Wrapf(err, "File descriptor %d is no listening socket", start+i)
+		}
+		activated = append(activated, Listener{Listener: l, Name: names[i]})
+	}
+	return activated, nil
+}
+
+// Notify sends the state to systemd, it does nothing if the service is not managed by systemd
+func Notify(state string) error {
+	socket := os.Getenv(envNotifySocket)
+	if socket == "" {
+		return nil
+	}
+	// Sockets starting with @ are in the abstract namespace
+	if strings.HasPrefix(socket, "@") {
+		socket = "\x00" + socket[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return errors.Wrap(err, "Could not connect to the notify socket")
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return errors.Wrap(err, "Could not notify systemd")
+	}
+	return nil
+}
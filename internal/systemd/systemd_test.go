@@ -0,0 +1,125 @@
+package systemd
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// env returns a getenv function for the given variables
+func env(vars map[string]string) func(string) string {
+	return func(key string) string { return vars[key] }
+}
+
+func TestListeners(t *testing.T) {
+	// given a listening socket which is passed like systemd does
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer l.Close()
+	f, err := l.(*net.TCPListener).File()
+	assert.NoError(t, err)
+	vars := map[string]string{envListenPID: "42", envListenFDs: "1", envListenFDNames: "https"}
+
+	// when
+	activated, err := listeners(env(vars), 42, int(f.Fd()))
+	_ = f.Close()
+
+	// then the socket can be used under its name
+	assert.NoError(t, err)
+	if assert.Len(t, activated, 1) {
+		defer activated[0].Close()
+		assert.Equal(t, "https", activated[0].Name)
+		assert.Equal(t, l.Addr().String(), activated[0].Addr().String())
+	}
+}
+
+func TestListeners_Not_Activated(t *testing.T) {
+	testCases := []struct {
+		desc string
+		vars map[string]string
+	}{
+		{desc: "No activation", vars: map[string]string{}},
+		{desc: "Activation of another process", vars: map[string]string{envListenPID: "7", envListenFDs: "1"}},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// when
+			activated, err := listeners(env(tC.vars), 42, listenFDsStart)
+
+			// then
+			assert.NoError(t, err)
+			assert.Empty(t, activated)
+		})
+	}
+}
+
+func TestListeners_withError(t *testing.T) {
+	// given a file which is no socket
+	f, err := ioutil.TempFile("", "systemd")
+	assert.NoError(t, err)
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	testCases := []struct {
+		desc string
+		vars map[string]string
+	}{
+		{desc: "Invalid count", vars: map[string]string{envListenPID: "42", envListenFDs: "many"}},
+		{desc: "No socket", vars: map[string]string{envListenPID: "42", envListenFDs: "1"}},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// when
+			activated, err := listeners(env(tC.vars), 42, int(f.Fd()))
+
+			// then
+			assert.Error(t, err)
+			assert.Empty(t, activated)
+		})
+	}
+}
+
+func TestNotify(t *testing.T) {
+	// given a notify socket
+	dir, err := ioutil.TempDir("", "systemd")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "notify")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	assert.NoError(t, err)
+	defer conn.Close()
+	os.Setenv(envNotifySocket, socket)
+	defer os.Unsetenv(envNotifySocket)
+
+	// when
+	err = Notify(Ready)
+
+	// then systemd receives the state
+	assert.NoError(t, err)
+	buf := make([]byte, 64)
+	n, err := conn.Read(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, Ready, string(buf[:n]))
+}
+
+func TestNotify_Not_Managed(t *testing.T) {
+	// given no notify socket
+	os.Unsetenv(envNotifySocket)
+
+	// then notifications are ignored
+	assert.NoError(t, Notify(Ready))
+}
+
+func TestNotify_withError(t *testing.T) {
+	// given a notify socket which does not exist
+	os.Setenv(envNotifySocket, "/nonexistent/"+strconv.Itoa(os.Getpid()))
+	defer os.Unsetenv(envNotifySocket)
+
+	// then
+	assert.Error(t, Notify(Ready))
+}
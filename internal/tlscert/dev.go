@@ -0,0 +1,181 @@
+package tlscert
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Validity of development certificates, leaves are issued again on every start
+const (
+	devCAValidity   = 365 * 24 * time.Hour
+	devLeafValidity = 30 * 24 * time.Hour
+)
+
+// Files of a cached development CA, clients trust the certificate file
+const (
+	DevCACertFile = "ca.pem"
+	devCAKeyFile  = "ca.key"
+)
+
+// DevCA is a self-signed certificate authority for development, its certificates must never be trusted in production.
+type DevCA struct {
+	Cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// LoadDevCA reads the development CA cached in the directory or creates it there, so clients only need to trust it once.
+// Without a directory an ephemeral CA is created in memory.
+func LoadDevCA(dir string) (*DevCA, error) {
+	if dir == "" {
+		return newDevCA()
+	}
+	certFile, keyFile := filepath.Join(dir, DevCACertFile), filepath.Join(dir, devCAKeyFile)
+	ca, err := readDevCA(certFile, keyFile)
+	if err == nil && time.Now().Add(devLeafValidity).Before(ca.Cert.NotAfter) {
+		return ca, nil
+	}
+	if err != nil && !os.IsNotExist(errors.Cause(err)) {
+		return nil, err
+	}
+
+	// The CA is missing or expires before a leaf would
+	if ca, err = newDevCA(); err != nil {
+		return nil, err
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(ca.key)
+	if err != nil {
+		return nil, errors.Wrap(err, "Could not encode development CA key")
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, errors.Wrap(err, "Could not create directory of development CA")
+	}
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		return nil, errors.Wrap(err, "Could not write development CA key")
+	}
+	if err := ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Cert.Raw}), 0644); err != nil {
+		return nil, errors.Wrap(err, "Could not write development CA certificate")
+	}
+	return ca, nil
+}
+
+// readDevCA reads a cached development CA, a missing file is returned as its os error
+func readDevCA(certFile, keyFile string) (*DevCA, error) {
+	certPEM, err := ioutil.ReadFile(certFile)
+	if err != nil {
+		return nil, err
+	}
+	keyPEM, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	pair, err := X509KeyPair(certPEM, keyPEM, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "Could not read development CA")
+	}
+	key, ok := pair.PrivateKey.(*ecdsa.PrivateKey)
+	if !ok || !pair.Leaf.IsCA {
+		return nil, errors.Errorf("%s is not a development CA", certFile)
+	}
+	return &DevCA{Cert: pair.Leaf, key: key}, nil
+}
+
+func newDevCA() (*DevCA, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, errors.Wrap(err, "Could not generate development CA key")
+	}
+	template, err := devTemplate("pwgen development CA", devCAValidity)
+	if err != nil {
+		return nil, err
+	}
+	template.IsCA = true
+	template.BasicConstraintsValid = true
+	template.MaxPathLenZero = true
+	template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, errors.Wrap(err, "Could not create development CA")
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, errors.Wrap(err, "Could not parse development CA")
+	}
+	return &DevCA{Cert: cert, key: key}, nil
+}
+
+// Issue creates a certificate for localhost and the hosts, which may be names or IP addresses.
+// The certificate is returned as inline source, so it can be served like any other certificate.
+func (ca *DevCA) Issue(hosts ...string) (Source, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return Source{}, errors.Wrap(err, "Could not generate development key")
+	}
+	template, err := devTemplate("localhost", devLeafValidity)
+	if err != nil {
+		return Source{}, err
+	}
+	template.KeyUsage = x509.KeyUsageDigitalSignature
+	template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	for _, host := range append([]string{"localhost", "127.0.0.1", "::1"}, hosts...) {
+		host = strings.TrimSpace(host)
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else if host != "" {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.Cert, &key.PublicKey, ca.key)
+	if err != nil {
+		return Source{}, errors.Wrap(err, "Could not create development certificate")
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return Source{}, errors.Wrap(err, "Could not encode development key")
+	}
+	// The chain includes the CA, so clients may pin it from the handshake
+	certPEM := append(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Cert.Raw})...)
+	return Source{
+		CertPEM: string(certPEM),
+		KeyPEM:  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})),
+	}, nil
+}
+
+// Fingerprint returns the SHA-256 fingerprint of the CA certificate as colon separated hex bytes
+func (ca *DevCA) Fingerprint() string {
+	sum := sha256.Sum256(ca.Cert.Raw)
+	hex := make([]string, len(sum))
+	for i, b := range sum {
+		hex[i] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(hex, ":")
+}
+
+func devTemplate(commonName string, validity time.Duration) (*x509.Certificate, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, errors.Wrap(err, "Could not generate serial number")
+	}
+	return &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{Organization: []string{"pwgen development"}, CommonName: commonName},
+		// Tolerate clocks of clients which are slightly behind
+		NotBefore: time.Now().Add(-time.Hour),
+		NotAfter:  time.Now().Add(validity),
+	}, nil
+}
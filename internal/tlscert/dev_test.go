@@ -0,0 +1,62 @@
+package tlscert
+
+import (
+	"crypto/x509"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDevCA_Issue(t *testing.T) {
+	// given an ephemeral CA
+	ca, err := LoadDevCA("")
+	assert.NoError(t, err)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Cert)
+
+	// when a certificate for an additional name and address is issued
+	source, err := ca.Issue("pwgen.test", "10.0.0.1")
+	assert.NoError(t, err)
+	cert, err := source.Load()
+	assert.NoError(t, err)
+
+	// then it is valid for localhost and the hosts
+	for _, host := range []string{"localhost", "127.0.0.1", "::1", "pwgen.test", "10.0.0.1"} {
+		_, err := cert.Leaf.Verify(x509.VerifyOptions{DNSName: host, Roots: roots})
+		assert.NoError(t, err, host)
+	}
+	_, err = cert.Leaf.Verify(x509.VerifyOptions{DNSName: "example.com", Roots: roots})
+	assert.Error(t, err)
+	assert.Len(t, ca.Fingerprint(), 32*3-1)
+}
+
+func TestLoadDevCA_cached(t *testing.T) {
+	// given a cache directory
+	dir, err := ioutil.TempDir("", "tlscert")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	cacheDir := filepath.Join(dir, "dev")
+
+	// when the CA is loaded twice
+	created, err := LoadDevCA(cacheDir)
+	assert.NoError(t, err)
+	cached, err := LoadDevCA(cacheDir)
+	assert.NoError(t, err)
+
+	// then the same CA is used and its key is private
+	assert.Equal(t, created.Fingerprint(), cached.Fingerprint())
+	info, err := os.Stat(filepath.Join(cacheDir, devCAKeyFile))
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// when the cache holds something else than a CA
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(cacheDir, DevCACertFile), readFile(t, certFile), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(cacheDir, devCAKeyFile), readFile(t, plainKey), 0600))
+	_, err = LoadDevCA(cacheDir)
+
+	// then it is not overwritten
+	assert.Error(t, err)
+}
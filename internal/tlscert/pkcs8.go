@@ -0,0 +1,173 @@
+package tlscert
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"hash"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Object identifiers of the password based encryption schemes of RFC 8018 and RFC 7914
+var (
+	oidPBES2  = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2 = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	oidScrypt = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11591, 4, 11}
+
+	oidHMACWithSHA1   = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+	oidHMACWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidHMACWithSHA384 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 10}
+	oidHMACWithSHA512 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 11}
+
+	oidAES128CBC  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES192CBC  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}
+	oidAES256CBC  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+	oidDESEDE3CBC = asn1.ObjectIdentifier{1, 2, 840, 113549, 3, 7}
+)
+
+// errBadPassphrase is returned if the decrypted key is garbage
+var errBadPassphrase = errors.New("wrong passphrase or corrupted key")
+
+type encryptedPrivateKeyInfo struct {
+	Algorithm     pkix.AlgorithmIdentifier
+	EncryptedData []byte
+}
+
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+type pbkdf2Params struct {
+	Salt           []byte
+	IterationCount int
+	KeyLength      int                      `asn1:"optional"`
+	PRF            pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+type scryptParams struct {
+	Salt                     []byte
+	CostParameter            int
+	BlockSize                int
+	ParallelizationParameter int
+	KeyLength                int `asn1:"optional"`
+}
+
+// pbes2Cipher is a block cipher in CBC mode which can be used by PBES2
+type pbes2Cipher struct {
+	keyLength int
+	block     func(key []byte) (cipher.Block, error)
+}
+
+var pbes2Ciphers = map[string]pbes2Cipher{
+	oidAES128CBC.String():  {16, aes.NewCipher},
+	oidAES192CBC.String():  {24, aes.NewCipher},
+	oidAES256CBC.String():  {32, aes.NewCipher},
+	oidDESEDE3CBC.String(): {24, des.NewTripleDESCipher},
+}
+
+var pbkdf2PRFs = map[string]func() hash.Hash{
+	oidHMACWithSHA1.String():   sha1.New,
+	oidHMACWithSHA256.String(): sha256.New,
+	oidHMACWithSHA384.String(): sha512.New384,
+	oidHMACWithSHA512.String(): sha512.New,
+}
+
+// decryptPKCS8 decrypts an encrypted PKCS #8 private key, only PBES2 with PBKDF2 or scrypt is supported
+func decryptPKCS8(der, passphrase []byte) ([]byte, error) {
+	var info encryptedPrivateKeyInfo
+	if _, err := asn1.Unmarshal(der, &info); err != nil {
+		return nil, errors.Wrap(err, "Could not parse encrypted private key")
+	}
+	if !info.Algorithm.Algorithm.Equal(oidPBES2) {
+		return nil, errors.Errorf("Encryption scheme %s is not supported, only PBES2", info.Algorithm.Algorithm)
+	}
+	var params pbes2Params
+	if _, err := asn1.Unmarshal(info.Algorithm.Parameters.FullBytes, &params); err != nil {
+		return nil, errors.Wrap(err, "Could not parse PBES2 parameters")
+	}
+
+	c, ok := pbes2Ciphers[params.EncryptionScheme.Algorithm.String()]
+	if !ok {
+		return nil, errors.Errorf("Cipher %s is not supported", params.EncryptionScheme.Algorithm)
+	}
+	var iv []byte
+	if _, err := asn1.Unmarshal(params.EncryptionScheme.Parameters.FullBytes, &iv); err != nil {
+		return nil, errors.Wrap(err, "Could not parse the initialization vector")
+	}
+	key, err := deriveKey(params.KeyDerivationFunc, passphrase, c.keyLength)
+	if err != nil {
+		return nil, err
+	}
+	block, err := c.block(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "Could not create cipher")
+	}
+	if len(iv) != block.BlockSize() || len(info.EncryptedData) == 0 || len(info.EncryptedData)%block.BlockSize() != 0 {
+		return nil, errors.New("Encrypted private key has an invalid length")
+	}
+
+	plain := make([]byte, len(info.EncryptedData))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plain, info.EncryptedData)
+	plain, err = unpad(plain, block.BlockSize())
+	if err != nil {
+		return nil, err
+	}
+	// A wrong passphrase can produce valid padding by chance, but no valid key
+	if _, err := x509.ParsePKCS8PrivateKey(plain); err != nil {
+		return nil, errBadPassphrase
+	}
+	return plain, nil
+}
+
+// deriveKey derives the key of the cipher from the passphrase
+func deriveKey(kdf pkix.AlgorithmIdentifier, passphrase []byte, keyLength int) ([]byte, error) {
+	switch {
+	case kdf.Algorithm.Equal(oidPBKDF2):
+		var params pbkdf2Params
+		if _, err := asn1.Unmarshal(kdf.Parameters.FullBytes, &params); err != nil {
+			return nil, errors.Wrap(err, "Could not parse PBKDF2 parameters")
+		}
+		// HMAC-SHA1 is the default of RFC 8018
+		prf := sha1.New
+		if len(params.PRF.Algorithm) > 0 {
+			var ok bool
+			if prf, ok = pbkdf2PRFs[params.PRF.Algorithm.String()]; !ok {
+				return nil, errors.Errorf("Pseudorandom function %s is not supported", params.PRF.Algorithm)
+			}
+		}
+		return pbkdf2.Key(passphrase, params.Salt, params.IterationCount, keyLength, prf), nil
+	case kdf.Algorithm.Equal(oidScrypt):
+		var params scryptParams
+		if _, err := asn1.Unmarshal(kdf.Parameters.FullBytes, &params); err != nil {
+			return nil, errors.Wrap(err, "Could not parse scrypt parameters")
+		}
+		key, err := scrypt.Key(passphrase, params.Salt, params.CostParameter, params.BlockSize, params.ParallelizationParameter, keyLength)
+		return key, errors.Wrap(err, "Could not derive key with scrypt")
+	default:
+		return nil, errors.Errorf("Key derivation function %s is not supported", kdf.Algorithm)
+	}
+}
+
+// unpad removes the PKCS #7 padding of decrypted data
+func unpad(data []byte, blockSize int) ([]byte, error) {
+	n := int(data[len(data)-1])
+	if n == 0 || n > blockSize {
+		return nil, errBadPassphrase
+	}
+	for _, b := range data[len(data)-n:] {
+		if int(b) != n {
+			return nil, errBadPassphrase
+		}
+	}
+	return data[:len(data)-n], nil
+}
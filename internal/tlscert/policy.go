@@ -0,0 +1,118 @@
+package tlscert
+
+import (
+	"crypto/tls"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Policy restricts the TLS versions, cipher suites, curves and application protocols of a server by their names.
+// Empty values keep the defaults of Go, except for cipher suites which default to the CipherSuites.
+// Cipher suites only apply up to TLS 1.2.
+type Policy struct {
+	MinVersion   string
+	MaxVersion   string
+	CipherSuites []string
+	Curves       []string
+	ALPN         []string
+}
+
+// Versions maps the names of the supported TLS versions to their IDs
+var Versions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// CipherSuites are the ECDHE suites with AES-GCM or ChaCha20-Poly1305, the only ones which can be configured.
+// Go still enables suites with CBC mode by default, which lack forward secrecy or are prone to padding oracles.
+var CipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+}
+
+// Curves maps the names of the supported elliptic curves to their IDs
+var Curves = map[string]tls.CurveID{
+	"X25519": tls.X25519,
+	"P-256":  tls.CurveP256,
+	"P-384":  tls.CurveP384,
+	"P-521":  tls.CurveP521,
+}
+
+// Config creates a tls.Config following the policy
+func (p Policy) Config() (*tls.Config, error) {
+	c := &tls.Config{NextProtos: p.ALPN}
+	var err error
+	if c.MinVersion, err = version(p.MinVersion); err != nil {
+		return nil, err
+	}
+	if c.MaxVersion, err = version(p.MaxVersion); err != nil {
+		return nil, err
+	}
+	if c.MinVersion != 0 && c.MaxVersion != 0 && c.MaxVersion < c.MinVersion {
+		return nil, errors.Errorf("Maximum TLS version %s is below the minimum %s", p.MaxVersion, p.MinVersion)
+	}
+
+	// Only the AEAD suites with forward secrecy can be configured
+	aead := map[string]uint16{}
+	for _, id := range CipherSuites {
+		aead[tls.CipherSuiteName(id)] = id
+	}
+	for _, name := range p.CipherSuites {
+		id, ok := aead[strings.TrimSpace(name)]
+		if !ok {
+			return nil, errors.Errorf("Cipher suite %s is unknown or not an ECDHE suite with AES-GCM or ChaCha20-Poly1305", name)
+		}
+		c.CipherSuites = append(c.CipherSuites, id)
+	}
+	if len(c.CipherSuites) == 0 {
+		c.CipherSuites = append([]uint16{}, CipherSuites...)
+	}
+	for _, name := range p.Curves {
+		id, ok := Curves[strings.TrimSpace(name)]
+		if !ok {
+			return nil, errors.Errorf("Curve %s is not supported", name)
+		}
+		c.CurvePreferences = append(c.CurvePreferences, id)
+	}
+	return c, nil
+}
+
+// version returns the ID of the named TLS version, zero for the default
+func version(name string) (uint16, error) {
+	if name == "" {
+		return 0, nil
+	}
+	// TLS1.2 and TLSv1.2 are common spellings as well
+	v, ok := Versions[strings.TrimPrefix(strings.TrimPrefix(strings.ToUpper(name), "TLS"), "V")]
+	if !ok {
+		return 0, errors.Errorf("TLS version %s is not supported", name)
+	}
+	return v, nil
+}
+
+// VersionName returns the name of the TLS version ID
+func VersionName(id uint16) string {
+	for name, v := range Versions {
+		if v == id {
+			return name
+		}
+	}
+	return "default"
+}
+
+// CurveName returns the name of the curve ID
+func CurveName(id tls.CurveID) string {
+	for name, c := range Curves {
+		if c == id {
+			return name
+		}
+	}
+	return "unknown"
+}
@@ -0,0 +1,59 @@
+package tlscert
+
+import (
+	"crypto/tls"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicy_Config(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		policy      Policy
+		expected    *tls.Config
+		expectedErr bool
+	}{
+		{desc: "Defaults", expected: &tls.Config{CipherSuites: CipherSuites}},
+		{
+			desc: "Baseline",
+			policy: Policy{
+				MinVersion:   "1.2",
+				MaxVersion:   "TLSv1.3",
+				CipherSuites: []string{"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384", " TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256"},
+				Curves:       []string{"X25519", "P-256"},
+				ALPN:         []string{"h2", "http/1.1"},
+			},
+			expected: &tls.Config{
+				MinVersion:       tls.VersionTLS12,
+				MaxVersion:       tls.VersionTLS13,
+				CipherSuites:     []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384, tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256},
+				CurvePreferences: []tls.CurveID{tls.X25519, tls.CurveP256},
+				NextProtos:       []string{"h2", "http/1.1"},
+			},
+		},
+		{desc: "Unknown version", policy: Policy{MinVersion: "1.4"}, expectedErr: true},
+		{desc: "Maximum below minimum", policy: Policy{MinVersion: "1.3", MaxVersion: "1.2"}, expectedErr: true},
+		{desc: "Insecure cipher suite", policy: Policy{CipherSuites: []string{"TLS_RSA_WITH_RC4_128_SHA"}}, expectedErr: true},
+		{desc: "CBC cipher suite", policy: Policy{CipherSuites: []string{"TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA"}}, expectedErr: true},
+		{desc: "Cipher suite without forward secrecy", policy: Policy{CipherSuites: []string{"TLS_RSA_WITH_AES_128_GCM_SHA256"}}, expectedErr: true},
+		{desc: "Unknown curve", policy: Policy{Curves: []string{"P-224"}}, expectedErr: true},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// when
+			c, err := tC.policy.Config()
+
+			// then
+			assert.Equal(t, tC.expectedErr, err != nil, err)
+			assert.Equal(t, tC.expected, c)
+		})
+	}
+}
+
+func TestNames(t *testing.T) {
+	// then IDs are named like in the policy
+	assert.Equal(t, "1.2", VersionName(tls.VersionTLS12))
+	assert.Equal(t, "default", VersionName(0))
+	assert.Equal(t, "X25519", CurveName(tls.X25519))
+}
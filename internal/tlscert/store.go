@@ -0,0 +1,120 @@
+package tlscert
+
+import (
+	"crypto/tls"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Store serves certificates selected by the server name of TLS clients and reloads them from their sources.
+// A certificate which can not be reloaded is kept, so a broken rotation does not take the service down.
+type Store struct {
+	sources []Source
+
+	// certs holds a []*tls.Certificate in the order of the sources, it is replaced as a whole on reload
+	certs atomic.Value
+	// mu serializes reloads and guards stamps
+	mu     sync.Mutex
+	stamps map[string]stamp
+}
+
+// stamp identifies a version of a file
+type stamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// NewStore loads the certificates of the sources, the first one is served to clients no other certificate is valid for
+func NewStore(sources ...Source) (*Store, error) {
+	if len(sources) == 0 {
+		return nil, errors.New("At least one certificate is required")
+	}
+	s := &Store{sources: sources}
+	s.certs.Store(make([]*tls.Certificate, len(sources)))
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Reload loads all certificates again and replaces them at once.
+// Certificates which fail to load are kept and their errors are returned.
+func (s *Store) Reload() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old := s.certificates()
+	certs := make([]*tls.Certificate, len(s.sources))
+	var failed []string
+	for i, source := range s.sources {
+		cert, err := source.Load()
+		if err != nil {
+			certs[i] = old[i]
+			failed = append(failed, err.Error())
+			continue
+		}
+		certs[i] = &cert
+	}
+	// Files are only checked again after they changed, even if loading failed
+	s.stamps = s.stat()
+	for _, cert := range certs {
+		if cert == nil {
+			return errors.Errorf("Could not load certificates: %s", strings.Join(failed, "; "))
+		}
+	}
+	s.certs.Store(certs)
+
+	if len(failed) > 0 {
+		return errors.Errorf("Kept %d previous certificates: %s", len(failed), strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// Changed reports whether a file of the certificates changed since the last reload
+func (s *Store) Changed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stamps := s.stat()
+	for file, st := range stamps {
+		if s.stamps[file] != st {
+			return true
+		}
+	}
+	return false
+}
+
+// GetCertificate returns the first certificate the client supports, it can be used as tls.Config.GetCertificate
+func (s *Store) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	certs := s.certificates()
+	for _, cert := range certs {
+		if hello.SupportsCertificate(cert) == nil {
+			return cert, nil
+		}
+	}
+	// Clients asking for an unknown server name get the default certificate
+	return certs[0], nil
+}
+
+func (s *Store) certificates() []*tls.Certificate {
+	return s.certs.Load().([]*tls.Certificate)
+}
+
+// stat returns the current stamps of all files of the sources, missing files have an empty stamp
+func (s *Store) stat() map[string]stamp {
+	stamps := map[string]stamp{}
+	for _, source := range s.sources {
+		for _, file := range source.files() {
+			if info, err := os.Stat(file); err == nil {
+				stamps[file] = stamp{info.ModTime(), info.Size()}
+			} else {
+				stamps[file] = stamp{}
+			}
+		}
+	}
+	return stamps
+}
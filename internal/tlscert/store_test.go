@@ -0,0 +1,159 @@
+package tlscert
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeCert writes a self signed certificate for the name and its key to the directory
+func writeCert(t *testing.T, dir, name string) Source {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	source := Source{CertFile: filepath.Join(dir, name+".pem"), KeyFile: filepath.Join(dir, name+".key")}
+	writeFile(t, source.CertFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	writeFile(t, source.KeyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}))
+	return source
+}
+
+// writeFile writes the file with a later modification time, even if the file system can not tell quick writes apart
+func writeFile(t *testing.T, file string, content []byte) {
+	modTime := time.Now()
+	if info, err := os.Stat(file); err == nil {
+		modTime = info.ModTime().Add(time.Second)
+	}
+	if err := ioutil.WriteFile(file, content, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(file, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// served returns the certificate the store serves to a client asking for the server name
+func served(t *testing.T, s *Store, serverName string) *x509.Certificate {
+	serverConn, clientConn := net.Pipe()
+	defer serverConn.Close()
+	defer clientConn.Close()
+	go func() {
+		_ = tls.Server(serverConn, &tls.Config{GetCertificate: s.GetCertificate}).Handshake()
+	}()
+	client := tls.Client(clientConn, &tls.Config{ServerName: serverName, InsecureSkipVerify: true})
+	if err := client.Handshake(); err != nil {
+		t.Fatal(err)
+	}
+	return client.ConnectionState().PeerCertificates[0]
+}
+
+func TestStore_GetCertificate(t *testing.T) {
+	// given a store with two certificates
+	dir, err := ioutil.TempDir("", "tlscert")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	s, err := NewStore(writeCert(t, dir, "a.test"), writeCert(t, dir, "b.test"))
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		serverName string
+		expected   string
+	}{
+		{serverName: "a.test", expected: "a.test"},
+		{serverName: "b.test", expected: "b.test"},
+		{serverName: "unknown.test", expected: "a.test"},
+		{serverName: "", expected: "a.test"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.serverName, func(t *testing.T) {
+			// when
+			cert := served(t, s, tC.serverName)
+
+			// then the certificate is selected by the server name
+			assert.Equal(t, tC.expected, cert.Subject.CommonName)
+		})
+	}
+}
+
+func TestStore_Reload(t *testing.T) {
+	// given a store with a certificate
+	dir, err := ioutil.TempDir("", "tlscert")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	source := writeCert(t, dir, "a.test")
+	s, err := NewStore(source)
+	assert.NoError(t, err)
+	old := served(t, s, "a.test")
+	assert.False(t, s.Changed())
+
+	// when the certificate is rotated
+	writeCert(t, dir, "a.test")
+	assert.True(t, s.Changed())
+	err = s.Reload()
+
+	// then the new certificate is served
+	assert.NoError(t, err)
+	assert.False(t, s.Changed())
+	rotated := served(t, s, "a.test")
+	assert.NotEqual(t, old.SerialNumber, rotated.SerialNumber)
+
+	// when the certificate is replaced with garbage
+	writeFile(t, source.CertFile, []byte("garbage"))
+	assert.True(t, s.Changed())
+	err = s.Reload()
+
+	// then the previous certificate is still served
+	assert.Error(t, err)
+	assert.False(t, s.Changed())
+	assert.Equal(t, rotated.SerialNumber, served(t, s, "a.test").SerialNumber)
+}
+
+func TestNewStore_withError(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		sources []Source
+	}{
+		{desc: "No source"},
+		{desc: "Missing files", sources: []Source{{CertFile: "missing.pem", KeyFile: "missing.key"}}},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// when
+			s, err := NewStore(tC.sources...)
+
+			// then
+			assert.Error(t, err)
+			assert.Nil(t, s)
+		})
+	}
+}
@@ -0,0 +1,136 @@
+// Package tlscert loads TLS certificates and their private keys, which may be encrypted with a passphrase.
+package tlscert
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Source describes where a certificate and its private key are read from.
+// Inline PEM content takes precedence over files, just like a passphrase over its file.
+type Source struct {
+	CertFile, KeyFile string
+	CertPEM, KeyPEM   string
+
+	Passphrase     string
+	PassphraseFile string
+}
+
+// Load reads the certificate and its private key, decrypting the key if necessary
+func (s Source) Load() (tls.Certificate, error) {
+	certPEM, err := read(s.CertPEM, s.CertFile)
+	if err != nil {
+		return tls.Certificate{}, errors.Wrap(err, "Could not read certificate")
+	}
+	keyPEM, err := read(s.KeyPEM, s.KeyFile)
+	if err != nil {
+		return tls.Certificate{}, errors.Wrap(err, "Could not read private key")
+	}
+	passphrase := []byte(s.Passphrase)
+	if s.Passphrase == "" && s.PassphraseFile != "" {
+		content, err := ioutil.ReadFile(s.PassphraseFile)
+		if err != nil {
+			return tls.Certificate{}, errors.Wrap(err, "Could not read passphrase")
+		}
+		// Editors and echo like to end files with a newline
+		passphrase = []byte(strings.TrimRight(string(content), "\r\n"))
+	}
+	return X509KeyPair(certPEM, keyPEM, passphrase)
+}
+
+// files returns the files the source is read from
+func (s Source) files() []string {
+	var files []string
+	if s.CertPEM == "" && s.CertFile != "" {
+		files = append(files, s.CertFile)
+	}
+	if s.KeyPEM == "" && s.KeyFile != "" {
+		files = append(files, s.KeyFile)
+	}
+	if s.Passphrase == "" && s.PassphraseFile != "" {
+		files = append(files, s.PassphraseFile)
+	}
+	return files
+}
+
+// CertPool reads a bundle of PEM encoded CA certificates
+func CertPool(file string) (*x509.CertPool, error) {
+	bundle, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrap(err, "Could not read CA certificates")
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(bundle) {
+		return nil, errors.Errorf("%s contains no PEM encoded certificates", file)
+	}
+	return pool, nil
+}
+
+// read returns the inline content or else the content of the file
+func read(inline, file string) ([]byte, error) {
+	if inline != "" {
+		return []byte(inline), nil
+	}
+	if file == "" {
+		return nil, errors.New("Neither PEM content nor a file was given")
+	}
+	return ioutil.ReadFile(file)
+}
+
+// X509KeyPair parses a certificate and its private key like tls.X509KeyPair.
+// Keys can be encrypted PKCS #8 keys or legacy PEM blocks encrypted as described by RFC 1423.
+func X509KeyPair(certPEM, keyPEM, passphrase []byte) (tls.Certificate, error) {
+	block, err := privateKeyBlock(keyPEM)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	switch {
+	case block.Type == "ENCRYPTED PRIVATE KEY":
+		if len(passphrase) == 0 {
+			return tls.Certificate{}, errors.New("Private key is encrypted, but no passphrase was given")
+		}
+		der, err := decryptPKCS8(block.Bytes, passphrase)
+		if err != nil {
+			return tls.Certificate{}, errors.Wrap(err, "Could not decrypt private key")
+		}
+		block = &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+	case x509.IsEncryptedPEMBlock(block):
+		if len(passphrase) == 0 {
+			return tls.Certificate{}, errors.New("Private key is encrypted, but no passphrase was given")
+		}
+		der, err := x509.DecryptPEMBlock(block, passphrase)
+		if err != nil {
+			return tls.Certificate{}, errors.Wrap(err, "Could not decrypt private key")
+		}
+		block = &pem.Block{Type: block.Type, Bytes: der}
+	}
+
+	cert, err := tls.X509KeyPair(certPEM, pem.EncodeToMemory(block))
+	if err != nil {
+		return tls.Certificate{}, errors.Wrap(err, "Could not use certificate and private key")
+	}
+	// The leaf is needed to select certificates by server name
+	if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+		return tls.Certificate{}, errors.Wrap(err, "Could not parse certificate")
+	}
+	return cert, nil
+}
+
+// privateKeyBlock returns the first PEM block containing a private key
+func privateKeyBlock(keyPEM []byte) (*pem.Block, error) {
+	for {
+		var block *pem.Block
+		block, keyPEM = pem.Decode(keyPEM)
+		if block == nil {
+			return nil, errors.New("No PEM encoded private key found")
+		}
+		if strings.HasSuffix(block.Type, "PRIVATE KEY") {
+			return block, nil
+		}
+	}
+}
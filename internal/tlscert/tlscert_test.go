@@ -0,0 +1,122 @@
+package tlscert
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	certFile   = "../../cert.pem"
+	plainKey   = "../../key.unencrypted.pem"
+	passphrase = "pwgen"
+)
+
+func readFile(t *testing.T, file string) []byte {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return content
+}
+
+func TestX509KeyPair(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		keyFile       string
+		passphrase    string
+		expectedErr   bool
+		expectedCause error
+	}{
+		{desc: "Unencrypted", keyFile: plainKey},
+		{desc: "Unencrypted ignores passphrase", keyFile: plainKey, passphrase: passphrase},
+		{desc: "PBES2 with DES-EDE3 and HMAC-SHA256", keyFile: "../../key.pem", passphrase: passphrase},
+		{desc: "PBES2 with AES-128 and HMAC-SHA1", keyFile: "testdata/key.aes128.pem", passphrase: passphrase},
+		{desc: "PBES2 with AES-256 and HMAC-SHA512", keyFile: "testdata/key.aes256.pem", passphrase: passphrase},
+		{desc: "PBES2 with scrypt", keyFile: "testdata/key.scrypt.pem", passphrase: passphrase},
+		{desc: "Legacy PEM encryption", keyFile: "testdata/key.legacy.pem", passphrase: passphrase},
+		{desc: "Wrong passphrase", keyFile: "testdata/key.aes256.pem", passphrase: "wrong", expectedErr: true, expectedCause: errBadPassphrase},
+		{desc: "Missing passphrase", keyFile: "../../key.pem", expectedErr: true},
+		{desc: "Missing passphrase of legacy PEM encryption", keyFile: "testdata/key.legacy.pem", expectedErr: true},
+		{desc: "Wrong passphrase of legacy PEM encryption", keyFile: "testdata/key.legacy.pem", passphrase: "wrong", expectedErr: true},
+		{desc: "PBES1 is not supported", keyFile: "testdata/key.pbes1.pem", passphrase: passphrase, expectedErr: true},
+		{desc: "No key", keyFile: certFile, expectedErr: true},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// when
+			cert, err := X509KeyPair(readFile(t, certFile), readFile(t, tC.keyFile), []byte(tC.passphrase))
+
+			// then
+			if !tC.expectedErr {
+				assert.NoError(t, err)
+				assert.NotNil(t, cert.PrivateKey)
+				return
+			}
+			assert.Error(t, err)
+			if tC.expectedCause != nil {
+				assert.Equal(t, tC.expectedCause, errors.Cause(err))
+			}
+		})
+	}
+}
+
+func TestSource_Load(t *testing.T) {
+	// given a passphrase file ending with a newline
+	dir, err := ioutil.TempDir("", "tlscert")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	passphraseFile := filepath.Join(dir, "passphrase")
+	assert.NoError(t, ioutil.WriteFile(passphraseFile, []byte(passphrase+"\n"), 0600))
+	cert, key := string(readFile(t, certFile)), string(readFile(t, "../../key.pem"))
+
+	testCases := []struct {
+		desc        string
+		source      Source
+		expectedErr bool
+	}{
+		{desc: "Files", source: Source{CertFile: certFile, KeyFile: "../../key.pem", Passphrase: passphrase}},
+		{desc: "Passphrase file", source: Source{CertFile: certFile, KeyFile: "../../key.pem", PassphraseFile: passphraseFile}},
+		{desc: "Passphrase before its file", source: Source{CertFile: certFile, KeyFile: "../../key.pem", Passphrase: passphrase, PassphraseFile: "missing"}},
+		{desc: "Inline PEM", source: Source{CertPEM: cert, KeyPEM: key, Passphrase: passphrase}},
+		{desc: "Inline PEM before files", source: Source{CertPEM: cert, KeyPEM: key, CertFile: "missing", KeyFile: "missing", Passphrase: passphrase}},
+		{desc: "Missing certificate", source: Source{KeyFile: plainKey}, expectedErr: true},
+		{desc: "Missing key file", source: Source{CertFile: certFile, KeyFile: "missing"}, expectedErr: true},
+		{desc: "Missing passphrase file", source: Source{CertFile: certFile, KeyFile: "../../key.pem", PassphraseFile: "missing"}, expectedErr: true},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// when
+			_, err := tC.source.Load()
+
+			// then
+			assert.Equal(t, tC.expectedErr, err != nil, err)
+		})
+	}
+}
+
+func TestCertPool(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		file        string
+		expectedErr bool
+	}{
+		{desc: "Certificate", file: certFile},
+		{desc: "No certificate", file: plainKey, expectedErr: true},
+		{desc: "Missing file", file: "missing.pem", expectedErr: true},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// when
+			pool, err := CertPool(tC.file)
+
+			// then
+			assert.Equal(t, tC.expectedErr, err != nil, err)
+			assert.Equal(t, tC.expectedErr, pool == nil)
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package x25519
+
+import (
+	"strings"
+)
+
+// Bech32 as specified by BIP 173, without the 90 character limit just like age
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+var bech32Generator = [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+
+// bech32Encode encodes data with a lower case human readable part
+func bech32Encode(hrp string, data []byte) string {
+	values := convertBits(data)
+	var b strings.Builder
+	b.WriteString(hrp)
+	b.WriteByte('1')
+	for _, v := range append(values, bech32Checksum(hrp, values)...) {
+		b.WriteByte(bech32Charset[v])
+	}
+	return b.String()
+}
+
+func bech32Polymod(values []byte) uint32 {
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i, g := range bech32Generator {
+			if (top>>uint(i))&1 == 1 {
+				chk ^= g
+			}
+		}
+	}
+	return chk
+}
+
+func bech32Checksum(hrp string, data []byte) []byte {
+	values := make([]byte, 0, len(hrp)*2+1+len(data)+6)
+	for i := 0; i < len(hrp); i++ {
+		values = append(values, hrp[i]>>5)
+	}
+	values = append(values, 0)
+	for i := 0; i < len(hrp); i++ {
+		values = append(values, hrp[i]&31)
+	}
+	values = append(values, data...)
+	values = append(values, 0, 0, 0, 0, 0, 0)
+	mod := bech32Polymod(values) ^ 1
+	checksum := make([]byte, 6)
+	for i := range checksum {
+		checksum[i] = byte(mod>>uint(5*(5-i))) & 31
+	}
+	return checksum
+}
+
+// Regroup bytes into 5 bit values, padding the last one with zeros
+func convertBits(data []byte) []byte {
+	var acc uint32
+	var bits uint
+	result := make([]byte, 0, (len(data)*8+4)/5)
+	for _, b := range data {
+		acc = acc<<8 | uint32(b)
+		bits += 8
+		for bits >= 5 {
+			bits -= 5
+			result = append(result, byte(acc>>bits)&31)
+		}
+	}
+	if bits > 0 {
+		result = append(result, byte(acc<<(5-bits))&31)
+	}
+	return result
+}
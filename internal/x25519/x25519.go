@@ -0,0 +1,68 @@
+// Package x25519 generates Curve25519 key pairs and encodes them for WireGuard and age.
+package x25519
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/curve25519"
+)
+
+// KeySize is the size of private, public and preshared keys in bytes
+const KeySize = 32
+
+// Human readable parts of age identities and recipients
+const (
+	ageIdentityHRP  = "AGE-SECRET-KEY-"
+	ageRecipientHRP = "age"
+)
+
+// KeyPair is a X25519 private key with its public key
+type KeyPair struct {
+	Private, Public [KeySize]byte
+}
+
+// Generate creates a key pair from a random private key.
+func Generate() (KeyPair, error) {
+	var private [KeySize]byte
+	if _, err := rand.Read(private[:]); err != nil {
+		return KeyPair{}, errors.Wrap(err, "Could not read random private key")
+	}
+	return NewKeyPair(private)
+}
+
+// NewKeyPair clamps the private key like WireGuard does and derives its public key.
+func NewKeyPair(private [KeySize]byte) (KeyPair, error) {
+	private[0] &= 248
+	private[31] = private[31]&127 | 64
+	public, err := curve25519.X25519(private[:], curve25519.Basepoint)
+	if err != nil {
+		return KeyPair{}, errors.Wrap(err, "Could not derive public key")
+	}
+	k := KeyPair{Private: private}
+	copy(k.Public[:], public)
+	return k, nil
+}
+
+// WireGuard returns the private and public key in the base64 encoding of wg(8).
+func (k KeyPair) WireGuard() (private, public string) {
+	return base64.StdEncoding.EncodeToString(k.Private[:]), base64.StdEncoding.EncodeToString(k.Public[:])
+}
+
+// Age returns the identity and recipient in the bech32 encoding of age.
+func (k KeyPair) Age() (identity, recipient string) {
+	identity = strings.ToUpper(bech32Encode(strings.ToLower(ageIdentityHRP), k.Private[:]))
+	recipient = bech32Encode(ageRecipientHRP, k.Public[:])
+	return identity, recipient
+}
+
+// PresharedKey returns a random symmetric key for WireGuard peers in base64 encoding.
+func PresharedKey() (string, error) {
+	var key [KeySize]byte
+	if _, err := rand.Read(key[:]); err != nil {
+		return "", errors.Wrap(err, "Could not read random preshared key")
+	}
+	return base64.StdEncoding.EncodeToString(key[:]), nil
+}
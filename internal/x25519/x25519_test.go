@@ -0,0 +1,77 @@
+package x25519
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewKeyPair(t *testing.T) {
+	// given the private key of Alice from RFC 7748
+	var private [KeySize]byte
+	b, _ := hex.DecodeString("77076d0a7318a57d3c16c17251b26645df4c2f87ebc0992ab177fba51db92c2a")
+	copy(private[:], b)
+
+	// when
+	k, err := NewKeyPair(private)
+
+	// then we derive the public key of Alice
+	assert.NoError(t, err)
+	assert.Equal(t, "8520f0098930a754748b7ddcb43ef75a0dbf3a0d26381af4eba4a98eaa9b4e6a", hex.EncodeToString(k.Public[:]))
+	// and the private key is clamped
+	assert.Equal(t, byte(0), k.Private[0]&7)
+	assert.Equal(t, byte(64), k.Private[31]&192)
+}
+
+func TestKeyPair_Encodings(t *testing.T) {
+	// given a fixed private key
+	var private [KeySize]byte
+	for i := range private {
+		private[i] = byte(i + 1)
+	}
+	k, _ := NewKeyPair(private)
+
+	// when
+	wgPrivate, wgPublic := k.WireGuard()
+	identity, recipient := k.Age()
+
+	// then the keys match the encodings of wg and age
+	assert.Equal(t, "AAIDBAUGBwgJCgsMDQ4PEBESExQVFhcYGRobHB0eH2A=", wgPrivate)
+	assert.Equal(t, "B6N8vBQgk8i3VdwbEOhstCY3StFqqFPtC9/AsrhtHHw=", wgPublic)
+	assert.Equal(t, "AGE-SECRET-KEY-1QQPQXPQ9QCRSSZG2PVXQ6RS0ZQG3YYC5Z5TPWXQERGD3C8G7RASQV9E967", identity)
+	assert.Equal(t, "age1q73he0q5yzfu3d64msd3p6rvksnrwjk3d2598mgtmlqt9wrdr37q2vrn72", recipient)
+}
+
+func TestGenerate(t *testing.T) {
+	// when
+	a, errA := Generate()
+	b, errB := Generate()
+
+	// then
+	assert.NoError(t, errA)
+	assert.NoError(t, errB)
+	assert.NotEqual(t, a.Private, b.Private)
+	identity, recipient := a.Age()
+	assert.True(t, strings.HasPrefix(identity, "AGE-SECRET-KEY-1"))
+	assert.True(t, strings.HasPrefix(recipient, "age1"))
+}
+
+func TestPresharedKey(t *testing.T) {
+	// when
+	psk, err := PresharedKey()
+
+	// then
+	assert.NoError(t, err)
+	raw, err := base64.StdEncoding.DecodeString(psk)
+	assert.NoError(t, err)
+	assert.Equal(t, KeySize, len(raw))
+}
+
+func TestBech32Encode(t *testing.T) {
+	// Valid checksums from BIP 173
+	assert.Equal(t, "a12uel5l", bech32Encode("a", nil))
+	assert.Equal(t, "abcdef1qpzry9x8gf2tvdw0s3jn54khce6mua7lmqqqxw", bech32Encode("abcdef", []byte{0x00, 0x44, 0x32, 0x14, 0xc7, 0x42, 0x54, 0xb6, 0x35, 0xcf, 0x84, 0x65, 0x3a, 0x56, 0xd7, 0xc6, 0x75, 0xbe, 0x77, 0xdf}))
+}